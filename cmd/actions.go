@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"webshell-detector/internal/result"
+)
+
+// runActionsCommand 列出自动响应执行过的动作
+func runActionsCommand(args []string) error {
+	fs := flag.NewFlagSet("actions", flag.ExitOnError)
+	dbPath := fs.String("db", result.DefaultDBPath, "Path of the result database")
+	limit := fs.Int("limit", 50, "Maximum actions shown (0 = all)")
+	file := fs.String("file", "", "Only show actions taken on this file")
+	fs.Parse(args)
+
+	storage, err := result.NewStorage(*dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open result storage: %v", err)
+	}
+	defer storage.Close()
+
+	records, err := storage.ListActions(*limit, *file)
+	if err != nil {
+		return err
+	}
+	// 结果按扫描时传入的路径记录，原样查不到时再按绝对路径查找
+	if len(records) == 0 && *file != "" {
+		if abs, err := filepath.Abs(*file); err == nil && abs != *file {
+			if records, err = storage.ListActions(*limit, abs); err != nil {
+				return err
+			}
+		}
+	}
+	if len(records) == 0 {
+		fmt.Println("No response actions found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTIME\tACTION\tSTATUS\tRULE\tRISK\tSCORE\tSCAN\tFILE\tDETAIL")
+	for _, r := range records {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%.2f\t%s\t%s\t%s\n",
+			r.ID, r.ActionTime.Format("2006-01-02 15:04:05"), r.Action, r.Status, r.Rule,
+			r.RiskLevel, r.TotalScore, r.ScanType, r.FilePath, r.Detail)
+	}
+	w.Flush()
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"webshell-detector/internal/config"
+	"webshell-detector/pkg/mlmodel"
+	"webshell-detector/pkg/signature"
+)
+
+// subcommands 子命令表
+var subcommands = map[string]func(args []string) error{
+	"features":      runFeaturesCommand,
+	"train":         runTrainCommand,
+	"evaluate":      runEvaluateCommand,
+	"label":         runLabelCommand,
+	"export":        runExportCommand,
+	"shadow-report": runShadowReportCommand,
+	"quarantine":    runQuarantineCommand,
+	"actions":       runActionsCommand,
+	"jobs":          runJobsCommand,
+}
+
+// runSubcommand 执行子命令
+func runSubcommand(name string, args []string) {
+	run, ok := subcommands[name]
+	if !ok {
+		names := make([]string, 0, len(subcommands))
+		for n := range subcommands {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		log.Fatalf("Unknown command: %s (available: %s)", name, strings.Join(names, ", "))
+	}
+
+	if err := run(args); err != nil {
+		log.Fatalf("%s failed: %v", name, err)
+	}
+}
+
+// stringList 可重复指定的字符串参数，如 -benign a -benign b
+type stringList []string
+
+func (s *stringList) String() string {
+	return fmt.Sprint(*s)
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// loadEngines 加载配置、特征库和模型，模型加载失败时仅告警
+func loadEngines(configPath string) (*config.Config, *signature.Manager, *mlmodel.Model, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load config: %v", err)
+	}
+
+	sigMgr, err := signature.NewManager(cfg.SignaturePath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to initialize signature manager: %v", err)
+	}
+
+	model := loadModel(cfg)
+
+	return cfg, sigMgr, model, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"webshell-detector/internal/dataset"
+	"webshell-detector/internal/detector"
+	"webshell-detector/internal/evaluation"
+)
+
+// runEvaluateCommand 在带标签语料上评估完整检测器和各引擎的检测效果
+func runEvaluateCommand(args []string) error {
+	fs := flag.NewFlagSet("evaluate", flag.ExitOnError)
+	configPath := fs.String("config", "configs/config.yaml", "Path to config file")
+	output := fs.String("output", "data/evaluation.json", "Path of the JSON report")
+	maxListed := fs.Int("list", 20, "Maximum false positives/negatives listed in the table (0 = all)")
+	var benignDirs, maliciousDirs stringList
+	fs.Var(&benignDirs, "benign", "Directory of benign samples (repeatable)")
+	fs.Var(&maliciousDirs, "malicious", "Directory of webshell samples (repeatable)")
+	fs.Parse(args)
+
+	if len(benignDirs) == 0 && len(maliciousDirs) == 0 {
+		return fmt.Errorf("specify at least one -benign or -malicious directory")
+	}
+
+	cfg, sigMgr, model, err := loadEngines(*configPath)
+	if err != nil {
+		return err
+	}
+	defer sigMgr.Close()
+
+	corpus := dataset.Corpus{
+		BenignDirs:    benignDirs,
+		MaliciousDirs: maliciousDirs,
+		FileTypes:     cfg.Scan.FileTypes,
+		MaxFileSize:   cfg.Scan.Schedule.MaxFileSize,
+	}
+	samples, err := corpus.Collect()
+	if err != nil {
+		return err
+	}
+
+	det := detector.NewDetector(cfg, sigMgr, model)
+	defer det.Close()
+
+	log.Printf("Evaluating %d samples...", len(samples))
+	report, err := evaluation.Run(context.Background(), det, samples)
+	if err != nil {
+		return err
+	}
+
+	report.PrintTable(os.Stdout, *maxListed)
+	if err := report.WriteJSON(*output); err != nil {
+		return err
+	}
+	log.Printf("Report written to %s", *output)
+	return nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"webshell-detector/internal/dataset"
+	"webshell-detector/internal/result"
+)
+
+// manifestEntry 导出数据集中单个样本的来源和标注信息
+type manifestEntry struct {
+	File       string    `json:"file"` // 相对导出目录的路径
+	SHA256     string    `json:"sha256"`
+	Label      int       `json:"label"`
+	Verdict    string    `json:"verdict"`
+	Family     string    `json:"family,omitempty"`
+	Notes      string    `json:"notes,omitempty"`
+	Analyst    string    `json:"analyst,omitempty"`
+	SourcePath string    `json:"source_path"`
+	ResultID   int64     `json:"result_id"`
+	RiskLevel  string    `json:"risk_level"`  // 标注时检测器给出的风险等级
+	IsWebshell bool      `json:"is_webshell"` // 标注时检测器的判定
+	LabelTime  time.Time `json:"label_time"`
+
+	archivePath string
+}
+
+// runExportCommand 将分析人员标注的结果导出为训练/评估数据集：
+// benign/ 与 malicious/ 目录可直接用于 train、evaluate 和 features 子命令，
+// manifest.jsonl 记录每个样本的期望结论，供规则回归比对
+func runExportCommand(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dbPath := fs.String("db", result.DefaultDBPath, "Path of the result database")
+	output := fs.String("output", "data/dataset", "Output directory")
+	featuresPath := fs.String("features", "", "Also write feature vectors to this CSV file")
+	fs.Parse(args)
+
+	storage, err := result.NewStorage(*dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open result storage: %v", err)
+	}
+	defer storage.Close()
+
+	labeled, err := storage.LabeledResults()
+	if err != nil {
+		return err
+	}
+
+	// 同一内容可能被多次标注，结论冲突的样本不导出
+	entries := make(map[string]*manifestEntry)
+	var order []string
+	conflicts := make(map[string]bool)
+	skipped := 0
+	for _, r := range labeled {
+		label := r.Label
+		if label.ArchivePath == "" {
+			skipped++
+			continue
+		}
+
+		entry := &manifestEntry{
+			SHA256:     label.ContentHash,
+			Label:      dataset.LabelBenign,
+			Verdict:    label.Verdict,
+			Family:     label.Family,
+			Notes:      label.Notes,
+			Analyst:    label.Analyst,
+			SourcePath: r.FilePath,
+			ResultID:   r.ID,
+			RiskLevel:  r.RiskLevel,
+			IsWebshell: r.IsWebshell,
+			LabelTime:  label.LabelTime,
+
+			archivePath: label.ArchivePath,
+		}
+		if label.Verdict == result.VerdictConfirmed {
+			entry.Label = dataset.LabelMalicious
+		}
+
+		dir := "benign"
+		if entry.Label == dataset.LabelMalicious {
+			dir = "malicious"
+		}
+		entry.File = filepath.Join(dir, filepath.Base(label.ArchivePath))
+
+		if prev, ok := entries[label.ContentHash]; ok {
+			if prev.Label != entry.Label {
+				conflicts[label.ContentHash] = true
+			}
+			// 保留最新的标注
+			entries[label.ContentHash] = entry
+			continue
+		}
+		entries[label.ContentHash] = entry
+		order = append(order, label.ContentHash)
+	}
+
+	for _, dir := range []string{"benign", "malicious"} {
+		if err := os.MkdirAll(filepath.Join(*output, dir), 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %v", err)
+		}
+	}
+
+	manifestFile, err := os.Create(filepath.Join(*output, "manifest.jsonl"))
+	if err != nil {
+		return fmt.Errorf("failed to create manifest: %v", err)
+	}
+	defer manifestFile.Close()
+	manifest := bufio.NewWriter(manifestFile)
+	enc := json.NewEncoder(manifest)
+
+	var samples []dataset.Sample
+	counts := make(map[int]int)
+	for _, hash := range order {
+		if conflicts[hash] {
+			log.Printf("Warning: Skipping %s: conflicting verdicts", hash)
+			continue
+		}
+		entry := entries[hash]
+
+		dst := filepath.Join(*output, entry.File)
+		if err := result.CopyArchived(entry.archivePath, dst); err != nil {
+			log.Printf("Warning: Skipping result %d: %v", entry.ResultID, err)
+			continue
+		}
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to write manifest: %v", err)
+		}
+		samples = append(samples, dataset.Sample{Path: dst, Label: entry.Label})
+		counts[entry.Label]++
+	}
+	if err := manifest.Flush(); err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+
+	if *featuresPath != "" {
+		if err := writeFeatureCSV(*featuresPath, samples); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Exported %d labeled samples to %s (%d malicious, %d benign; %d without archived content, %d conflicting)",
+		len(samples), *output, counts[dataset.LabelMalicious], counts[dataset.LabelBenign], skipped, len(conflicts))
+	return nil
+}
+
+// writeFeatureCSV 提取样本特征并写入 CSV 文件
+func writeFeatureCSV(path string, samples []dataset.Sample) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create features file: %v", err)
+	}
+	defer file.Close()
+	buffered := bufio.NewWriter(file)
+
+	writer, err := dataset.NewRecordWriter(buffered, "csv")
+	if err != nil {
+		return err
+	}
+	for _, sample := range samples {
+		record, err := dataset.NewRecord(sample)
+		if err != nil {
+			log.Printf("Warning: Skipping %s: %v", sample.Path, err)
+			continue
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write record: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write features: %v", err)
+	}
+	return buffered.Flush()
+}
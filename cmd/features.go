@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"webshell-detector/internal/config"
+	"webshell-detector/internal/dataset"
+	"webshell-detector/pkg/mlmodel"
+)
+
+// runFeaturesCommand 导出带标签语料的特征向量，供模型训练使用
+func runFeaturesCommand(args []string) error {
+	fs := flag.NewFlagSet("features", flag.ExitOnError)
+	configPath := fs.String("config", "configs/config.yaml", "Path to config file")
+	format := fs.String("format", "csv", "Output format: csv/jsonl")
+	output := fs.String("output", "", "Output file (default stdout)")
+	var benignDirs, maliciousDirs stringList
+	fs.Var(&benignDirs, "benign", "Directory of benign samples (repeatable)")
+	fs.Var(&maliciousDirs, "malicious", "Directory of webshell samples (repeatable)")
+	fs.Parse(args)
+
+	if len(benignDirs) == 0 && len(maliciousDirs) == 0 {
+		return fmt.Errorf("specify at least one -benign or -malicious directory")
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %v", err)
+	}
+
+	corpus := dataset.Corpus{
+		BenignDirs:    benignDirs,
+		MaliciousDirs: maliciousDirs,
+		FileTypes:     cfg.Scan.FileTypes,
+		MaxFileSize:   cfg.Scan.Schedule.MaxFileSize,
+	}
+	samples, err := corpus.Collect()
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	buffered := bufio.NewWriter(out)
+	defer buffered.Flush()
+
+	writer, err := dataset.NewRecordWriter(buffered, *format)
+	if err != nil {
+		return err
+	}
+
+	exported := 0
+	for _, sample := range samples {
+		record, err := dataset.NewRecord(sample)
+		if err != nil {
+			log.Printf("Warning: Skipping %s: %v", sample.Path, err)
+			continue
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write record: %v", err)
+		}
+		exported++
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush output: %v", err)
+	}
+
+	log.Printf("Exported %d samples (schema v%d, %d features)", exported, mlmodel.FeatureSchemaVersion, mlmodel.FeatureCount)
+	return nil
+}
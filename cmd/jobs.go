@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"webshell-detector/internal/scanjob"
+)
+
+// controlTimeout 发出控制请求后等待任务确认的时间
+const controlTimeout = 5 * time.Second
+
+// runJobsCommand 查看和控制扫描任务：list 列出任务，status 查看进度，pause/resume/cancel 暂停、恢复、取消
+func runJobsCommand(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		args = append([]string{"list"}, args...)
+	}
+	action, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("jobs "+action, flag.ExitOnError)
+	dir := fs.String("dir", scanjob.DefaultDir, "Scan job state directory (scan.job_dir)")
+
+	switch action {
+	case "list":
+		all := fs.Bool("all", false, "Include finished jobs")
+		fs.Parse(args)
+		snapshots, err := scanjob.List(*dir)
+		if err != nil {
+			return err
+		}
+		printJobs(snapshots, *all)
+		return nil
+
+	case "status":
+		id := fs.String("id", "", "ID of the scan job")
+		fs.Parse(args)
+		if *id == "" {
+			return fmt.Errorf("specify -id (use jobs list to find IDs)")
+		}
+		snapshot, err := scanjob.Load(*dir, *id)
+		if err != nil {
+			return err
+		}
+		printJobStatus(snapshot)
+		return nil
+
+	case scanjob.ControlPause, scanjob.ControlResume, scanjob.ControlCancel:
+		id := fs.String("id", "", "ID of the scan job")
+		fs.Parse(args)
+		if *id == "" {
+			return fmt.Errorf("specify -id (use jobs list to find IDs)")
+		}
+		if err := scanjob.RequestControl(*dir, *id, action); err != nil {
+			return err
+		}
+		snapshot, err := waitForControl(*dir, *id, action)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Job %s is %s: %s\n", snapshot.ID, snapshot.Status, snapshot.Progress)
+		return nil
+	}
+	return fmt.Errorf("unknown jobs action %q (available: list, status, pause, resume, cancel)", action)
+}
+
+// waitForControl 等待任务所在进程处理控制请求
+func waitForControl(dir, id, action string) (*scanjob.Snapshot, error) {
+	deadline := time.Now().Add(controlTimeout)
+	for {
+		snapshot, err := scanjob.Load(dir, id)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case action == scanjob.ControlPause && snapshot.Status == scanjob.StatusPaused,
+			action == scanjob.ControlResume && snapshot.Status == scanjob.StatusRunning,
+			action == scanjob.ControlCancel && snapshot.Status != scanjob.StatusRunning && snapshot.Status != scanjob.StatusPaused,
+			!snapshot.Active():
+			return snapshot, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("job %s did not acknowledge the %s request within %v (still %s)", id, action, controlTimeout, snapshot.Status)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// printJobs 打印任务列表
+func printJobs(snapshots []*scanjob.Snapshot, all bool) {
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tPID\tSTARTED\tFILES\tDONE\tRATE\tETA")
+	shown := 0
+	for _, s := range snapshots {
+		if !all && !s.Active() {
+			continue
+		}
+		shown++
+		p := s.Progress
+		done := "-"
+		if percent := p.Percent(); percent >= 0 {
+			done = fmt.Sprintf("%.1f%%", percent)
+		}
+		eta := "-"
+		if p.ETA > 0 && s.Active() {
+			eta = p.ETA.Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%d/%d\t%s\t%.1f/s\t%s\n",
+			s.ID, jobStatus(s, now), s.PID, s.StartTime.Format("2006-01-02 15:04:05"),
+			p.Scanned+p.Failed, p.Discovered-p.Skipped, done, p.FilesPerSec, eta)
+	}
+	w.Flush()
+	if shown == 0 {
+		if all {
+			fmt.Println("No scan jobs found.")
+		} else {
+			fmt.Println("No running scan jobs (use -all to include finished jobs).")
+		}
+	}
+}
+
+// printJobStatus 打印单个任务的详细进度
+func printJobStatus(s *scanjob.Snapshot) {
+	p := s.Progress
+	fmt.Printf("Job:\t\t%s\n", s.ID)
+	fmt.Printf("Type:\t\t%s\n", s.Type)
+	if s.Name != "" {
+		fmt.Printf("Name:\t\t%s\n", s.Name)
+	}
+	fmt.Printf("Status:\t\t%s\n", jobStatus(s, time.Now()))
+	fmt.Printf("PID:\t\t%d\n", s.PID)
+	fmt.Printf("Targets:\t%s\n", strings.Join(s.Targets, ", "))
+	fmt.Printf("Started:\t%s\n", s.StartTime.Format("2006-01-02 15:04:05"))
+	if !s.EndTime.IsZero() {
+		fmt.Printf("Finished:\t%s\n", s.EndTime.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Printf("Elapsed:\t%v (excluding pauses)\n", p.Elapsed.Round(time.Second))
+	fmt.Printf("Discovered:\t%d files (walk finished: %v)\n", p.Discovered, p.WalkDone)
+	fmt.Printf("Scanned:\t%d\n", p.Scanned)
+	fmt.Printf("Skipped:\t%d (unchanged)\n", p.Skipped)
+	fmt.Printf("Failed:\t\t%d\n", p.Failed)
+	fmt.Printf("Bytes:\t\t%s of %s\n", scanjob.FormatBytes(p.BytesScanned), scanjob.FormatBytes(p.BytesDiscovered))
+	fmt.Printf("Rate:\t\t%.1f files/s, %s/s\n", p.FilesPerSec, scanjob.FormatBytes(int64(p.BytesPerSec)))
+	if p.ETA > 0 && s.Active() {
+		fmt.Printf("ETA:\t\t%v\n", p.ETA.Round(time.Second))
+	}
+	fmt.Printf("Updated:\t%s\n", s.UpdatedAt.Format("2006-01-02 15:04:05"))
+}
+
+// jobStatus 任务状态，长时间未更新的运行中任务标记为 stale
+func jobStatus(s *scanjob.Snapshot, now time.Time) string {
+	if s.Stale(now) {
+		return s.Status + " (stale)"
+	}
+	return s.Status
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"webshell-detector/internal/quarantine"
+	"webshell-detector/internal/result"
+)
+
+// runLabelCommand 记录分析人员对检测结果的结论，或列出待标注的结果
+func runLabelCommand(args []string) error {
+	fs := flag.NewFlagSet("label", flag.ExitOnError)
+	dbPath := fs.String("db", result.DefaultDBPath, "Path of the result database")
+	archiveDir := fs.String("archive", result.DefaultArchiveDir, "Directory where labeled file content is archived")
+	vaultDir := fs.String("quarantine", quarantine.DefaultDir, "Quarantine vault directory, searched when the file was quarantined or replaced since the scan")
+	keyFile := fs.String("key", "", "Vault encryption key file (default <quarantine>/vault.key)")
+	list := fs.Bool("list", false, "List stored results instead of labeling")
+	all := fs.Bool("all", false, "With -list: include SAFE results")
+	unlabeled := fs.Bool("unlabeled", false, "With -list: only show results without a label")
+	limit := fs.Int("limit", 20, "With -list: maximum results shown (0 = all)")
+	id := fs.Int64("id", 0, "ID of the result to label")
+	file := fs.String("file", "", "Label the latest result of this file instead of -id")
+	verdict := fs.String("verdict", "", "Analyst verdict: confirmed/false_positive")
+	family := fs.String("family", "", "Webshell family, e.g. chopper")
+	notes := fs.String("notes", "", "Free-form analyst notes")
+	analyst := fs.String("analyst", os.Getenv("USER"), "Name of the analyst")
+	fs.Parse(args)
+
+	storage, err := result.NewStorage(*dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open result storage: %v", err)
+	}
+	defer storage.Close()
+
+	if *list {
+		results, err := storage.ListResults(*limit, !*all, *unlabeled)
+		if err != nil {
+			return err
+		}
+		printStoredResults(results)
+		return nil
+	}
+
+	if *id == 0 && *file == "" {
+		return fmt.Errorf("specify -id or -file (use -list to find result IDs)")
+	}
+	if !result.ValidVerdict(*verdict) {
+		return fmt.Errorf("-verdict must be %s or %s", result.VerdictConfirmed, result.VerdictFalsePositive)
+	}
+
+	resultID := *id
+	if resultID == 0 {
+		path, err := filepath.Abs(*file)
+		if err != nil {
+			return err
+		}
+		// 结果按扫描时传入的路径存储，先按原样查找再按绝对路径查找
+		resultID, err = storage.LatestResultID(*file)
+		if err != nil {
+			if resultID, err = storage.LatestResultID(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	label := &result.Label{
+		ResultID: resultID,
+		Verdict:  *verdict,
+		Family:   *family,
+		Notes:    *notes,
+		Analyst:  *analyst,
+	}
+	source, closeVault := vaultSource(*vaultDir, *keyFile)
+	defer closeVault()
+	if err := storage.LabelResult(label, *archiveDir, source); err != nil {
+		return err
+	}
+
+	if label.ArchivePath != "" {
+		log.Printf("Labeled result %d as %s, content archived to %s", resultID, label.Verdict, label.ArchivePath)
+	} else {
+		log.Printf("Labeled result %d as %s (scanned content not available, excluded from exports)", resultID, label.Verdict)
+	}
+	return nil
+}
+
+// vaultSource 从隔离区取回检测时的文件内容，隔离区在首次需要时打开，不存在时不会创建
+func vaultSource(dir, keyFile string) (result.ContentSource, func()) {
+	var vault *quarantine.Vault
+	source := func(hash string) ([]byte, error) {
+		if vault == nil {
+			if _, err := os.Stat(filepath.Join(dir, "vault.db")); err != nil {
+				return nil, fmt.Errorf("no quarantine vault in %s", dir)
+			}
+			v, err := quarantine.Open(dir, keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open quarantine vault: %v", err)
+			}
+			vault = v
+		}
+		return vault.Content(hash)
+	}
+	return source, func() {
+		if vault != nil {
+			vault.Close()
+		}
+	}
+}
+
+// printStoredResults 以表格形式打印检测结果及标注
+func printStoredResults(results []*result.StoredResult) {
+	if len(results) == 0 {
+		fmt.Println("No results found.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSCAN TIME\tRISK\tSCORE\tWEBSHELL\tLABEL\tFAMILY\tFILE")
+	for _, r := range results {
+		verdict, family := "-", "-"
+		if r.Label != nil {
+			verdict = r.Label.Verdict
+			if r.Label.Family != "" {
+				family = r.Label.Family
+			}
+		}
+		path := r.FilePath
+		if r.ParentPath != "" {
+			path += " (dropped by " + r.ParentPath + ")"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%.2f\t%v\t%s\t%s\t%s\n",
+			r.ID, r.ScanTime.Format("2006-01-02 15:04:05"), r.RiskLevel, r.TotalScore, r.IsWebshell, verdict, family, path)
+	}
+	w.Flush()
+}
@@ -4,6 +4,8 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 	"time"
 
 	"webshell-detector/internal/config"
@@ -13,17 +15,33 @@ import (
 )
 
 func main() {
+	// 子命令（如 features）单独解析参数
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		runSubcommand(os.Args[1], os.Args[2:])
+		return
+	}
+
 	// 解析命令行参数
 	configPath := flag.String("config", "configs/config.yaml", "Path to config file")
-	filePath := flag.String("file", "", "Path to file to scan")
 	scanMode := flag.String("mode", "manual", "Scan mode: manual/realtime/scheduled")
+	var targets, excludes stringList
+	flag.Var(&targets, "file", "File, directory or glob pattern to scan in manual mode (repeatable, or pass as arguments)")
+	flag.Var(&excludes, "exclude", "Additional directory or glob pattern to exclude (repeatable)")
+	fileTypes := flag.String("types", "", "Comma-separated file extensions to scan, overrides scan.file_types (e.g. .php,.jsp)")
+	workers := flag.Int("workers", 0, "Manual mode: number of concurrent workers (default scan.realtime.max_concurrency)")
+	fresh := flag.Bool("fresh", false, "Manual mode: ignore the checkpoint of an interrupted scan of the same targets and start over")
 	flag.Parse()
+	targets = append(targets, flag.Args()...)
 
 	// 加载配置
 	cfg, err := config.LoadConfig(*configPath)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	cfg.Scan.ExcludeDirs = append(cfg.Scan.ExcludeDirs, excludes...)
+	if *fileTypes != "" {
+		cfg.Scan.FileTypes = strings.Split(*fileTypes, ",")
+	}
 
 	// 初始化特征库
 	sigMgr, err := signature.NewManager(cfg.SignaturePath)
@@ -33,26 +51,25 @@ func main() {
 	defer sigMgr.Close()
 
 	// 初始化机器学习模型
-	model, err := mlmodel.LoadModel(cfg.ModelPath)
-	if err != nil {
-		log.Printf("Warning: Failed to load ML model: %v", err)
-	}
+	model := loadModel(cfg)
 
 	// 根据扫描模式选择不同的操作
 	switch *scanMode {
 	case "manual":
-		if *filePath == "" {
-			log.Fatal("Please specify a file to scan using -file flag")
+		if len(targets) == 0 {
+			log.Fatal("Please specify files, directories or glob patterns to scan using -file flag or arguments")
 		}
-		// 执行单文件扫描
-		handleManualScan(cfg, sigMgr, model, *filePath)
+		// 执行手动扫描
+		handleManualScan(cfg, sigMgr, model, targets, *workers, *fresh)
 
 	case "realtime":
 		// 启动实时扫描
+		model = startModelReload(cfg, model)
 		handleRealtimeScan(cfg, sigMgr, model)
 
 	case "scheduled":
 		// 启动定时扫描
+		model = startModelReload(cfg, model)
 		handleScheduledScan(cfg, sigMgr, model)
 
 	default:
@@ -60,15 +77,22 @@ func main() {
 	}
 }
 
-// handleManualScan 处理单文件扫描
-func handleManualScan(cfg *config.Config, sigMgr *signature.Manager, model *mlmodel.Model, filePath string) {
+// handleManualScan 处理手动扫描
+func handleManualScan(cfg *config.Config, sigMgr *signature.Manager, model *mlmodel.Model, targets []string, workers int, fresh bool) {
 	startTime := time.Now()
 
 	// 创建手动扫描器
-	manualScanner, err := scanner.NewManualScanner(cfg, sigMgr, model, filePath)
+	manualScanner, err := scanner.NewManualScanner(cfg, sigMgr, model, targets, workers)
 	if err != nil {
 		log.Fatalf("Failed to create manual scanner: %v", err)
 	}
+	manualScanner.SetFresh(fresh)
+
+	// SIGINT、SIGTERM 中断扫描，已完成检测的结果照常输出，启用断点续扫时保存断点
+	go func() {
+		waitForSignals()
+		manualScanner.Stop()
+	}()
 
 	// 执行扫描
 	if err := manualScanner.Start(); err != nil {
@@ -97,7 +121,10 @@ func handleRealtimeScan(cfg *config.Config, sigMgr *signature.Manager, model *ml
 	}
 
 	// 等待中断信号
-	select {}
+	waitForSignals()
+	if err := realtimeScanner.Stop(); err != nil {
+		log.Fatalf("Failed to stop realtime scan: %v", err)
+	}
 }
 
 // handleScheduledScan 处理定时扫描
@@ -112,6 +139,9 @@ func handleScheduledScan(cfg *config.Config, sigMgr *signature.Manager, model *m
 		log.Fatalf("Failed to start scheduled scan: %v", err)
 	}
 
-	// 等待中断信号
-	select {}
+	// 等待中断信号，正在进行的扫描被取消后退出
+	waitForSignals()
+	if err := scheduledScanner.Stop(); err != nil {
+		log.Fatalf("Failed to stop scheduled scan: %v", err)
+	}
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"webshell-detector/internal/config"
+	"webshell-detector/internal/dataset"
+	"webshell-detector/pkg/mlmodel"
+)
+
+// loadModel 加载检测使用的模型，与热加载执行相同的校验（校验和文件、金丝雀样本），
+// 加载失败时仅告警并返回 nil
+func loadModel(cfg *config.Config) *mlmodel.Model {
+	model := mlmodel.Unloaded(cfg.ModelPath)
+	configureModel(cfg, model)
+	if err := model.Load(); err != nil {
+		log.Printf("Warning: Failed to load ML model: %v", err)
+		return nil
+	}
+	return model
+}
+
+// configureModel 设置模型校验使用的金丝雀样本和配置的判定阈值
+func configureModel(cfg *config.Config, model *mlmodel.Model) {
+	canaries, err := loadCanaries(cfg.Detection.MachineLearning.CanaryDir)
+	if err != nil {
+		log.Printf("Warning: Failed to load canary samples: %v", err)
+	}
+	model.SetCanaries(canaries)
+	model.SetConfiguredThreshold(cfg.Detection.MachineLearning.Threshold, cfg.Detection.MachineLearning.OverrideThreshold)
+}
+
+// startModelReload 启动模型文件热加载，返回检测使用的模型；
+// 初始加载失败时同样监控模型文件，返回尚未加载的模型，文件修复后自动生效
+func startModelReload(cfg *config.Config, model *mlmodel.Model) *mlmodel.Model {
+	if !cfg.Detection.MachineLearning.HotReload || cfg.ModelPath == "" {
+		return model
+	}
+	if model == nil {
+		model = mlmodel.Unloaded(cfg.ModelPath)
+		configureModel(cfg, model)
+	}
+	canaries := model.CanaryCount()
+
+	if _, err := model.Watch(func(version string, err error) {
+		if err != nil {
+			log.Printf("Model reload failed: %v", err)
+			return
+		}
+		log.Printf("Model reloaded, active version %s", mlmodel.ShortVersion(version))
+	}); err != nil {
+		log.Printf("Warning: Model hot reload disabled: %v", err)
+		if !model.Loaded() {
+			return nil
+		}
+		return model
+	}
+	if !model.Loaded() {
+		log.Printf("Watching model %s, ML detection resumes once a valid model is written (%d canary samples)", model.Path, canaries)
+		return model
+	}
+	log.Printf("Watching model %s (version %s, %d canary samples)", model.Path, mlmodel.ShortVersion(model.Version()), canaries)
+	return model
+}
+
+// loadCanaries 从 <dir>/benign 和 <dir>/malicious 读取金丝雀样本并提取特征
+func loadCanaries(dir string) ([]mlmodel.Canary, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	corpus := dataset.Corpus{}
+	if benign := filepath.Join(dir, "benign"); dirExists(benign) {
+		corpus.BenignDirs = append(corpus.BenignDirs, benign)
+	}
+	if malicious := filepath.Join(dir, "malicious"); dirExists(malicious) {
+		corpus.MaliciousDirs = append(corpus.MaliciousDirs, malicious)
+	}
+	if len(corpus.BenignDirs) == 0 && len(corpus.MaliciousDirs) == 0 {
+		return nil, nil
+	}
+
+	samples, err := corpus.Collect()
+	if err != nil {
+		return nil, err
+	}
+
+	canaries := make([]mlmodel.Canary, 0, len(samples))
+	for _, sample := range samples {
+		record, err := dataset.NewRecord(sample)
+		if err != nil {
+			return nil, err
+		}
+		canaries = append(canaries, mlmodel.Canary{
+			Name:      sample.Path,
+			Features:  record.Features,
+			Malicious: sample.Label == dataset.LabelMalicious,
+		})
+	}
+	return canaries, nil
+}
+
+// dirExists 检查目录是否存在
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"webshell-detector/internal/quarantine"
+)
+
+// runQuarantineCommand 管理隔离区：add 隔离文件，list 列出隔离记录，restore 恢复文件，purge 永久删除隔离文件
+func runQuarantineCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: quarantine add|list|restore|purge [flags]")
+	}
+	action, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("quarantine "+action, flag.ExitOnError)
+	dir := fs.String("dir", quarantine.DefaultDir, "Quarantine vault directory")
+	keyFile := fs.String("key", "", "Vault encryption key file (default <dir>/vault.key)")
+
+	switch action {
+	case "add":
+		reason := fs.String("reason", "", "Why the files are quarantined")
+		analyst := fs.String("analyst", os.Getenv("USER"), "Name of the analyst")
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			return fmt.Errorf("specify the files to quarantine")
+		}
+		return withVault(*dir, *keyFile, func(vault *quarantine.Vault) error {
+			failed := 0
+			for _, path := range fs.Args() {
+				entry, err := vault.Quarantine(path, "", quarantine.Detail{Reason: *reason, Actor: *analyst})
+				if err != nil {
+					log.Printf("Error: %v", err)
+					failed++
+					continue
+				}
+				log.Printf("Quarantined %s (quarantine ID %d)", entry.OriginalPath, entry.ID)
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d files could not be quarantined", failed, fs.NArg())
+			}
+			return nil
+		})
+
+	case "list":
+		all := fs.Bool("all", false, "Include restored and purged entries")
+		status := fs.String("status", "", "Only list entries with this status: quarantined/restored/purged")
+		fs.Parse(args)
+		filter := *status
+		if filter == "" && !*all {
+			filter = quarantine.StatusQuarantined
+		}
+		return withVault(*dir, *keyFile, func(vault *quarantine.Vault) error {
+			entries, err := vault.List(filter)
+			if err != nil {
+				return err
+			}
+			printQuarantineEntries(entries)
+			return nil
+		})
+
+	case "restore":
+		id := fs.Int64("id", 0, "ID of the quarantine entry to restore")
+		to := fs.String("to", "", "Restore to this path instead of the original location")
+		force := fs.Bool("force", false, "Overwrite the destination if it exists")
+		fs.Parse(args)
+		if *id == 0 {
+			return fmt.Errorf("specify -id (use quarantine list to find IDs)")
+		}
+		return withVault(*dir, *keyFile, func(vault *quarantine.Vault) error {
+			entry, err := vault.Restore(*id, *to, *force)
+			if err != nil {
+				return err
+			}
+			log.Printf("Restored quarantine entry %d to %s", entry.ID, entry.RestoredTo)
+			return nil
+		})
+
+	case "purge":
+		id := fs.Int64("id", 0, "ID of the quarantine entry to purge")
+		olderThan := fs.Duration("older-than", 0, "Purge all entries quarantined longer ago than this duration")
+		fs.Parse(args)
+		if (*id == 0) == (*olderThan == 0) {
+			return fmt.Errorf("specify either -id or -older-than")
+		}
+		return withVault(*dir, *keyFile, func(vault *quarantine.Vault) error {
+			if *id != 0 {
+				if err := vault.Purge(*id); err != nil {
+					return err
+				}
+				log.Printf("Purged quarantine entry %d", *id)
+				return nil
+			}
+
+			entries, err := vault.List(quarantine.StatusQuarantined)
+			if err != nil {
+				return err
+			}
+			cutoff := time.Now().Add(-*olderThan)
+			purged := 0
+			for _, entry := range entries {
+				if entry.QuarantinedAt.After(cutoff) {
+					continue
+				}
+				if err := vault.Purge(entry.ID); err != nil {
+					log.Printf("Error: %v", err)
+					continue
+				}
+				purged++
+			}
+			log.Printf("Purged %d quarantine entries older than %v", purged, *olderThan)
+			return nil
+		})
+
+	default:
+		return fmt.Errorf("unknown quarantine action %q (expected add, list, restore or purge)", action)
+	}
+}
+
+// withVault 打开隔离区执行操作后关闭
+func withVault(dir, keyFile string, run func(vault *quarantine.Vault) error) error {
+	vault, err := quarantine.Open(dir, keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to open quarantine vault: %v", err)
+	}
+	defer vault.Close()
+	return run(vault)
+}
+
+// printQuarantineEntries 以表格形式打印隔离记录
+func printQuarantineEntries(entries []*quarantine.Entry) {
+	if len(entries) == 0 {
+		fmt.Println("No quarantine entries found.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tQUARANTINED\tSTATUS\tRISK\tSCORE\tSIZE\tOWNER\tMODE\tSHA256\tBY\tFILE")
+	for _, e := range entries {
+		risk := e.RiskLevel
+		if risk == "" {
+			risk = "-"
+		}
+		path := e.OriginalPath
+		if e.Status == quarantine.StatusRestored && e.RestoredTo != e.OriginalPath {
+			path += " (restored to " + e.RestoredTo + ")"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%.2f\t%d\t%d:%d\t%v\t%s\t%s\t%s\n",
+			e.ID, e.QuarantinedAt.Format("2006-01-02 15:04:05"), e.Status, risk, e.TotalScore, e.Size,
+			e.UID, e.GID, e.Mode, e.SHA256[:12], e.Actor, path)
+	}
+	w.Flush()
+}
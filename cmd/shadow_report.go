@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"webshell-detector/internal/result"
+)
+
+// runShadowReportCommand 汇总影子模型和规则集与当前引擎的判定分歧
+func runShadowReportCommand(args []string) error {
+	fs := flag.NewFlagSet("shadow-report", flag.ExitOnError)
+	dbPath := fs.String("db", result.DefaultDBPath, "Path of the result database")
+	since := fs.Duration("since", 30*24*time.Hour, "Only include results scanned within this duration")
+	period := fs.String("period", "day", "Aggregation period: day/week/month")
+	maxListed := fs.Int("list", 20, "Maximum recent disagreements listed (0 = all)")
+	fs.Parse(args)
+
+	storage, err := result.NewStorage(*dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open result storage: %v", err)
+	}
+	defer storage.Close()
+
+	start := time.Now().Add(-*since)
+	summaries, err := storage.ShadowSummaries(start, *period)
+	if err != nil {
+		return err
+	}
+	if len(summaries) == 0 {
+		fmt.Printf("No shadow results since %s.\n", start.Format("2006-01-02 15:04"))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SHADOW\tKIND\tPERIOD\tSCANS\tDISAGREE\tRATE\tSHADOW-ONLY\tACTIVE-ONLY\tRISK-CHANGED\tERRORS\tMEAN DELTA")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%.2f%%\t%d\t%d\t%d\t%d\t%+.2f\n",
+			s.Name, s.Kind, s.Period, s.Total, s.Disagreements, s.DisagreementRate()*100,
+			s.ShadowOnly, s.ActiveOnly, s.RiskChanges, s.Errors, s.MeanScoreDelta)
+	}
+	w.Flush()
+
+	disagreements, err := storage.ShadowDisagreements(start, *maxListed)
+	if err != nil {
+		return err
+	}
+	if len(disagreements) == 0 {
+		return nil
+	}
+
+	fmt.Println("\nRecent disagreements:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSCAN TIME\tSHADOW\tACTIVE VERDICT\tSHADOW VERDICT\tFILE")
+	for _, d := range disagreements {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s %.2f (webshell=%v)\t%s %.2f (webshell=%v)\t%s\n",
+			d.ResultID, d.ScanTime.Format("2006-01-02 15:04:05"), d.Name,
+			d.ActiveRiskLevel, d.ActiveTotalScore, d.ActiveIsWebshell,
+			d.ShadowRiskLevel, d.ShadowTotalScore, d.ShadowIsWebshell,
+			d.FilePath)
+	}
+	w.Flush()
+	return nil
+}
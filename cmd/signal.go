@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"webshell-detector/internal/scanjob"
+	"webshell-detector/internal/scanner"
+)
+
+// waitForSignals 处理暂停、恢复信号，收到 SIGINT 或 SIGTERM 时返回；
+// 此后再次收到停止信号时立即退出，不再等待扫描收尾
+func waitForSignals() {
+	notify := []os.Signal{os.Interrupt, syscall.SIGTERM}
+	for sig := range controlSignals {
+		notify = append(notify, sig)
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, notify...)
+
+	for sig := range signals {
+		if handleControlSignal(sig) {
+			continue
+		}
+		log.Printf("Received %v, stopping (send again to exit immediately)", sig)
+		go func() {
+			for sig := range signals {
+				if !handleControlSignal(sig) {
+					log.Printf("Received %v again, exiting", sig)
+					os.Exit(1)
+				}
+			}
+		}()
+		return
+	}
+}
+
+// handleControlSignal 暂停或恢复本进程中全部扫描任务，不是控制信号时返回 false
+func handleControlSignal(sig os.Signal) bool {
+	action, ok := controlSignals[sig]
+	if !ok {
+		return false
+	}
+	count := 0
+	for _, job := range scanner.ActiveJobs() {
+		if action == scanjob.ControlPause && job.Pause() {
+			count++
+		} else if action == scanjob.ControlResume && job.Resume() {
+			count++
+		}
+	}
+	log.Printf("Received %v (%s), %d scan job(s) affected", sig, action, count)
+	return true
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+
+	"webshell-detector/internal/scanjob"
+)
+
+// controlSignals SIGUSR1 暂停、SIGUSR2 恢复本进程中的扫描任务
+var controlSignals = map[os.Signal]string{
+	syscall.SIGUSR1: scanjob.ControlPause,
+	syscall.SIGUSR2: scanjob.ControlResume,
+}
@@ -0,0 +1,6 @@
+package main
+
+import "os"
+
+// controlSignals Windows 没有 SIGUSR1、SIGUSR2，只能通过 jobs 命令暂停和恢复扫描任务
+var controlSignals = map[os.Signal]string{}
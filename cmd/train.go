@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"webshell-detector/internal/config"
+	"webshell-detector/internal/dataset"
+	"webshell-detector/internal/training"
+	"webshell-detector/pkg/mlmodel"
+)
+
+// runTrainCommand 使用带标签语料在本地训练模型，不依赖 Python 环境
+func runTrainCommand(args []string) error {
+	defaults := training.DefaultOptions()
+
+	fs := flag.NewFlagSet("train", flag.ExitOnError)
+	configPath := fs.String("config", "configs/config.yaml", "Path to config file")
+	output := fs.String("output", "", "Output model path (default model_path from config)")
+	algorithm := fs.String("algorithm", defaults.Algorithm, "Training algorithm: logistic/random_forest")
+	seed := fs.Int64("seed", defaults.Seed, "Random seed")
+	testSplit := fs.Float64("test-split", defaults.TestSplit, "Fraction of samples held out for evaluation")
+	threshold := fs.Float64("threshold", defaults.Threshold, "Decision threshold stored in the model")
+	epochs := fs.Int("epochs", defaults.Epochs, "Logistic regression: gradient descent epochs")
+	learningRate := fs.Float64("lr", defaults.LearningRate, "Logistic regression: learning rate")
+	l2 := fs.Float64("l2", defaults.L2, "Logistic regression: L2 regularization strength")
+	trees := fs.Int("trees", defaults.Trees, "Random forest: number of trees")
+	maxDepth := fs.Int("max-depth", defaults.MaxDepth, "Random forest: maximum tree depth")
+	minLeaf := fs.Int("min-leaf", defaults.MinSamplesLeaf, "Random forest: minimum samples per leaf")
+	var benignDirs, maliciousDirs stringList
+	fs.Var(&benignDirs, "benign", "Directory of benign samples (repeatable)")
+	fs.Var(&maliciousDirs, "malicious", "Directory of webshell samples (repeatable)")
+	fs.Parse(args)
+
+	if len(benignDirs) == 0 || len(maliciousDirs) == 0 {
+		return fmt.Errorf("both -benign and -malicious directories are required")
+	}
+
+	opts := training.Options{
+		Algorithm:      *algorithm,
+		Seed:           *seed,
+		TestSplit:      *testSplit,
+		Threshold:      *threshold,
+		Epochs:         *epochs,
+		LearningRate:   *learningRate,
+		L2:             *l2,
+		Trees:          *trees,
+		MaxDepth:       *maxDepth,
+		MinSamplesLeaf: *minLeaf,
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %v", err)
+	}
+	if *output == "" {
+		*output = cfg.ModelPath
+	}
+
+	// 收集样本并提取特征
+	corpus := dataset.Corpus{
+		BenignDirs:    benignDirs,
+		MaliciousDirs: maliciousDirs,
+		FileTypes:     cfg.Scan.FileTypes,
+		MaxFileSize:   cfg.Scan.Schedule.MaxFileSize,
+	}
+	samples, err := corpus.Collect()
+	if err != nil {
+		return err
+	}
+	ds, err := training.LoadDataset(samples)
+	if err != nil {
+		return err
+	}
+
+	// 切分训练集与测试集
+	trainSet, testSet := ds.Split(opts.TestSplit, opts.Seed)
+	log.Printf("Training %s model on %d samples (%d held out, feature schema v%d)...",
+		opts.Algorithm, len(trainSet.X), len(testSet.X), mlmodel.FeatureSchemaVersion)
+
+	startTime := time.Now()
+	modelData, err := training.Train(trainSet, opts)
+	if err != nil {
+		return err
+	}
+	log.Printf("Training completed in %v", time.Since(startTime))
+
+	// 保存前按加载模型时的校验检查模型数据，没有留出集时同样检查
+	model, err := mlmodel.NewModel(modelData)
+	if err != nil {
+		return fmt.Errorf("trained model is invalid: %v", err)
+	}
+
+	// 留出集评估
+	if len(testSet.X) > 0 {
+		metrics, err := training.Evaluate(model, testSet, opts.Threshold)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate model: %v", err)
+		}
+		fmt.Printf("\nHold-out evaluation (%d samples):\n", metrics.Samples)
+		fmt.Printf("  Accuracy:  %.4f\n", metrics.Accuracy)
+		fmt.Printf("  Precision: %.4f\n", metrics.Precision)
+		fmt.Printf("  Recall:    %.4f\n", metrics.Recall)
+		fmt.Printf("  F1:        %.4f\n\n", metrics.F1)
+	}
+
+	if err := mlmodel.SaveModel(*output, modelData); err != nil {
+		return err
+	}
+	log.Printf("Model saved to %s", *output)
+	return nil
+}
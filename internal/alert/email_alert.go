@@ -124,6 +124,7 @@ func (e *EmailAlert) generateEmailBody(result *detector.DetectionResult) (string
         <li><strong>Risk Level:</strong> <span class="{{.RiskLevelClass}}">{{.RiskLevel}}</span></li>
         <li><strong>Detection Time:</strong> {{.Time}}</li>
         <li><strong>Total Score:</strong> {{printf "%.2f" .TotalScore}}</li>
+        {{if .Process}}<li><strong>Written By:</strong> pid {{.Process.PID}}, uid {{.Process.UID}}, {{.Process.Exe}}</li>{{end}}
     </ul>
     
     <div class="details">
@@ -131,10 +132,21 @@ func (e *EmailAlert) generateEmailBody(result *detector.DetectionResult) (string
         <ul>
             <li>Feature Score: {{printf "%.2f" .FeatureScore}}</li>
             <li>Behavior Score: {{printf "%.2f" .BehaviorScore}}</li>
-            <li>ML Score: {{printf "%.2f" .MLScore}}</li>
+            <li>ML Score: {{printf "%.2f" .MLScore}}{{if .MLVerdict}} (above threshold){{end}}</li>
         </ul>
     </div>
 
+    {{if .MLTopFeatures}}
+    <div class="features">
+        <h3>Top ML Features:</h3>
+        <ul>
+            {{range .MLTopFeatures}}
+            <li>{{.Name}} = {{printf "%.4g" .Value}} ({{printf "%+.4f" .Contribution}})</li>
+            {{end}}
+        </ul>
+    </div>
+    {{end}}
+
     {{if .MatchedFeatures}}
     <div class="features">
         <h3>Matched Features:</h3>
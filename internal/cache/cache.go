@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// pruneEvery 每写入多少条记录检查一次缓存上限
+const pruneEvery = 1000
+
+// Cache 以文件内容哈希为键的检测结果缓存
+type Cache struct {
+	db         *sql.DB
+	maxEntries int           // 记录上限，0 表示不限制
+	maxAge     time.Duration // 最后一次写入或命中后的保留时长，0 表示不限制
+
+	mu   sync.Mutex
+	puts int // 上次淘汰后写入的记录数
+}
+
+// NewCache 创建结果缓存，超出 maxEntries 时淘汰最久未命中的记录，
+// 超过 maxAge 未写入或命中的记录被删除，两者为 0 时不做相应的淘汰
+func NewCache(dbPath string, maxEntries int, maxAge time.Duration) (*Cache, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+
+	if err := initCacheDatabase(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize database: %v", err)
+	}
+
+	c := &Cache{db: db, maxEntries: maxEntries, maxAge: maxAge}
+	if _, err := c.Prune(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// initCacheDatabase 初始化缓存数据库
+func initCacheDatabase(db *sql.DB) error {
+	createTable := `
+	CREATE TABLE IF NOT EXISTS detection_cache (
+		content_hash TEXT NOT NULL,
+		engine_version TEXT NOT NULL,
+		result TEXT NOT NULL,
+		create_time DATETIME DEFAULT CURRENT_TIMESTAMP,
+		hit_count INTEGER DEFAULT 0,
+		last_hit DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (content_hash, engine_version)
+	);
+	CREATE INDEX IF NOT EXISTS idx_engine_version ON detection_cache(engine_version);
+	`
+
+	if _, err := db.Exec(createTable); err != nil {
+		return err
+	}
+
+	// 旧版本数据库补齐 last_hit 列，已有记录以写入时间代替
+	var columns int
+	if err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('detection_cache') WHERE name = 'last_hit'").Scan(&columns); err != nil {
+		return err
+	}
+	if columns == 0 {
+		if _, err := db.Exec("ALTER TABLE detection_cache ADD COLUMN last_hit DATETIME"); err != nil {
+			return fmt.Errorf("failed to add column last_hit: %v", err)
+		}
+		if _, err := db.Exec("UPDATE detection_cache SET last_hit = create_time"); err != nil {
+			return fmt.Errorf("failed to fill column last_hit: %v", err)
+		}
+	}
+
+	_, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_last_hit ON detection_cache(last_hit)")
+	return err
+}
+
+// Get 查询缓存，返回缓存的结果数据以及是否命中
+func (c *Cache) Get(contentHash, engineVersion string) ([]byte, bool, error) {
+	var payload string
+	err := c.db.QueryRow(`
+		SELECT result FROM detection_cache
+		WHERE content_hash = ? AND engine_version = ?
+	`, contentHash, engineVersion).Scan(&payload)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to query cache: %v", err)
+	}
+
+	if _, err := c.db.Exec(`
+		UPDATE detection_cache SET hit_count = hit_count + 1, last_hit = CURRENT_TIMESTAMP
+		WHERE content_hash = ? AND engine_version = ?
+	`, contentHash, engineVersion); err != nil {
+		return nil, false, fmt.Errorf("failed to update hit count: %v", err)
+	}
+
+	return []byte(payload), true, nil
+}
+
+// Put 写入缓存，已存在的记录会被覆盖；每写入 pruneEvery 条记录淘汰一次超出上限的记录
+func (c *Cache) Put(contentHash, engineVersion string, payload []byte) error {
+	_, err := c.db.Exec(`
+		INSERT OR REPLACE INTO detection_cache (content_hash, engine_version, result, last_hit)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+	`, contentHash, engineVersion, string(payload))
+	if err != nil {
+		return fmt.Errorf("failed to store cache entry: %v", err)
+	}
+
+	c.mu.Lock()
+	c.puts++
+	prune := c.puts >= pruneEvery
+	if prune {
+		c.puts = 0
+	}
+	c.mu.Unlock()
+	if prune {
+		if _, err := c.Prune(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prune 删除超过 maxAge 未写入或命中的记录，记录数超出 maxEntries 时删除最久未命中的记录，返回删除的记录数
+func (c *Cache) Prune() (int64, error) {
+	var removed int64
+	if c.maxAge > 0 {
+		cutoff := time.Now().Add(-c.maxAge).UTC().Format("2006-01-02 15:04:05")
+		res, err := c.db.Exec("DELETE FROM detection_cache WHERE last_hit < ?", cutoff)
+		if err != nil {
+			return 0, fmt.Errorf("failed to prune expired cache entries: %v", err)
+		}
+		n, _ := res.RowsAffected()
+		removed += n
+	}
+
+	if c.maxEntries > 0 {
+		var count int
+		if err := c.db.QueryRow("SELECT COUNT(*) FROM detection_cache").Scan(&count); err != nil {
+			return removed, fmt.Errorf("failed to count cache entries: %v", err)
+		}
+		if excess := count - c.maxEntries; excess > 0 {
+			res, err := c.db.Exec(`
+				DELETE FROM detection_cache WHERE rowid IN (
+					SELECT rowid FROM detection_cache ORDER BY last_hit ASC LIMIT ?
+				)
+			`, excess)
+			if err != nil {
+				return removed, fmt.Errorf("failed to evict cache entries: %v", err)
+			}
+			n, _ := res.RowsAffected()
+			removed += n
+		}
+	}
+	return removed, nil
+}
+
+// Invalidate 删除不属于当前引擎版本的缓存记录，返回删除的记录数
+func (c *Cache) Invalidate(currentVersion string) (int64, error) {
+	res, err := c.db.Exec("DELETE FROM detection_cache WHERE engine_version != ?", currentVersion)
+	if err != nil {
+		return 0, fmt.Errorf("failed to invalidate cache: %v", err)
+	}
+	return res.RowsAffected()
+}
+
+// Close 关闭缓存
+func (c *Cache) Close() error {
+	return c.db.Close()
+}
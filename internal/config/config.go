@@ -5,6 +5,8 @@ import (
 	"os"
 	"time"
 
+	"webshell-detector/internal/schedule"
+
 	"gopkg.in/yaml.v3"
 )
 
@@ -18,6 +20,10 @@ type Config struct {
 	Alert AlertConfig `yaml:"alert"`
 	// 存储配置
 	Storage StorageConfig `yaml:"storage"`
+	// 隔离区配置
+	Quarantine QuarantineConfig `yaml:"quarantine"`
+	// 自动响应配置
+	Response ResponseConfig `yaml:"response"`
 	// 路径配置
 	SignaturePath string `yaml:"signature_path"` // 特征库路径
 	ModelPath     string `yaml:"model_path"`     // 机器学习模型路径
@@ -37,20 +43,70 @@ type ScanConfig struct {
 
 	// 文件类型配置
 	FileTypes []string `yaml:"file_types"` // 需要扫描的文件类型，如 [".php", ".jsp", ".asp"]
+
+	// 扫描流水线配置
+	Pipeline PipelineConfig `yaml:"pipeline"`
+
+	// 扫描任务状态目录，jobs 命令从这里读取进度并发出暂停、恢复和取消请求，默认 data/jobs
+	JobDir string `yaml:"job_dir"`
+
+	// 断点续扫配置
+	Checkpoint CheckpointConfig `yaml:"checkpoint"`
+}
+
+// CheckpointConfig 断点续扫配置：手动和定时扫描定期把遍历位置和已完成的文件写入 storage.database.path，
+// 扫描被中断后，下次扫描相同范围时从断点继续
+type CheckpointConfig struct {
+	Enabled  bool          `yaml:"enabled"`  // 是否启用断点续扫
+	Interval time.Duration `yaml:"interval"` // 写入断点的间隔，默认 30s
+}
+
+// PipelineConfig 扫描流水线配置：遍历 → 有界文件队列 → 分析协程 → 批量打分 → 结果输出
+type PipelineConfig struct {
+	QueueSize       int `yaml:"queue_size"`        // 待分析文件队列容量，队列满时遍历和文件事件处理阻塞等待
+	Workers         int `yaml:"workers"`           // 分析协程数，为 0 时使用 realtime.max_concurrency
+	ResultQueueSize int `yaml:"result_queue_size"` // 已分析待打分结果的队列容量
+	AlertWorkers    int `yaml:"alert_workers"`     // 发送告警的协程数
+	AlertQueueSize  int `yaml:"alert_queue_size"`  // 待发送告警队列容量，队列满时丢弃告警并记录日志
 }
 
 // ScheduleConfig 定时扫描配置
 type ScheduleConfig struct {
 	Enabled     bool          `yaml:"enabled"`      // 是否启用定时扫描
-	Interval    time.Duration `yaml:"interval"`     // 扫描间隔
-	StartTime   string        `yaml:"start_time"`   // 首次扫描时间
+	Interval    time.Duration `yaml:"interval"`     // 扫描间隔，未配置 jobs 时使用
+	StartTime   string        `yaml:"start_time"`   // 首次扫描时间，未配置 jobs 时使用
 	MaxFileSize int64         `yaml:"max_filesize"` // 最大文件大小限制(bytes)
+	Incremental bool          `yaml:"incremental"`  // 增量扫描：跳过上次扫描后未变化的文件，作为任务 depth 的默认值
+	StateIndex  string        `yaml:"state_index"`  // 文件状态索引路径，默认 data/filestate.db
+	StatePath   string        `yaml:"state_path"`   // 各任务上次运行时间的保存路径，默认 data/schedule_state.json
+	Jobs        []ScheduleJob `yaml:"jobs"`         // 定时扫描任务，为空时按 start_time 和 interval 运行一个 default 任务
+}
+
+// ScheduleJob 一个定时扫描任务
+type ScheduleJob struct {
+	Name        string        `yaml:"name"`        // 任务名称，用于日志、扫描历史和保存运行时间，不能重复
+	Cron        string        `yaml:"cron"`        // cron 表达式（分 时 日 月 周），也支持 @daily、@hourly、@every 6h
+	Directories []string      `yaml:"directories"` // 扫描目录，为空时使用 scan.directories
+	FileTypes   []string      `yaml:"file_types"`  // 文件类型，为空时使用 scan.file_types
+	Depth       string        `yaml:"depth"`       // quick 跳过未变化的文件，full 重新扫描全部文件；默认由 incremental 决定
+	Jitter      time.Duration `yaml:"jitter"`      // 每次运行随机推迟 0 到 jitter，避免多台主机同时扫描
+	SkipMissed  bool          `yaml:"skip_missed"` // 停机期间错过的运行不补跑
 }
 
 // RealtimeConfig 实时扫描配置
 type RealtimeConfig struct {
-	Enabled        bool `yaml:"enabled"`         // 是否启用实时扫描
-	MaxConcurrency int  `yaml:"max_concurrency"` // 最大并发扫描数
+	Enabled        bool          `yaml:"enabled"`         // 是否启用实时扫描
+	MaxConcurrency int           `yaml:"max_concurrency"` // 最大并发扫描数
+	QuietPeriod    time.Duration `yaml:"quiet_period"`    // 文件最后一次事件后等待的静默期，默认 1s
+	MaxDelay       time.Duration `yaml:"max_delay"`       // 文件持续变化时最长推迟扫描的时间，默认 30s
+
+	// 监控后端：inotify（默认）、fanotify 或 poll；fanotify 按挂载点监控写入并记录写入进程，需要 Linux 和 CAP_SYS_ADMIN，
+	// 新建、移入和权限变化仍由同时运行的 inotify 报告；
+	// poll 定期比较文件快照，用于 inotify 不生效的 NFS 和 overlay 文件系统
+	Backend           string        `yaml:"backend"`
+	PollInterval      time.Duration `yaml:"poll_interval"`      // poll：轮询间隔，默认 10s；inotify 不可用或监控数耗尽时的轮询也使用该间隔
+	BlockPending      bool          `yaml:"block_pending"`      // fanotify：文件得出结论前阻止其他进程打开，判定为 webshell 时拒绝
+	PermissionTimeout time.Duration `yaml:"permission_timeout"` // fanotify：等待结论的最长时间，超时放行，默认 10s
 }
 
 // DetectionConfig 检测算法相关配置
@@ -66,17 +122,36 @@ type DetectionConfig struct {
 		Enabled     bool  `yaml:"enabled"`    // 是否启用行为分析
 		Timeout     int64 `yaml:"timeout"`    // 行为分析超时时间(秒)
 		MaxMemoryMB int64 `yaml:"max_memory"` // 最大内存限制(MB)
+
+		// 释放文件捕获配置
+		CaptureDropped bool   `yaml:"capture_dropped"` // 是否捕获沙箱中新建或修改的文件
+		MaxDropDepth   int    `yaml:"max_drop_depth"`  // 释放文件递归检测的最大深度
+		DroppedDir     string `yaml:"dropped_dir"`     // 释放文件的保存目录
+
+		// 各脚本语言使用的解释器命令，如 {"php": "php-cgi -f", "python": "python3"}
+		Interpreters map[string]string `yaml:"interpreters"`
 	} `yaml:"behavior_analysis"`
 
 	// 机器学习配置
 	MachineLearning struct {
-		Enabled   bool    `yaml:"enabled"`    // 是否启用机器学习检测
-		Threshold float64 `yaml:"threshold"`  // 检测阈值
-		BatchSize int     `yaml:"batch_size"` // 批处理大小
+		Enabled           bool    `yaml:"enabled"`            // 是否启用机器学习检测
+		Threshold         float64 `yaml:"threshold"`          // 检测阈值，模型文件未带阈值时使用
+		OverrideThreshold bool    `yaml:"override_threshold"` // 为 true 时 threshold 覆盖模型自带的阈值
+		BatchSize         int     `yaml:"batch_size"`         // 批处理大小
+
+		// 模型热加载配置
+		HotReload bool   `yaml:"hot_reload"` // 模型文件变化时自动校验并加载
+		CanaryDir string `yaml:"canary_dir"` // 金丝雀样本目录，包含 benign/ 和 malicious/ 子目录
 	} `yaml:"machine_learning"`
 
 	// YARA配置
 	Yara YaraConfig `yaml:"yara"`
+
+	// 结果缓存配置
+	Cache CacheConfig `yaml:"cache"`
+
+	// 影子模式配置
+	Shadow ShadowConfig `yaml:"shadow"`
 }
 
 // AlertConfig 告警相关配置
@@ -89,24 +164,33 @@ type AlertConfig struct {
 	} `yaml:"threshold"`
 
 	// 邮件告警配置
-	Email struct {
-		Enabled  bool     `yaml:"enabled"`  // 是否启用邮件告警
-		Host     string   `yaml:"host"`     // SMTP服务器地址
-		Port     int      `yaml:"port"`     // SMTP服务器端口
-		Username string   `yaml:"username"` // SMTP用户名
-		Password string   `yaml:"password"` // SMTP密码
-		From     string   `yaml:"from"`     // 发件人地址
-		To       []string `yaml:"to"`       // 收件人地址列表
-	} `yaml:"email"`
+	Email EmailConfig `yaml:"email"`
 
 	// 短信告警配置
-	SMS struct {
-		Enabled   bool     `yaml:"enabled"`  // 是否启用短信告警
-		Gateway   string   `yaml:"gateway"`  // 短信网关地址
-		APIKey    string   `yaml:"api_key"`  // API密钥
-		Template  string   `yaml:"template"` // 短信模板
-		PhoneList []string `yaml:"phones"`   // 接收手机号列表
-	} `yaml:"sms"`
+	SMS SMSConfig `yaml:"sms"`
+
+	// 同一文件两次告警的最小间隔，为 0 时使用默认值 1h
+	RateLimit time.Duration `yaml:"rate_limit"`
+}
+
+// EmailConfig 邮件告警配置
+type EmailConfig struct {
+	Enabled  bool     `yaml:"enabled"`  // 是否启用邮件告警
+	Host     string   `yaml:"host"`     // SMTP服务器地址
+	Port     int      `yaml:"port"`     // SMTP服务器端口
+	Username string   `yaml:"username"` // SMTP用户名
+	Password string   `yaml:"password"` // SMTP密码
+	From     string   `yaml:"from"`     // 发件人地址
+	To       []string `yaml:"to"`       // 收件人地址列表
+}
+
+// SMSConfig 短信告警配置
+type SMSConfig struct {
+	Enabled   bool     `yaml:"enabled"`  // 是否启用短信告警
+	Gateway   string   `yaml:"gateway"`  // 短信网关地址
+	APIKey    string   `yaml:"api_key"`  // API密钥
+	Template  string   `yaml:"template"` // 短信模板
+	PhoneList []string `yaml:"phones"`   // 接收手机号列表
 }
 
 // StorageConfig 存储相关配置
@@ -124,11 +208,37 @@ type StorageConfig struct {
 
 	// 历史记录保留配置
 	History struct {
-		RetentionDays int   `yaml:"retention_days"` // 历史记录保留天数
-		MaxRecords    int64 `yaml:"max_records"`    // 最大记录数
+		RetentionDays   int           `yaml:"retention_days"`   // 历史记录保留天数
+		MaxRecords      int64         `yaml:"max_records"`      // 最大记录数
+		CleanupInterval time.Duration `yaml:"cleanup_interval"` // 实时/定时模式下清理过期记录的间隔，默认 24h
 	} `yaml:"history"`
 }
 
+// QuarantineConfig 隔离区配置
+type QuarantineConfig struct {
+	Dir     string `yaml:"dir"`      // 隔离区目录，默认 data/quarantine
+	KeyFile string `yaml:"key_file"` // 隔离文件的加密密钥，不存在时自动生成，默认 <dir>/vault.key
+	Auto    bool   `yaml:"auto"`     // 自动隔离判定为 webshell 的文件，等价于追加在响应策略最后的一条 quarantine 规则
+}
+
+// ResponseConfig 检测结论出来后的自动响应策略
+type ResponseConfig struct {
+	Enabled           bool           `yaml:"enabled"`              // 是否启用自动响应
+	DryRun            bool           `yaml:"dry_run"`              // 只记录将要执行的动作，不修改文件
+	MaxActionsPerHour int            `yaml:"max_actions_per_hour"` // 每小时最多执行的修改文件的动作数，超出后只记录不执行，0 表示不限制
+	Allowlist         []string       `yaml:"allowlist"`            // 永不修改的目录或 glob 模式
+	Rules             []ResponseRule `yaml:"rules"`                // 按顺序匹配，使用第一条匹配的规则
+}
+
+// ResponseRule 一条响应规则：结论和路径都匹配时依次执行 actions
+type ResponseRule struct {
+	Name       string   `yaml:"name"`        // 名称，用于日志和动作记录
+	RiskLevels []string `yaml:"risk_levels"` // 匹配的风险等级，如 [HIGH, MEDIUM]，为空时匹配 SAFE 以外的任意等级
+	Webshell   bool     `yaml:"webshell"`    // 只匹配判定为 webshell 的结果
+	Paths      []string `yaml:"paths"`       // 匹配的目录或 glob 模式，为空时匹配所有文件
+	Actions    []string `yaml:"actions"`     // log、alert、chmod、rename、quarantine 或 stub
+}
+
 // YaraConfig YARA配置
 type YaraConfig struct {
 	Enabled     bool     `yaml:"enabled"`       // 是否启用YARA检测
@@ -137,6 +247,34 @@ type YaraConfig struct {
 	MaxFileSize int64    `yaml:"max_file_size"` // 最大扫描文件大小
 }
 
+// CacheConfig 检测结果缓存配置
+type CacheConfig struct {
+	Enabled    bool          `yaml:"enabled"`     // 是否启用结果缓存
+	Path       string        `yaml:"path"`        // 缓存数据库路径
+	MaxEntries int           `yaml:"max_entries"` // 缓存记录上限，超出时淘汰最久未命中的记录，默认 1000000
+	MaxAge     time.Duration `yaml:"max_age"`     // 记录最后一次写入或命中后的保留时长，0 表示不按时间淘汰
+}
+
+// ShadowConfig 影子模式配置：影子模型和规则集与当前引擎并行运行，结果只记录不影响判定
+type ShadowConfig struct {
+	Enabled  bool                `yaml:"enabled"`   // 是否启用影子模式
+	Models   []ShadowModelConfig `yaml:"models"`    // 影子模型，替换当前模型计算得分
+	RuleSets []ShadowRuleConfig  `yaml:"rule_sets"` // 影子 YARA 规则集，替换当前规则计算特征匹配得分
+}
+
+// ShadowModelConfig 影子模型配置
+type ShadowModelConfig struct {
+	Name string `yaml:"name"` // 名称，用于报告
+	Path string `yaml:"path"` // 模型文件路径
+}
+
+// ShadowRuleConfig 影子规则集配置
+type ShadowRuleConfig struct {
+	Name      string   `yaml:"name"`       // 名称，用于报告
+	RulesDir  string   `yaml:"rules_dir"`  // 规则目录
+	RuleTypes []string `yaml:"rule_types"` // 要检测的规则类型
+}
+
 // LoadConfig 从指定路径加载配置文件
 func LoadConfig(path string) (*Config, error) {
 	// 读取配置文件
@@ -200,5 +338,75 @@ func validateConfig(cfg *Config) error {
 		}
 	}
 
+	// 验证定时扫描任务
+	if err := validateScheduleJobs(cfg.Scan.Schedule.Jobs); err != nil {
+		return err
+	}
+
+	// 验证响应策略
+	if cfg.Response.Enabled {
+		if err := validateResponse(cfg.Response); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateScheduleJobs 验证定时扫描任务的名称、cron 表达式和扫描深度
+func validateScheduleJobs(jobs []ScheduleJob) error {
+	names := make(map[string]bool)
+	for i, job := range jobs {
+		if job.Name == "" {
+			return fmt.Errorf("schedule job #%d has no name", i+1)
+		}
+		if names[job.Name] {
+			return fmt.Errorf("duplicate schedule job name %q", job.Name)
+		}
+		names[job.Name] = true
+		if _, err := schedule.Parse(job.Cron); err != nil {
+			return fmt.Errorf("schedule job %s: %v", job.Name, err)
+		}
+		if job.Depth != "" && job.Depth != "quick" && job.Depth != "full" {
+			return fmt.Errorf("schedule job %s: depth must be quick or full", job.Name)
+		}
+		if job.Jitter < 0 {
+			return fmt.Errorf("schedule job %s: jitter must not be negative", job.Name)
+		}
+	}
+	return nil
+}
+
+// validateResponse 验证响应规则中的风险等级和动作；修改文件的规则必须至少设置一个匹配条件，
+// 避免一条没有条件的 quarantine 规则处理整个网站目录
+func validateResponse(cfg ResponseConfig) error {
+	levels := map[string]bool{"HIGH": true, "MEDIUM": true, "LOW": true, "SAFE": true}
+	actions := map[string]bool{"log": true, "alert": true, "chmod": true, "rename": true, "quarantine": true, "stub": true}
+	modifying := map[string]bool{"chmod": true, "rename": true, "quarantine": true, "stub": true}
+	for i, rule := range cfg.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if len(rule.Actions) == 0 {
+			return fmt.Errorf("response rule %s has no actions", name)
+		}
+		for _, level := range rule.RiskLevels {
+			if !levels[level] {
+				return fmt.Errorf("response rule %s: unknown risk level %q", name, level)
+			}
+		}
+		for _, action := range rule.Actions {
+			if !actions[action] {
+				return fmt.Errorf("response rule %s: unknown action %q (expected log, alert, chmod, rename, quarantine or stub)", name, action)
+			}
+			if modifying[action] && len(rule.RiskLevels) == 0 && !rule.Webshell && len(rule.Paths) == 0 {
+				return fmt.Errorf("response rule %s: action %s modifies files and needs risk_levels, webshell or paths", name, action)
+			}
+		}
+	}
+	if cfg.MaxActionsPerHour < 0 {
+		return fmt.Errorf("response max_actions_per_hour must not be negative")
+	}
 	return nil
 }
@@ -0,0 +1,25 @@
+package config
+
+import "testing"
+
+func TestValidateResponseRequiresFiltersForFileActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    ResponseRule
+		wantErr bool
+	}{
+		{"quarantine without filters", ResponseRule{Name: "all", Actions: []string{"quarantine"}}, true},
+		{"chmod without filters", ResponseRule{Name: "all", Actions: []string{"log", "chmod"}}, true},
+		{"log without filters", ResponseRule{Name: "all", Actions: []string{"log", "alert"}}, false},
+		{"quarantine webshells", ResponseRule{Name: "shells", Webshell: true, Actions: []string{"quarantine"}}, false},
+		{"stub high risk", ResponseRule{Name: "high", RiskLevels: []string{"HIGH"}, Actions: []string{"stub"}}, false},
+		{"rename in path", ResponseRule{Name: "uploads", Paths: []string{"/var/www/uploads"}, Actions: []string{"rename"}}, false},
+		{"unknown level", ResponseRule{Name: "bad", RiskLevels: []string{"CRITICAL"}, Actions: []string{"log"}}, true},
+	}
+	for _, tt := range tests {
+		err := validateResponse(ResponseConfig{Rules: []ResponseRule{tt.rule}})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateResponse() error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package dataset
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 样本标签
+const (
+	LabelBenign    = 0
+	LabelMalicious = 1
+)
+
+// Sample 带标签的样本文件
+type Sample struct {
+	Path  string
+	Label int
+}
+
+// Corpus 带标签的语料目录
+type Corpus struct {
+	BenignDirs    []string // 正常文件目录
+	MaliciousDirs []string // webshell 样本目录
+	FileTypes     []string // 需要收集的文件扩展名，为空时收集全部文件
+	MaxFileSize   int64    // 最大文件大小，0 表示不限制
+}
+
+// Collect 遍历语料目录，按路径排序返回全部样本
+func (c Corpus) Collect() ([]Sample, error) {
+	var samples []Sample
+	for _, dir := range c.BenignDirs {
+		found, err := c.walk(dir, LabelBenign)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, found...)
+	}
+	for _, dir := range c.MaliciousDirs {
+		found, err := c.walk(dir, LabelMalicious)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, found...)
+	}
+
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no samples found")
+	}
+	return samples, nil
+}
+
+// walk 收集单个目录下的样本
+func (c Corpus) walk(dir string, label int) ([]Sample, error) {
+	var samples []Sample
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if c.MaxFileSize > 0 && info.Size() > c.MaxFileSize {
+			return nil
+		}
+		if !MatchFileType(path, c.FileTypes) {
+			return nil
+		}
+		samples = append(samples, Sample{Path: path, Label: label})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %v", dir, err)
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Path < samples[j].Path })
+	return samples, nil
+}
+
+// MatchFileType 检查文件扩展名是否在列表中，列表为空时全部匹配
+func MatchFileType(path string, fileTypes []string) bool {
+	if len(fileTypes) == 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, fileType := range fileTypes {
+		if ext == strings.ToLower(fileType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package dataset
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"webshell-detector/pkg/mlmodel"
+)
+
+// Record 导出的特征向量记录
+type Record struct {
+	SchemaVersion int       `json:"schema_version"`
+	Path          string    `json:"path"`
+	Label         int       `json:"label"`
+	Features      []float64 `json:"features"`
+}
+
+// NewRecord 读取样本文件并按当前特征模式提取特征
+func NewRecord(sample Sample) (*Record, error) {
+	content, err := os.ReadFile(sample.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sample: %v", err)
+	}
+
+	return &Record{
+		SchemaVersion: mlmodel.FeatureSchemaVersion,
+		Path:          sample.Path,
+		Label:         sample.Label,
+		Features:      mlmodel.ExtractFeatures(content),
+	}, nil
+}
+
+// RecordWriter 特征记录写入器
+type RecordWriter interface {
+	Write(record *Record) error
+	Flush() error
+}
+
+// NewRecordWriter 按格式创建写入器，支持 csv 和 jsonl
+func NewRecordWriter(w io.Writer, format string) (RecordWriter, error) {
+	switch format {
+	case "csv":
+		return newCSVWriter(w)
+	case "jsonl":
+		return &jsonlWriter{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// csvWriter CSV 格式写入器，首行为 path,label,schema_version 及特征名称
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	header := append([]string{"path", "label", "schema_version"}, mlmodel.FeatureNames...)
+	if err := cw.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %v", err)
+	}
+	return &csvWriter{w: cw}, nil
+}
+
+func (c *csvWriter) Write(record *Record) error {
+	row := make([]string, 0, len(record.Features)+3)
+	row = append(row, record.Path, strconv.Itoa(record.Label), strconv.Itoa(record.SchemaVersion))
+	for _, v := range record.Features {
+		row = append(row, strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	return c.w.Write(row)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonlWriter JSON Lines 格式写入器
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlWriter) Write(record *Record) error {
+	return j.enc.Encode(record)
+}
+
+func (j *jsonlWriter) Flush() error {
+	return nil
+}
@@ -2,21 +2,41 @@ package detector
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
+	"io/fs"
 	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
 
 // BehaviorAnalysisResult 行为分析结果
 type BehaviorAnalysisResult struct {
-	Score     float64
-	Behaviors []string
+	Score        float64
+	Behaviors    []string
+	DroppedFiles []DroppedFile // 执行期间释放的文件
 }
 
+// DroppedFile 沙箱执行期间新建或修改的文件
+type DroppedFile struct {
+	Path     string // 相对沙箱目录的路径
+	Content  []byte // 文件内容，沙箱删除前读取
+	Modified bool   // true 表示修改已有文件，false 表示新建文件
+}
+
+// sandboxFileState 沙箱内文件的状态快照
+type sandboxFileState struct {
+	size int64
+	hash [sha256.Size]byte
+}
+
+// maxDroppedFiles 单次执行最多捕获的释放文件数
+const maxDroppedFiles = 32
+
 // behaviorAnalyze 执行行为分析检测
 func (d *Detector) behaviorAnalyze(ctx context.Context, filePath string, content []byte) (*BehaviorAnalysisResult, error) {
 	result := &BehaviorAnalysisResult{
@@ -24,6 +44,18 @@ func (d *Detector) behaviorAnalyze(ctx context.Context, filePath string, content
 		Behaviors: make([]string, 0),
 	}
 
+	// 根据脚本语言选择解释器，无法执行的文件跳过行为分析
+	lang := detectLanguage(filePath, content)
+	if lang == "" {
+		fmt.Printf("Skipping behavior analysis: unsupported script language for %s\n", filePath)
+		return result, nil
+	}
+	interpreter, err := d.interpreterCommand(lang)
+	if err != nil {
+		fmt.Printf("Skipping behavior analysis: %v\n", err)
+		return result, nil
+	}
+
 	// 创建临时沙箱环境
 	sandboxDir, err := os.MkdirTemp("", "webshell-sandbox-*")
 	if err != nil {
@@ -37,19 +69,40 @@ func (d *Detector) behaviorAnalyze(ctx context.Context, filePath string, content
 		return nil, fmt.Errorf("failed to copy file to sandbox: %v", err)
 	}
 
+	// 记录执行前的沙箱状态
+	captureDropped := d.config.Detection.BehaviorAnalysis.CaptureDropped
+	var before map[string]sandboxFileState
+	if captureDropped {
+		if before, err = snapshotSandbox(sandboxDir); err != nil {
+			return nil, fmt.Errorf("failed to snapshot sandbox: %v", err)
+		}
+	}
+
 	// 设置超时上下文
 	timeout := time.Duration(d.config.Detection.BehaviorAnalysis.Timeout) * time.Second
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	// 使用strace监控系统调用
-	cmd := exec.CommandContext(ctx, "strace", "-f", "-e", "trace=process,file,network", "php", sandboxFile)
+	args := []string{"-f", "-e", "trace=process,file,network"}
+	args = append(args, interpreter...)
+	args = append(args, sandboxFile)
+	cmd := exec.CommandContext(ctx, "strace", args...)
 	cmd.Dir = sandboxDir
 	output, err := cmd.CombinedOutput()
 	if err != nil && !strings.Contains(err.Error(), "exit status") {
 		return nil, fmt.Errorf("failed to analyze behavior: %v", err)
 	}
 
+	// 对比执行前后的沙箱状态，捕获释放的文件
+	if captureDropped {
+		dropped, err := d.collectDroppedFiles(sandboxDir, before)
+		if err != nil {
+			fmt.Printf("Warning: Failed to collect dropped files: %v\n", err)
+		}
+		result.DroppedFiles = dropped
+	}
+
 	// 分析系统调用
 	straceOutput := string(output)
 
@@ -126,3 +179,96 @@ func (d *Detector) behaviorAnalyze(ctx context.Context, filePath string, content
 
 	return result, nil
 }
+
+// snapshotSandbox 记录沙箱目录下所有普通文件的大小和哈希。
+// 被执行脚本改成不可读的目录和文件先恢复属主权限，仍无法读取时跳过，不影响其他文件
+func snapshotSandbox(dir string) (map[string]sandboxFileState, error) {
+	states := make(map[string]sandboxFileState)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		// WalkDir 在读取目录内容前访问目录，此时恢复权限对本次遍历生效
+		if info.IsDir() {
+			if perm := info.Mode().Perm(); perm&0700 != 0700 {
+				os.Chmod(path, perm|0700)
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil && info.Mode().Perm()&0400 == 0 && os.Chmod(path, info.Mode().Perm()|0400) == nil {
+			content, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		states[rel] = sandboxFileState{
+			size: info.Size(),
+			hash: sha256.Sum256(content),
+		}
+		return nil
+	})
+	return states, err
+}
+
+// collectDroppedFiles 对比执行前后的快照，返回新建或修改的文件
+func (d *Detector) collectDroppedFiles(sandboxDir string, before map[string]sandboxFileState) ([]DroppedFile, error) {
+	after, err := snapshotSandbox(sandboxDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按路径排序，保证结果稳定
+	paths := make([]string, 0, len(after))
+	for path := range after {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var dropped []DroppedFile
+	for _, path := range paths {
+		state := after[path]
+		old, existed := before[path]
+		if existed && old.hash == state.hash {
+			continue
+		}
+
+		// 超过大小限制的文件不做检测
+		if maxSize := d.config.Detection.Yara.MaxFileSize; maxSize > 0 && state.size > maxSize {
+			fmt.Printf("Warning: Dropped file %s exceeds size limit, skipped\n", path)
+			continue
+		}
+
+		if len(dropped) >= maxDroppedFiles {
+			fmt.Printf("Warning: Too many dropped files, only the first %d are captured\n", maxDroppedFiles)
+			break
+		}
+
+		content, err := os.ReadFile(filepath.Join(sandboxDir, path))
+		if err != nil {
+			continue
+		}
+		dropped = append(dropped, DroppedFile{
+			Path:     path,
+			Content:  content,
+			Modified: existed,
+		})
+	}
+
+	return dropped, nil
+}
@@ -3,9 +3,12 @@ package detector
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"sync"
+	"time"
 
+	"webshell-detector/internal/cache"
 	"webshell-detector/internal/config"
 	"webshell-detector/pkg/mlmodel"
 	"webshell-detector/pkg/signature"
@@ -13,15 +16,32 @@ import (
 
 // DetectionResult 检测结果结构
 type DetectionResult struct {
-	FilePath        string
-	IsWebshell      bool
-	RiskLevel       RiskLevel
-	FeatureScore    float64
-	BehaviorScore   float64
-	MLScore         float64
-	MatchedFeatures []string
-	Behaviors       []string
-	TotalScore      float64
+	FilePath         string
+	ContentHash      string // 文件内容 SHA-256
+	IsWebshell       bool
+	RiskLevel        RiskLevel
+	FeatureScore     float64
+	BehaviorScore    float64
+	MLScore          float64
+	RawBehaviorScore float64                // 合成总分前行为分析的原始得分，没有可疑行为时 BehaviorScore 会被置 0
+	RawMLScore       float64                // 合成总分前模型给出的得分（概率×100），MLScore 会按其他引擎结论降权
+	MLVerdict        bool                   // ML概率是否达到判定阈值
+	ModelVersion     string                 // 打分所用模型的版本（模型文件 SHA-256）
+	MLTopFeatures    []mlmodel.Contribution // 对ML得分贡献最大的特征
+	MatchedFeatures  []string
+	Behaviors        []string
+	TotalScore       float64
+	DroppedFiles     []*DetectionResult // 行为分析中释放文件的检测结果
+	Shadow           []ShadowResult     // 影子模型和规则集的结论，不影响判定
+	Process          *ProcessInfo       // 最后写入文件的进程，仅监控后端能提供时记录
+}
+
+// ProcessInfo 写入文件的进程信息
+type ProcessInfo struct {
+	PID     int    `json:"pid"`
+	UID     int    `json:"uid"`
+	Exe     string `json:"exe,omitempty"`
+	Cmdline string `json:"cmdline,omitempty"`
 }
 
 // RiskLevel 风险等级
@@ -41,31 +61,122 @@ type Detector struct {
 	mlModel    *mlmodel.Model
 	resultChan chan *DetectionResult
 	mu         sync.Mutex
+
+	// 结果缓存
+	cache            *cache.Cache
+	fingerprint      string
+	fingerprintStamp string    // 计算指纹时模型、特征库和规则文件的版本，变化时重新计算指纹
+	rulesStamped     string    // 上次检查时规则文件的版本
+	rulesCheckedAt   time.Time // 上次检查规则文件的时间
+	thresholdUsed    string    // 上次输出日志时的ML判定阈值及来源
+
+	// 影子模式引擎
+	shadowModels []*shadowModel
+	shadowRules  []*shadowRuleSet
 }
 
 // NewDetector 创建新的检测器
 func NewDetector(cfg *config.Config, sigMgr *signature.Manager, mlModel *mlmodel.Model) *Detector {
-	return &Detector{
+	d := &Detector{
 		config:     cfg,
 		sigMgr:     sigMgr,
 		mlModel:    mlModel,
 		resultChan: make(chan *DetectionResult, 100),
 	}
+
+	// 打开结果缓存，失败时不影响检测
+	if cfg.Detection.Cache.Enabled {
+		cachePath := cfg.Detection.Cache.Path
+		if cachePath == "" {
+			cachePath = defaultCachePath
+		}
+		maxEntries := cfg.Detection.Cache.MaxEntries
+		if maxEntries <= 0 {
+			maxEntries = defaultCacheMaxEntries
+		}
+		resultCache, err := cache.NewCache(cachePath, maxEntries, cfg.Detection.Cache.MaxAge)
+		if err != nil {
+			fmt.Printf("Warning: Failed to open result cache: %v\n", err)
+		} else {
+			d.cache = resultCache
+		}
+	}
+
+	d.loadShadows()
+
+	return d
+}
+
+// Close 释放检测器持有的资源
+func (d *Detector) Close() error {
+	if d.cache != nil {
+		return d.cache.Close()
+	}
+	return nil
+}
+
+// Analysis 已完成除机器学习外全部检测的中间结果，等待（批量）机器学习打分后得到最终结果
+type Analysis struct {
+	Result   *DetectionResult
+	Info     os.FileInfo // 读取内容前的文件信息，与 Result.ContentHash 对应同一版本的文件
+	features []float64   // 待打分的特征向量，nil 表示不需要机器学习打分
+	hash     string      // 文件内容哈希，用于文件状态索引
+	cacheKey string      // 结果缓存键
+	degraded bool        // 有引擎执行失败，结果不完整时不写入缓存
+	complete bool        // 缓存命中，结果已是最终结果
+
+	shadowMatches []shadowMatch   // 影子规则集的特征匹配结果
+	shadows       []*ShadowResult // 影子引擎结论，正式结果计算完成后写入 Result
 }
 
 // Detect 执行文件检测
 func (d *Detector) Detect(ctx context.Context, filePath string) (*DetectionResult, error) {
+	analysis, err := d.Analyze(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Finalize([]*Analysis{analysis})
+	return analysis.Result, nil
+}
+
+// Analyze 执行特征匹配和行为分析，并提取机器学习特征；需调用 Finalize 完成打分
+func (d *Detector) Analyze(ctx context.Context, filePath string) (*Analysis, error) {
 	fmt.Println("\nStarting detection process...")
 
-	// 读取文件内容
-	content, err := os.ReadFile(filePath)
+	// 读取文件内容，并记录读取前的文件信息：读取期间文件被修改时，文件信息与哈希不一致会使下次增量扫描重新检测
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %v", err)
+	}
+	content, err := io.ReadAll(file)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %v", err)
 	}
 
+	// 相同内容且引擎版本未变化时直接复用缓存结果
+	analysis := &Analysis{Info: info, hash: contentHash(content)}
+	if d.cache != nil {
+		analysis.cacheKey = d.cacheKey(analysis.hash, filePath, content)
+		if cached, ok := d.lookupCache(analysis.cacheKey, filePath); ok {
+			fmt.Println("Result cache hit, skipping analysis.")
+			cached.ContentHash = analysis.hash
+			analysis.Result = cached
+			analysis.complete = true
+			return analysis, nil
+		}
+	}
+
 	result := &DetectionResult{
-		FilePath: filePath,
+		FilePath:    filePath,
+		ContentHash: analysis.hash,
 	}
+	analysis.Result = result
 
 	// 特征匹配检测
 	fmt.Println("1. Running feature matching analysis...")
@@ -75,6 +186,7 @@ func (d *Detector) Detect(ctx context.Context, filePath string) (*DetectionResul
 	}
 	result.FeatureScore = featureResult.Score
 	result.MatchedFeatures = featureResult.Matches
+	analysis.shadowMatches = d.shadowFeatureMatch(content)
 
 	// 行为分析检测
 	if d.config.Detection.BehaviorAnalysis.Enabled {
@@ -82,28 +194,65 @@ func (d *Detector) Detect(ctx context.Context, filePath string) (*DetectionResul
 		behaviorResult, err := d.behaviorAnalyze(ctx, filePath, content)
 		if err != nil {
 			fmt.Printf("Warning: Behavior analysis failed: %v\n", err)
+			analysis.degraded = true
 		} else {
 			result.BehaviorScore = behaviorResult.Score
 			result.Behaviors = behaviorResult.Behaviors
+
+			// 递归检测释放的文件
+			if len(behaviorResult.DroppedFiles) > 0 {
+				d.scanDroppedFiles(ctx, result, behaviorResult.DroppedFiles)
+			}
+			result.RawBehaviorScore = result.BehaviorScore
 		}
 	}
 
-	// 机器学习检测
+	// 提取机器学习特征，打分在 Finalize 中批量进行
 	if d.config.Detection.MachineLearning.Enabled {
-		fmt.Println("3. Running machine learning analysis...")
-		mlScore, err := d.mlDetect(ctx, content)
+		features, err := d.extractFeatures(content)
 		if err != nil {
-			fmt.Printf("Warning: ML detection failed: %v\n", err)
+			fmt.Printf("Warning: ML feature extraction failed: %v\n", err)
+			analysis.degraded = true
 		} else {
-			result.MLScore = mlScore
+			analysis.features = features
 		}
 	}
 
-	// 计算总分并确定风险等级
-	d.calculateTotalScore(result)
-	fmt.Println("Detection process completed.")
+	return analysis, nil
+}
+
+// Finalize 对一批分析结果统一进行机器学习打分，计算总分并写入缓存
+func (d *Detector) Finalize(analyses []*Analysis) {
+	// 机器学习检测
+	var pending []*Analysis
+	for _, a := range analyses {
+		if !a.complete && a.features != nil {
+			pending = append(pending, a)
+		}
+	}
+	if len(pending) > 0 {
+		fmt.Printf("3. Running machine learning analysis (%d file(s))...\n", len(pending))
+		d.mlDetectBatch(pending)
+	}
+
+	// 影子引擎基于同样的原始得分计算各自结论
+	d.evaluateShadows(analyses)
 
-	return result, nil
+	for _, a := range analyses {
+		if a.complete {
+			continue
+		}
+
+		// 计算总分并确定风险等级
+		d.calculateTotalScore(a.Result)
+		attachShadows(a)
+		a.complete = true
+
+		if d.cache != nil && !a.degraded {
+			d.storeCache(a.cacheKey, a.Result)
+		}
+	}
+	fmt.Println("Detection process completed.")
 }
 
 // calculateTotalScore 计算总分并确定风险等级
@@ -170,4 +319,30 @@ func (d *Detector) calculateTotalScore(result *DetectionResult) {
 			result.TotalScore = 30
 		}
 	}
+
+	// ML概率达到判定阈值时提高风险等级下限：仅模型判定时至少为低风险以便人工复核，
+	// 特征匹配或行为分析也有发现时至少为中风险；是否判定为webshell仍由特征匹配和总分决定
+	if d.config.Detection.MachineLearning.Enabled && result.MLVerdict {
+		floor := RiskLevelLow
+		if result.FeatureScore > 0 || len(result.Behaviors) > 0 {
+			floor = RiskLevelMedium
+		}
+		if riskRank(result.RiskLevel) < riskRank(floor) {
+			result.RiskLevel = floor
+		}
+	}
+}
+
+// riskRank 风险等级的高低顺序
+func riskRank(level RiskLevel) int {
+	switch level {
+	case RiskLevelHigh:
+		return 3
+	case RiskLevelMedium:
+		return 2
+	case RiskLevelLow:
+		return 1
+	default:
+		return 0
+	}
 }
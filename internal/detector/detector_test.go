@@ -0,0 +1,117 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"webshell-detector/internal/config"
+	"webshell-detector/pkg/mlmodel"
+)
+
+func TestCalculateTotalScoreMLVerdict(t *testing.T) {
+	tests := []struct {
+		name      string
+		ml        bool
+		result    DetectionResult
+		wantLevel RiskLevel
+		wantShell bool
+	}{
+		{"clean", true, DetectionResult{}, RiskLevelSafe, false},
+		{"model only", true, DetectionResult{MLScore: 90, MLVerdict: true}, RiskLevelLow, false},
+		{"model below threshold", true, DetectionResult{MLScore: 60}, RiskLevelSafe, false},
+		{"model with weak feature match", true, DetectionResult{FeatureScore: 20, MLScore: 90, MLVerdict: true}, RiskLevelMedium, false},
+		{"model with behavior", true, DetectionResult{BehaviorScore: 40, Behaviors: []string{"exec"}, MLScore: 90, MLVerdict: true}, RiskLevelMedium, false},
+		{"floor does not lower high risk", true, DetectionResult{FeatureScore: 100, MLScore: 90, MLVerdict: true}, RiskLevelHigh, true},
+		{"ML disabled", false, DetectionResult{MLScore: 90, MLVerdict: true}, RiskLevelSafe, false},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{}
+		cfg.Detection.BehaviorAnalysis.Enabled = true
+		cfg.Detection.MachineLearning.Enabled = tt.ml
+		d := &Detector{config: cfg}
+
+		result := tt.result
+		d.calculateTotalScore(&result)
+		if result.RiskLevel != tt.wantLevel || result.IsWebshell != tt.wantShell {
+			t.Errorf("%s: risk level %s, webshell %v; want %s, %v",
+				tt.name, result.RiskLevel, result.IsWebshell, tt.wantLevel, tt.wantShell)
+		}
+	}
+}
+
+// linearModel 基于当前特征模式、所有特征权重相同的线性模型
+func linearModel(weight float64) *mlmodel.ModelData {
+	weights := make([]float64, mlmodel.FeatureCount)
+	for i := range weights {
+		weights[i] = weight
+	}
+	return &mlmodel.ModelData{
+		SchemaVersion: mlmodel.FeatureSchemaVersion,
+		FeatureNames:  mlmodel.FeatureNames,
+		FeatureCount:  mlmodel.FeatureCount,
+		Weights:       weights,
+		Threshold:     0.5,
+	}
+}
+
+func TestFingerprintChangesOnModelReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := mlmodel.SaveModel(path, linearModel(0.01)); err != nil {
+		t.Fatal(err)
+	}
+	model, err := mlmodel.LoadModel(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.Detection.MachineLearning.Enabled = true
+	d := NewDetector(cfg, nil, model)
+
+	before := d.Fingerprint()
+	if again := d.Fingerprint(); again != before {
+		t.Fatalf("fingerprint changed without an engine change")
+	}
+	if err := mlmodel.SaveModel(path, linearModel(0.02)); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if after := d.Fingerprint(); after == before {
+		t.Errorf("fingerprint still %s after the model was reloaded", before[:12])
+	}
+}
+
+func TestFingerprintChecksRulesPeriodically(t *testing.T) {
+	rulesDir := t.TempDir()
+	rulePath := filepath.Join(rulesDir, "webshell", "eval.yar")
+	if err := os.MkdirAll(filepath.Dir(rulePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rulePath, []byte("rule eval { condition: true }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.Detection.Yara.Enabled = true
+	cfg.Detection.Yara.RulesDir = rulesDir
+	cfg.Detection.Yara.RuleTypes = []string{"webshell"}
+	d := &Detector{config: cfg}
+
+	before := d.Fingerprint()
+	if err := os.WriteFile(rulePath, []byte("rule eval { strings: $a = \"eval(\" condition: $a }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 间隔内不再遍历规则目录
+	if again := d.Fingerprint(); again != before {
+		t.Fatalf("rule directory walked again within %v", ruleCheckInterval)
+	}
+
+	d.mu.Lock()
+	d.rulesCheckedAt = time.Now().Add(-ruleCheckInterval)
+	d.mu.Unlock()
+	if after := d.Fingerprint(); after == before {
+		t.Errorf("fingerprint unchanged after the rule file changed and the check interval passed")
+	}
+}
@@ -0,0 +1,123 @@
+package detector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// dropDepthKey 释放文件递归检测深度的上下文键
+type dropDepthKey struct{}
+
+// 释放文件检测的默认配置
+const (
+	defaultMaxDropDepth = 2
+	defaultDroppedDir   = "data/dropped"
+	droppedWebshellRisk = 40.0 // 释放出webshell时附加的行为分数
+	droppedSuffix       = ".dropped"
+)
+
+// dropDepth 返回当前检测所处的递归深度，顶层文件为0
+func dropDepth(ctx context.Context) int {
+	if depth, ok := ctx.Value(dropDepthKey{}).(int); ok {
+		return depth
+	}
+	return 0
+}
+
+// scanDroppedFiles 保存沙箱中释放的文件并递归检测，结果作为子结果挂到父结果上。
+// 检测使用保留原文件名的临时副本（解释器按扩展名选择），检测结果指向保存的副本
+func (d *Detector) scanDroppedFiles(ctx context.Context, parent *DetectionResult, files []DroppedFile) {
+	maxDepth := d.config.Detection.BehaviorAnalysis.MaxDropDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDropDepth
+	}
+
+	depth := dropDepth(ctx)
+	if depth >= maxDepth {
+		fmt.Printf("Warning: Dropped file depth limit (%d) reached, %d file(s) not analyzed\n", maxDepth, len(files))
+		return
+	}
+
+	dir := d.config.Detection.BehaviorAnalysis.DroppedDir
+	if dir == "" {
+		dir = defaultDroppedDir
+	}
+
+	childCtx := context.WithValue(ctx, dropDepthKey{}, depth+1)
+	for _, file := range files {
+		path, err := saveDroppedFile(dir, file)
+		if err != nil {
+			fmt.Printf("Warning: Failed to save dropped file %s: %v\n", file.Path, err)
+			continue
+		}
+
+		fmt.Printf("Analyzing dropped file %s (depth %d)...\n", file.Path, depth+1)
+		child, err := d.detectDroppedFile(childCtx, file)
+		if err != nil {
+			fmt.Printf("Warning: Failed to analyze dropped file %s: %v\n", file.Path, err)
+			continue
+		}
+		child.FilePath = path
+		parent.DroppedFiles = append(parent.DroppedFiles, child)
+
+		// 释放出webshell本身就是可疑行为
+		if child.IsWebshell {
+			parent.Behaviors = append(parent.Behaviors, fmt.Sprintf("Dropped webshell file: %s", file.Path))
+			parent.BehaviorScore = math.Min(parent.BehaviorScore+droppedWebshellRisk, 100)
+		}
+	}
+}
+
+// detectDroppedFile 将释放的文件写入临时目录，以原文件名检测后删除
+func (d *Detector) detectDroppedFile(ctx context.Context, file DroppedFile) (*DetectionResult, error) {
+	tmpDir, err := os.MkdirTemp("", "webshell-dropped-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, filepath.Base(file.Path))
+	if err := os.WriteFile(path, file.Content, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write temporary file: %v", err)
+	}
+	return d.Detect(ctx, path)
+}
+
+// saveDroppedFile 将释放的文件保存到 <dir>/<sha256>/<文件名>.dropped，返回保存路径。
+// 释放的文件多为二阶段载荷，追加后缀并设为只读，避免被 Web 服务器按脚本解析或被执行
+func saveDroppedFile(dir string, file DroppedFile) (string, error) {
+	sum := sha256.Sum256(file.Content)
+	targetDir := filepath.Join(dir, hex.EncodeToString(sum[:]))
+	if err := os.MkdirAll(targetDir, 0700); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(targetDir, filepath.Base(file.Path)+droppedSuffix)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	tmp, err := os.CreateTemp(targetDir, ".dropped-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(file.Content); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0400); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,160 @@
+package detector
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"webshell-detector/internal/config"
+)
+
+const (
+	droppedShell  = "<?php eval($_POST['x']);"
+	droppedBenign = "<?php echo 'hello';"
+)
+
+// newDropDetector 只做正则匹配的检测器，释放的文件保存到临时目录
+func newDropDetector(t *testing.T) *Detector {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Detection.Yara.MaxFileSize = 1 << 20
+	cfg.Detection.BehaviorAnalysis.DroppedDir = t.TempDir()
+	cfg.Detection.BehaviorAnalysis.MaxDropDepth = 2
+	return &Detector{config: cfg}
+}
+
+// writeSandboxFile 在沙箱目录中写入文件，按需创建上级目录
+func writeSandboxFile(t *testing.T, dir, rel, content string) {
+	t.Helper()
+	path := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollectDroppedFiles(t *testing.T) {
+	d := newDropDetector(t)
+	d.config.Detection.Yara.MaxFileSize = 64
+	sandbox := t.TempDir()
+	writeSandboxFile(t, sandbox, "sample.php", droppedBenign)
+	writeSandboxFile(t, sandbox, "config.php", droppedBenign)
+	before, err := snapshotSandbox(sandbox)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟样本执行：新建、修改、超过大小限制，以及藏在不可读目录中的文件
+	writeSandboxFile(t, sandbox, "config.php", droppedShell)
+	writeSandboxFile(t, sandbox, "up/shell.php", droppedShell)
+	writeSandboxFile(t, sandbox, "big.php", string(bytes.Repeat([]byte("a"), 100)))
+	writeSandboxFile(t, sandbox, "hidden/x.php", droppedShell)
+	if err := os.Chmod(filepath.Join(sandbox, "hidden"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	dropped, err := d.collectDroppedFiles(sandbox, before)
+	if err != nil {
+		t.Fatalf("collectDroppedFiles: %v", err)
+	}
+	want := []struct {
+		path     string
+		modified bool
+	}{
+		{"config.php", true},
+		{"hidden/x.php", false},
+		{"up/shell.php", false},
+	}
+	if len(dropped) != len(want) {
+		t.Fatalf("dropped %d files %v, want %d", len(dropped), dropped, len(want))
+	}
+	for i, w := range want {
+		got := dropped[i]
+		if got.Path != filepath.FromSlash(w.path) || got.Modified != w.modified || string(got.Content) != droppedShell {
+			t.Errorf("dropped[%d] = %s (modified %v, %q), want %s (modified %v)", i, got.Path, got.Modified, got.Content, w.path, w.modified)
+		}
+	}
+}
+
+func TestSaveDroppedFile(t *testing.T) {
+	dir := t.TempDir()
+	file := DroppedFile{Path: filepath.Join("up", "shell.php"), Content: []byte(droppedShell)}
+
+	path, err := saveDroppedFile(dir, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(file.Content)
+	if want := filepath.Join(dir, hex.EncodeToString(sum[:]), "shell.php"+droppedSuffix); path != want {
+		t.Errorf("saved to %s, want %s", path, want)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0400 {
+		t.Errorf("saved file mode = %v, want read-only 0400", info.Mode().Perm())
+	}
+	if content, _ := os.ReadFile(path); !bytes.Equal(content, file.Content) {
+		t.Errorf("saved content = %q, want %q", content, file.Content)
+	}
+
+	// 相同内容再次释放时沿用已保存的副本
+	again, err := saveDroppedFile(dir, file)
+	if err != nil || again != path {
+		t.Errorf("second save = %s, %v; want %s", again, err, path)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("%d entries in the sample directory, want 1", len(entries))
+	}
+}
+
+func TestScanDroppedFiles(t *testing.T) {
+	tests := []struct {
+		name          string
+		depth         int
+		behaviorScore float64
+		files         []DroppedFile
+		wantChildren  int
+		wantScore     float64
+		wantBehaviors int
+	}{
+		{"webshell raises behavior score", 0, 10, []DroppedFile{{Path: "shell.php", Content: []byte(droppedShell)}}, 1, 50, 1},
+		{"benign file only recorded", 0, 10, []DroppedFile{{Path: "cache.php", Content: []byte(droppedBenign)}}, 1, 10, 0},
+		{"score capped at 100", 1, 80, []DroppedFile{{Path: "shell.php", Content: []byte(droppedShell)}}, 1, 100, 1},
+		{"depth limit reached", 2, 10, []DroppedFile{{Path: "shell.php", Content: []byte(droppedShell)}}, 0, 10, 0},
+	}
+	for _, tt := range tests {
+		d := newDropDetector(t)
+		parent := &DetectionResult{BehaviorScore: tt.behaviorScore}
+		ctx := context.WithValue(context.Background(), dropDepthKey{}, tt.depth)
+
+		d.scanDroppedFiles(ctx, parent, tt.files)
+		if len(parent.DroppedFiles) != tt.wantChildren {
+			t.Fatalf("%s: %d child results, want %d", tt.name, len(parent.DroppedFiles), tt.wantChildren)
+		}
+		if parent.BehaviorScore != tt.wantScore || len(parent.Behaviors) != tt.wantBehaviors {
+			t.Errorf("%s: behavior score %g with %d behaviors, want %g with %d",
+				tt.name, parent.BehaviorScore, len(parent.Behaviors), tt.wantScore, tt.wantBehaviors)
+		}
+		for _, child := range parent.DroppedFiles {
+			// 子结果指向保存的只读副本
+			if filepath.Dir(filepath.Dir(child.FilePath)) != d.config.Detection.BehaviorAnalysis.DroppedDir ||
+				filepath.Ext(child.FilePath) != droppedSuffix {
+				t.Errorf("%s: child result path %s is not a saved copy", tt.name, child.FilePath)
+			}
+		}
+		if tt.wantChildren == 0 {
+			if entries, _ := os.ReadDir(d.config.Detection.BehaviorAnalysis.DroppedDir); len(entries) != 0 {
+				t.Errorf("%s: %d files saved beyond the depth limit", tt.name, len(entries))
+			}
+		}
+	}
+}
@@ -14,6 +14,8 @@ import (
 	"strings"
 	"time"
 
+	"webshell-detector/internal/config"
+
 	"github.com/hillu/go-yara/v4"
 )
 
@@ -71,6 +73,11 @@ type YaraConfig struct {
 
 // featureMatch 执行特征匹配检测
 func (d *Detector) featureMatch(ctx context.Context, content []byte) (*FeatureMatchResult, error) {
+	return d.featureMatchWith(content, d.config.Detection.Yara)
+}
+
+// featureMatchWith 使用指定的 YARA 规则配置执行特征匹配，影子规则集复用该逻辑
+func (d *Detector) featureMatchWith(content []byte, yaraConfig config.YaraConfig) (*FeatureMatchResult, error) {
 	result := &FeatureMatchResult{
 		Score:        0,
 		Matches:      make([]string, 0),
@@ -79,7 +86,7 @@ func (d *Detector) featureMatch(ctx context.Context, content []byte) (*FeatureMa
 	}
 
 	// 检查文件大小
-	if int64(len(content)) > d.config.Detection.Yara.MaxFileSize {
+	if int64(len(content)) > yaraConfig.MaxFileSize {
 		return nil, fmt.Errorf("file size exceeds maximum allowed size for YARA scanning")
 	}
 
@@ -89,8 +96,8 @@ func (d *Detector) featureMatch(ctx context.Context, content []byte) (*FeatureMa
 	result.Score += regexScore
 
 	// 执行YARA规则匹配
-	if d.config.Detection.Yara.Enabled {
-		yaraScore, yaraMatches, err := d.matchYaraRules(content)
+	if yaraConfig.Enabled {
+		yaraScore, yaraMatches, err := d.matchYaraRules(content, yaraConfig)
 		if err != nil {
 			return nil, fmt.Errorf("YARA matching failed: %v", err)
 		}
@@ -128,13 +135,13 @@ func (d *Detector) matchRegexPatterns(content []byte) (float64, []string) {
 }
 
 // matchYaraRules 执行YARA规则匹配【每次扫描，都要加载规则，效率慢】
-func (d *Detector) matchYaraRules(content []byte) (float64, []string, error) {
+func (d *Detector) matchYaraRules(content []byte, yaraConfig config.YaraConfig) (float64, []string, error) {
 	var score float64
 	var matches []string
 
 	// 遍历指定的规则类型目录
-	for _, ruleType := range d.config.Detection.Yara.RuleTypes {
-		rulePath := filepath.Join(d.config.Detection.Yara.RulesDir, ruleType)
+	for _, ruleType := range yaraConfig.RuleTypes {
+		rulePath := filepath.Join(yaraConfig.RulesDir, ruleType)
 
 		// 递归扫描规则文件
 		err := filepath.Walk(rulePath, func(path string, info os.FileInfo, err error) error {
@@ -0,0 +1,108 @@
+package detector
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// 行为分析支持的脚本语言
+const (
+	LanguagePHP    = "php"
+	LanguagePython = "python"
+	LanguagePerl   = "perl"
+	LanguageShell  = "shell"
+)
+
+// defaultInterpreters 未配置时各语言使用的解释器
+var defaultInterpreters = map[string]string{
+	LanguagePHP:    "php",
+	LanguagePython: "python3",
+	LanguagePerl:   "perl",
+	LanguageShell:  "bash",
+}
+
+// extensionLanguages 文件扩展名到脚本语言的映射
+var extensionLanguages = map[string]string{
+	".php":   LanguagePHP,
+	".php3":  LanguagePHP,
+	".php4":  LanguagePHP,
+	".php5":  LanguagePHP,
+	".php7":  LanguagePHP,
+	".phtml": LanguagePHP,
+	".py":    LanguagePython,
+	".pl":    LanguagePerl,
+	".pm":    LanguagePerl,
+	".sh":    LanguageShell,
+	".bash":  LanguageShell,
+}
+
+// detectLanguage 根据 shebang、扩展名和 PHP 起始标记识别脚本语言，无法识别时返回空字符串
+func detectLanguage(filePath string, content []byte) string {
+	// shebang 优先，.cgi 等通用扩展名只能靠它识别
+	if bytes.HasPrefix(content, []byte("#!")) {
+		line := string(content[2:])
+		if idx := strings.IndexByte(line, '\n'); idx >= 0 {
+			line = line[:idx]
+		}
+		if lang := shebangLanguage(line); lang != "" {
+			return lang
+		}
+	}
+
+	if lang := extensionLanguages[strings.ToLower(filepath.Ext(filePath))]; lang != "" {
+		return lang
+	}
+
+	// .inc 等扩展名未登记的文件仍可能被 include 后执行，含有 PHP 起始标记时按 PHP 分析
+	if bytes.Contains(bytes.ToLower(content), []byte("<?php")) || bytes.Contains(content, []byte("<?=")) {
+		return LanguagePHP
+	}
+	return ""
+}
+
+// shebangLanguage 从 shebang 行中识别解释器对应的语言
+func shebangLanguage(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	// #!/usr/bin/env python3 形式取 env 后的第一个参数
+	name := filepath.Base(fields[0])
+	if name == "env" && len(fields) > 1 {
+		name = filepath.Base(fields[1])
+	}
+
+	switch {
+	case strings.HasPrefix(name, "php"):
+		return LanguagePHP
+	case strings.HasPrefix(name, "python"):
+		return LanguagePython
+	case strings.HasPrefix(name, "perl"):
+		return LanguagePerl
+	case name == "sh" || name == "bash" || name == "dash" || name == "ksh" || name == "zsh":
+		return LanguageShell
+	}
+	return ""
+}
+
+// interpreterCommand 返回指定语言的解释器命令及参数，解释器未配置或未安装时返回错误
+func (d *Detector) interpreterCommand(lang string) ([]string, error) {
+	command, ok := d.config.Detection.BehaviorAnalysis.Interpreters[lang]
+	if !ok {
+		command = defaultInterpreters[lang]
+	}
+
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no interpreter configured for %s", lang)
+	}
+
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil, fmt.Errorf("interpreter %s for %s is not installed", args[0], lang)
+	}
+	return args, nil
+}
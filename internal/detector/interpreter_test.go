@@ -0,0 +1,88 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"webshell-detector/internal/config"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    string
+	}{
+		{"php extension", "index.php", "echo 1;", LanguagePHP},
+		{"extension case", "INDEX.PHTML", "", LanguagePHP},
+		{"python extension", "tool.py", "print(1)", LanguagePython},
+		{"perl module", "Lib.pm", "1;", LanguagePerl},
+		{"shell extension", "run.sh", "ls", LanguageShell},
+		{"shebang on cgi", "upload.cgi", "#!/usr/bin/perl -w\nprint 1;", LanguagePerl},
+		{"env shebang", "x.cgi", "#!/usr/bin/env python3\nprint(1)", LanguagePython},
+		{"versioned php shebang", "x", "#!/usr/local/bin/php8.2\n<?php echo 1;", LanguagePHP},
+		{"shebang before extension", "disguised.php", "#!/bin/bash\nid", LanguageShell},
+		{"unknown shebang falls back to extension", "x.py", "#!/usr/bin/ruby\nputs 1", LanguagePython},
+		{"env without argument", "x.sh", "#!/usr/bin/env\nls", LanguageShell},
+		{"php tag in unregistered extension", "config.inc", "<?PHP echo 1;", LanguagePHP},
+		{"short echo tag", "view.tpl", "<p><?= $x ?></p>", LanguagePHP},
+		{"unknown", "readme.txt", "hello", ""},
+		{"unknown shebang without extension", "x", "#!/usr/bin/ruby\nputs 1", ""},
+	}
+	for _, tt := range tests {
+		if got := detectLanguage(tt.path, []byte(tt.content)); got != tt.want {
+			t.Errorf("%s: detectLanguage(%s) = %q, want %q", tt.name, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestInterpreterCommand(t *testing.T) {
+	// PATH 中只有伪造的 php 和 python3
+	bin := t.TempDir()
+	for _, name := range []string{"php", "python3"} {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin)
+
+	tests := []struct {
+		name         string
+		lang         string
+		interpreters map[string]string
+		want         []string
+		wantErr      string
+	}{
+		{"default", LanguagePHP, nil, []string{"php"}, ""},
+		{"configured with arguments", LanguagePython, map[string]string{LanguagePython: "python3 -I -S"}, []string{"python3", "-I", "-S"}, ""},
+		{"configured absolute path", LanguagePerl, map[string]string{LanguagePerl: filepath.Join(bin, "php") + " -n"}, []string{filepath.Join(bin, "php"), "-n"}, ""},
+		{"configured not installed", LanguagePHP, map[string]string{LanguagePHP: "php8.3 -n"}, nil, "not installed"},
+		{"default not installed", LanguagePerl, nil, nil, "not installed"},
+		{"disabled by empty command", LanguagePHP, map[string]string{LanguagePHP: ""}, nil, "no interpreter configured"},
+		{"unknown language", "ruby", nil, nil, "no interpreter configured"},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{}
+		cfg.Detection.BehaviorAnalysis.Interpreters = tt.interpreters
+		d := &Detector{config: cfg}
+
+		got, err := d.interpreterCommand(tt.lang)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want error containing %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: interpreterCommand = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
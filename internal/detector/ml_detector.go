@@ -6,63 +6,75 @@
 package detector
 
 import (
-	"context"
 	"fmt"
-	"strings"
+
+	"webshell-detector/pkg/mlmodel"
 )
 
-// mlDetect 执行机器学习检测
-func (d *Detector) mlDetect(ctx context.Context, content []byte) (float64, error) {
-	// 提取特征
-	features, err := d.extractFeatures(content)
-	if err != nil {
-		return 0, fmt.Errorf("failed to extract features: %v", err)
+// mlTopFeatures 检测结果中保留的贡献最大的特征数
+const mlTopFeatures = 5
+
+// mlDetectBatch 对一批分析结果批量打分，并将分数和判定写回各自的检测结果
+func (d *Detector) mlDetectBatch(analyses []*Analysis) {
+	fail := func(err error) {
+		fmt.Printf("Warning: ML detection failed: %v\n", err)
+		for _, a := range analyses {
+			a.degraded = true
+		}
+	}
+
+	if d.mlModel == nil {
+		fail(fmt.Errorf("ML model not loaded"))
+		return
+	}
+
+	batch := make([][]float64, len(analyses))
+	for i, a := range analyses {
+		batch[i] = a.features
 	}
 
-	// 使用模型预测
-	score, err := d.mlModel.Predict(features)
+	// 使用模型批量预测并计算特征贡献
+	explanations, version, err := d.mlModel.ExplainBatch(batch)
 	if err != nil {
-		return 0, fmt.Errorf("failed to predict: %v", err)
+		fail(fmt.Errorf("failed to predict: %v", err))
+		return
 	}
 
 	// 将预测概率转换为0-100的分数
-	return score * 100, nil
+	threshold := d.mlThreshold()
+	for i, a := range analyses {
+		probability := explanations[i].Score
+		a.Result.MLScore = probability * 100
+		a.Result.RawMLScore = a.Result.MLScore
+		a.Result.MLVerdict = probability >= threshold
+		a.Result.ModelVersion = version
+		a.Result.MLTopFeatures = explanations[i].Top(mlTopFeatures)
+	}
 }
 
-// extractFeatures 提取文件特征
-func (d *Detector) extractFeatures(content []byte) ([]float64, error) {
-	fileStr := string(content)
-	features := make([]float64, 5) // 只提取5个特征，与训练时保持一致
-
-	// 1. 文件大小
-	features[0] = float64(len(content))
-
-	// 2. 危险函数数量
-	dangerousFuncs := []string{"eval(", "system(", "exec(", "shell_exec(", "passthru("}
-	count := 0
-	for _, fun := range dangerousFuncs {
-		count += strings.Count(fileStr, fun)
+// mlThreshold 返回ML判定阈值，规则见 mlmodel.ResolveThreshold；阈值或来源变化时输出日志
+func (d *Detector) mlThreshold() float64 {
+	var modelThreshold float64
+	if d.mlModel != nil {
+		modelThreshold = d.mlModel.GetThreshold()
 	}
-	features[1] = float64(count)
-
-	// 3. base64编码字符串的数量
-	features[2] = float64(strings.Count(fileStr, "base64_decode"))
+	threshold, source := mlmodel.ResolveThreshold(modelThreshold,
+		d.config.Detection.MachineLearning.Threshold, d.config.Detection.MachineLearning.OverrideThreshold)
 
-	// 4. 超全局变量使用数量
-	superGlobals := []string{"$_POST", "$_GET", "$_REQUEST", "$_SERVER"}
-	count = 0
-	for _, sg := range superGlobals {
-		count += strings.Count(fileStr, sg)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if used := fmt.Sprintf("%g from %s", threshold, source); used != d.thresholdUsed {
+		fmt.Printf("Using ML threshold %s\n", used)
+		d.thresholdUsed = used
 	}
-	features[3] = float64(count)
+	return threshold
+}
 
-	// 5. 文件操作函数数量
-	fileOps := []string{"chmod(", "chown(", "fopen(", "file_put_contents("}
-	count = 0
-	for _, op := range fileOps {
-		count += strings.Count(fileStr, op)
+// extractFeatures 提取文件特征，特征定义见 mlmodel.FeatureNames
+func (d *Detector) extractFeatures(content []byte) ([]float64, error) {
+	features := mlmodel.ExtractFeatures(content)
+	if len(features) != mlmodel.FeatureCount {
+		return nil, fmt.Errorf("feature schema mismatch: expected %d features, got %d", mlmodel.FeatureCount, len(features))
 	}
-	features[4] = float64(count)
-
 	return features, nil
 }
@@ -0,0 +1,208 @@
+package detector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"webshell-detector/internal/config"
+)
+
+// EngineVersion 检测引擎版本，评分逻辑变化时需要递增以使缓存失效
+const EngineVersion = "5"
+
+// 缓存相关默认配置
+const (
+	defaultCachePath       = "data/cache.db"
+	defaultCacheMaxEntries = 1000000 // 缓存记录上限
+
+	// ruleCheckInterval 检查YARA规则文件是否变化的最短间隔，避免每次查询缓存都遍历规则目录
+	ruleCheckInterval = 5 * time.Second
+)
+
+// cachedVersion 返回当前引擎、特征库、YARA规则与模型的组合指纹，
+// 模型热加载、特征库更新后立即重新计算，规则文件变化后最迟在 ruleCheckInterval 后重新计算，指纹变化时清除旧版本的缓存记录。
+// 指纹计算不持有锁进行
+func (d *Detector) cachedVersion() string {
+	stamp := d.engineStamp()
+	d.mu.Lock()
+	if d.fingerprint != "" && d.fingerprintStamp == stamp {
+		version := d.fingerprint
+		d.mu.Unlock()
+		return version
+	}
+	d.mu.Unlock()
+
+	version := d.computeFingerprint()
+
+	d.mu.Lock()
+	previous := d.fingerprint
+	d.fingerprint = version
+	d.fingerprintStamp = stamp
+	d.mu.Unlock()
+
+	if version != previous && previous != "" && d.cache != nil {
+		if removed, err := d.cache.Invalidate(version); err != nil {
+			fmt.Printf("Warning: Failed to invalidate result cache: %v\n", err)
+		} else if removed > 0 {
+			fmt.Printf("Detection engine changed, %d cached result(s) invalidated\n", removed)
+		}
+	}
+	return version
+}
+
+// Fingerprint 返回当前检测引擎的组合指纹，指纹不同的检测结论不可直接复用
+func (d *Detector) Fingerprint() string {
+	return d.cachedVersion()
+}
+
+// engineStamp 返回模型和特征库的版本号以及规则文件的版本，用于判断指纹是否需要重新计算；
+// 每次查询缓存时调用，只读取内存中的版本号
+func (d *Detector) engineStamp() string {
+	h := sha256.New()
+	if d.mlModel != nil {
+		fmt.Fprintf(h, "model:%d\n", d.mlModel.Generation())
+	}
+	if d.sigMgr != nil {
+		fmt.Fprintf(h, "signatures:%d\n", d.sigMgr.Generation())
+	}
+	fmt.Fprintf(h, "rules:%s\n", d.rulesStamp())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// rulesStamp 返回规则文件大小和修改时间的摘要，每隔 ruleCheckInterval 最多遍历一次规则目录，
+// 规则文件变化后最迟在一个间隔后使指纹重新计算
+func (d *Detector) rulesStamp() string {
+	if !d.config.Detection.Yara.Enabled && len(d.shadowRules) == 0 {
+		return ""
+	}
+	d.mu.Lock()
+	if d.rulesStamped != "" && time.Since(d.rulesCheckedAt) < ruleCheckInterval {
+		stamp := d.rulesStamped
+		d.mu.Unlock()
+		return stamp
+	}
+	// 检查期间其他查询沿用上次的结果
+	d.rulesCheckedAt = time.Now()
+	d.mu.Unlock()
+
+	h := sha256.New()
+	if d.config.Detection.Yara.Enabled {
+		hashYaraRules(h, d.config.Detection.Yara)
+	}
+	for _, shadow := range d.shadowRules {
+		hashYaraRules(h, shadow.yara)
+	}
+	stamp := hex.EncodeToString(h.Sum(nil))
+
+	d.mu.Lock()
+	d.rulesStamped = stamp
+	d.mu.Unlock()
+	return stamp
+}
+
+// computeFingerprint 计算影响检测结论的全部输入的摘要
+func (d *Detector) computeFingerprint() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "engine:%s\n", EngineVersion)
+
+	// 检测配置（启用的引擎、阈值、解释器等）
+	if detectionConfig, err := json.Marshal(d.config.Detection); err == nil {
+		h.Write(detectionConfig)
+	}
+
+	// 内置正则特征与特征库
+	for _, pattern := range WebshellPatterns {
+		fmt.Fprintf(h, "regex:%s:%g\n", pattern.Pattern, pattern.Score)
+	}
+	if d.sigMgr != nil {
+		fmt.Fprintf(h, "signatures:%s\n", d.sigMgr.Fingerprint())
+	}
+
+	// YARA规则文件
+	if d.config.Detection.Yara.Enabled {
+		hashYaraRules(h, d.config.Detection.Yara)
+	}
+
+	// 机器学习模型
+	if d.mlModel != nil {
+		fmt.Fprintf(h, "model:%s\n", d.mlModel.Version())
+	}
+
+	// 影子引擎的结论随结果一起缓存
+	for _, shadow := range d.shadowModels {
+		fmt.Fprintf(h, "shadow-model:%s:%s\n", shadow.name, shadow.model.Version())
+	}
+	for _, shadow := range d.shadowRules {
+		fmt.Fprintf(h, "shadow-rules:%s\n", shadow.name)
+		hashYaraRules(h, shadow.yara)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashYaraRules 将规则文件的路径、大小和修改时间写入摘要
+func hashYaraRules(h io.Writer, yaraConfig config.YaraConfig) {
+	for _, ruleType := range yaraConfig.RuleTypes {
+		rulePath := filepath.Join(yaraConfig.RulesDir, ruleType)
+		filepath.Walk(rulePath, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
+			}
+			fmt.Fprintf(h, "yara:%s:%d:%d\n", path, info.Size(), info.ModTime().UnixNano())
+			return nil
+		})
+	}
+}
+
+// cacheKey 缓存键：内容哈希加上行为分析所用的脚本语言。
+// 相同内容换用其他扩展名时解释器不同，不能复用未经行为分析的结论
+func (d *Detector) cacheKey(contentHash, filePath string, content []byte) string {
+	if !d.config.Detection.BehaviorAnalysis.Enabled {
+		return contentHash
+	}
+	return contentHash + ":" + detectLanguage(filePath, content)
+}
+
+// lookupCache 按缓存键查询缓存的检测结果
+func (d *Detector) lookupCache(key, filePath string) (*DetectionResult, bool) {
+	payload, ok, err := d.cache.Get(key, d.cachedVersion())
+	if err != nil {
+		fmt.Printf("Warning: Result cache lookup failed: %v\n", err)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+
+	var result DetectionResult
+	if err := json.Unmarshal(payload, &result); err != nil {
+		fmt.Printf("Warning: Corrupted result cache entry: %v\n", err)
+		return nil, false
+	}
+	result.FilePath = filePath
+	return &result, true
+}
+
+// storeCache 写入检测结果缓存
+func (d *Detector) storeCache(key string, result *DetectionResult) {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		fmt.Printf("Warning: Failed to encode cache entry: %v\n", err)
+		return
+	}
+	if err := d.cache.Put(key, d.cachedVersion(), payload); err != nil {
+		fmt.Printf("Warning: Failed to store cache entry: %v\n", err)
+	}
+}
+
+// contentHash 计算文件内容的 SHA-256
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
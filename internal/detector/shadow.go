@@ -0,0 +1,161 @@
+package detector
+
+import (
+	"fmt"
+
+	"webshell-detector/internal/config"
+	"webshell-detector/pkg/mlmodel"
+)
+
+// 影子引擎类型
+const (
+	ShadowKindModel = "model" // 影子模型，替换机器学习得分
+	ShadowKindRules = "rules" // 影子规则集，替换特征匹配得分
+)
+
+// ShadowResult 影子引擎的检测结论，只用于记录和对比，不影响正式判定
+type ShadowResult struct {
+	Name        string
+	Kind        string
+	Version     string    // 影子模型版本，规则集为空
+	EngineScore float64   // 影子引擎替换的那一项得分
+	ActiveScore float64   // 当前引擎对应一项的得分
+	TotalScore  float64   // 使用影子引擎得分计算的总分
+	RiskLevel   RiskLevel // 使用影子引擎得分确定的风险等级
+	IsWebshell  bool      // 使用影子引擎得分的判定
+	Disagrees   bool      // 判定与当前引擎不一致
+	Error       string    `json:",omitempty"` // 影子引擎执行失败的原因
+}
+
+// shadowModel 已加载的影子模型
+type shadowModel struct {
+	name  string
+	model *mlmodel.Model
+}
+
+// shadowRuleSet 影子规则集
+type shadowRuleSet struct {
+	name string
+	yara config.YaraConfig
+}
+
+// shadowMatch 影子规则集的特征匹配结果，在 Analyze 中计算，Finalize 中汇总
+type shadowMatch struct {
+	score float64
+	err   error
+}
+
+// loadShadows 按配置加载影子模型和规则集，加载失败的影子引擎跳过
+func (d *Detector) loadShadows() {
+	shadowConfig := d.config.Detection.Shadow
+	if !shadowConfig.Enabled {
+		return
+	}
+
+	for _, m := range shadowConfig.Models {
+		model, err := mlmodel.LoadModel(m.Path)
+		if err != nil {
+			fmt.Printf("Warning: Failed to load shadow model %s: %v\n", m.Name, err)
+			continue
+		}
+		d.shadowModels = append(d.shadowModels, &shadowModel{name: m.Name, model: model})
+	}
+
+	for _, r := range shadowConfig.RuleSets {
+		yaraConfig := d.config.Detection.Yara
+		yaraConfig.Enabled = true
+		yaraConfig.RulesDir = r.RulesDir
+		yaraConfig.RuleTypes = r.RuleTypes
+		d.shadowRules = append(d.shadowRules, &shadowRuleSet{name: r.Name, yara: yaraConfig})
+	}
+}
+
+// shadowFeatureMatch 使用各影子规则集执行特征匹配
+func (d *Detector) shadowFeatureMatch(content []byte) []shadowMatch {
+	if len(d.shadowRules) == 0 {
+		return nil
+	}
+
+	matches := make([]shadowMatch, len(d.shadowRules))
+	for i, rules := range d.shadowRules {
+		result, err := d.featureMatchWith(content, rules.yara)
+		if err != nil {
+			matches[i].err = err
+			continue
+		}
+		matches[i].score = result.Score
+	}
+	return matches
+}
+
+// evaluateShadows 计算一批分析结果在各影子引擎下的结论，需在正式结果计算总分前调用，
+// 以便基于同样的原始得分重新计算
+func (d *Detector) evaluateShadows(analyses []*Analysis) {
+	if len(d.shadowModels) == 0 && len(d.shadowRules) == 0 {
+		return
+	}
+
+	// 影子模型批量打分
+	var pending []*Analysis
+	var batch [][]float64
+	for _, a := range analyses {
+		if !a.complete && a.features != nil {
+			pending = append(pending, a)
+			batch = append(batch, a.features)
+		}
+	}
+	for _, shadow := range d.shadowModels {
+		var scores []float64
+		var version string
+		var err error
+		if len(batch) > 0 {
+			scores, version, err = shadow.model.PredictBatch(batch)
+		}
+		for i, a := range pending {
+			r := &ShadowResult{Name: shadow.name, Kind: ShadowKindModel, Version: version, ActiveScore: a.Result.MLScore}
+			// 影子引擎失败只记录在影子结论中，不影响正式结果及其缓存
+			if err != nil {
+				r.Error = err.Error()
+			} else {
+				r.EngineScore = scores[i] * 100
+				d.shadowTotal(a.Result, r, func(c *DetectionResult) { c.MLScore = r.EngineScore })
+			}
+			a.shadows = append(a.shadows, r)
+		}
+	}
+
+	// 影子规则集
+	for _, a := range analyses {
+		if a.complete {
+			continue
+		}
+		for i, match := range a.shadowMatches {
+			r := &ShadowResult{Name: d.shadowRules[i].name, Kind: ShadowKindRules, ActiveScore: a.Result.FeatureScore}
+			if match.err != nil {
+				r.Error = match.err.Error()
+			} else {
+				r.EngineScore = match.score
+				d.shadowTotal(a.Result, r, func(c *DetectionResult) { c.FeatureScore = match.score })
+			}
+			a.shadows = append(a.shadows, r)
+		}
+	}
+}
+
+// shadowTotal 在检测结果的副本上替换影子引擎得分后计算总分和判定
+func (d *Detector) shadowTotal(result *DetectionResult, shadow *ShadowResult, replace func(*DetectionResult)) {
+	c := *result
+	replace(&c)
+	d.calculateTotalScore(&c)
+	shadow.TotalScore = c.TotalScore
+	shadow.RiskLevel = c.RiskLevel
+	shadow.IsWebshell = c.IsWebshell
+}
+
+// attachShadows 正式结果计算完成后记录影子结论及其与正式判定的分歧
+func attachShadows(a *Analysis) {
+	for _, shadow := range a.shadows {
+		shadow.Disagrees = shadow.Error == "" && shadow.IsWebshell != a.Result.IsWebshell
+		a.Result.Shadow = append(a.Result.Shadow, *shadow)
+	}
+}
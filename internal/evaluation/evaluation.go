@@ -0,0 +1,233 @@
+package evaluation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"webshell-detector/internal/dataset"
+	"webshell-detector/internal/detector"
+)
+
+// engineVerdictScore 单引擎分数达到该值即视为判定为 webshell
+const engineVerdictScore = 50.0
+
+// Engine 参与评估的检测引擎
+type Engine struct {
+	Name    string
+	Score   func(r *detector.DetectionResult) float64
+	Verdict func(r *detector.DetectionResult) bool
+}
+
+// Engines 评估的引擎列表：完整检测器以及各单独引擎，单独引擎使用合成总分前的原始得分
+var Engines = []Engine{
+	{
+		Name:    "detector",
+		Score:   func(r *detector.DetectionResult) float64 { return r.TotalScore },
+		Verdict: func(r *detector.DetectionResult) bool { return r.IsWebshell },
+	},
+	{
+		Name:    "feature",
+		Score:   func(r *detector.DetectionResult) float64 { return r.FeatureScore },
+		Verdict: func(r *detector.DetectionResult) bool { return r.FeatureScore >= engineVerdictScore },
+	},
+	{
+		Name:    "behavior",
+		Score:   func(r *detector.DetectionResult) float64 { return r.RawBehaviorScore },
+		Verdict: func(r *detector.DetectionResult) bool { return r.RawBehaviorScore >= engineVerdictScore },
+	},
+	{
+		Name:    "ml",
+		Score:   func(r *detector.DetectionResult) float64 { return r.RawMLScore },
+		Verdict: func(r *detector.DetectionResult) bool { return r.MLVerdict },
+	},
+}
+
+// ConfusionMatrix 混淆矩阵
+type ConfusionMatrix struct {
+	TP int `json:"tp"`
+	FP int `json:"fp"`
+	TN int `json:"tn"`
+	FN int `json:"fn"`
+}
+
+// CurvePoint ROC/PR 曲线上的一个点
+type CurvePoint struct {
+	Threshold float64 `json:"threshold"`
+	TPR       float64 `json:"tpr"`
+	FPR       float64 `json:"fpr"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+}
+
+// SampleScore 样本及其引擎分数
+type SampleScore struct {
+	Path  string  `json:"path"`
+	Label int     `json:"label"`
+	Score float64 `json:"score"`
+}
+
+// EngineReport 单个引擎的评估结果
+type EngineReport struct {
+	Engine            string          `json:"engine"`
+	Confusion         ConfusionMatrix `json:"confusion"`
+	Accuracy          float64         `json:"accuracy"`
+	Precision         float64         `json:"precision"`
+	Recall            float64         `json:"recall"`
+	F1                float64         `json:"f1"`
+	FalsePositiveRate float64         `json:"false_positive_rate"`
+	ROCAUC            float64         `json:"roc_auc"`
+	Curve             []CurvePoint    `json:"curve"`
+	FalsePositives    []SampleScore   `json:"false_positives"`
+	FalseNegatives    []SampleScore   `json:"false_negatives"`
+}
+
+// Report 评估报告
+type Report struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Samples     int             `json:"samples"`
+	Malicious   int             `json:"malicious"`
+	Benign      int             `json:"benign"`
+	Failed      []string        `json:"failed"`
+	Duration    string          `json:"duration"`
+	Engines     []*EngineReport `json:"engines"`
+}
+
+// labeledResult 样本标签与检测结果
+type labeledResult struct {
+	sample dataset.Sample
+	result *detector.DetectionResult
+}
+
+// Run 使用检测器检测全部样本并生成评估报告
+func Run(ctx context.Context, det *detector.Detector, samples []dataset.Sample) (*Report, error) {
+	startTime := time.Now()
+	report := &Report{GeneratedAt: startTime}
+
+	var results []labeledResult
+	for _, sample := range samples {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result, err := det.Detect(ctx, sample.Path)
+		if err != nil {
+			report.Failed = append(report.Failed, fmt.Sprintf("%s: %v", sample.Path, err))
+			continue
+		}
+		results = append(results, labeledResult{sample: sample, result: result})
+		if sample.Label == dataset.LabelMalicious {
+			report.Malicious++
+		} else {
+			report.Benign++
+		}
+	}
+	report.Samples = len(results)
+	if report.Samples == 0 {
+		return nil, fmt.Errorf("no samples could be analyzed")
+	}
+
+	for _, engine := range Engines {
+		report.Engines = append(report.Engines, evaluateEngine(engine, results))
+	}
+	report.Duration = time.Since(startTime).String()
+	return report, nil
+}
+
+// evaluateEngine 计算单个引擎的指标与曲线
+func evaluateEngine(engine Engine, results []labeledResult) *EngineReport {
+	er := &EngineReport{Engine: engine.Name}
+	scores := make([]SampleScore, 0, len(results))
+
+	for _, lr := range results {
+		score := SampleScore{
+			Path:  lr.sample.Path,
+			Label: lr.sample.Label,
+			Score: engine.Score(lr.result),
+		}
+		scores = append(scores, score)
+
+		predicted := engine.Verdict(lr.result)
+		actual := lr.sample.Label == dataset.LabelMalicious
+		switch {
+		case predicted && actual:
+			er.Confusion.TP++
+		case predicted && !actual:
+			er.Confusion.FP++
+			er.FalsePositives = append(er.FalsePositives, score)
+		case !predicted && actual:
+			er.Confusion.FN++
+			er.FalseNegatives = append(er.FalseNegatives, score)
+		default:
+			er.Confusion.TN++
+		}
+	}
+
+	c := er.Confusion
+	er.Accuracy = ratio(c.TP+c.TN, c.TP+c.TN+c.FP+c.FN)
+	er.Precision = ratio(c.TP, c.TP+c.FP)
+	er.Recall = ratio(c.TP, c.TP+c.FN)
+	er.FalsePositiveRate = ratio(c.FP, c.FP+c.TN)
+	if er.Precision+er.Recall > 0 {
+		er.F1 = 2 * er.Precision * er.Recall / (er.Precision + er.Recall)
+	}
+
+	er.Curve = buildCurve(scores)
+	er.ROCAUC = rocAUC(er.Curve)
+	return er
+}
+
+// buildCurve 以每个不同的分数作为阈值（score >= 阈值判定为恶意）计算曲线点，阈值从高到低排列
+func buildCurve(scores []SampleScore) []CurvePoint {
+	sorted := make([]SampleScore, len(scores))
+	copy(sorted, scores)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Score > sorted[j].Score })
+
+	positives, negatives := 0, 0
+	for _, s := range sorted {
+		if s.Label == dataset.LabelMalicious {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+
+	curve := []CurvePoint{{Threshold: sorted[0].Score + 1, Precision: 1}}
+	tp, fp := 0, 0
+	for i, s := range sorted {
+		if s.Label == dataset.LabelMalicious {
+			tp++
+		} else {
+			fp++
+		}
+		// 相同分数的样本在同一阈值下一起计入
+		if i+1 < len(sorted) && sorted[i+1].Score == s.Score {
+			continue
+		}
+		curve = append(curve, CurvePoint{
+			Threshold: s.Score,
+			TPR:       ratio(tp, positives),
+			FPR:       ratio(fp, negatives),
+			Precision: ratio(tp, tp+fp),
+			Recall:    ratio(tp, positives),
+		})
+	}
+	return curve
+}
+
+// rocAUC 梯形法计算 ROC 曲线下面积
+func rocAUC(curve []CurvePoint) float64 {
+	auc := 0.0
+	for i := 1; i < len(curve); i++ {
+		auc += (curve[i].FPR - curve[i-1].FPR) * (curve[i].TPR + curve[i-1].TPR) / 2
+	}
+	return auc
+}
+
+// ratio 安全除法
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
@@ -0,0 +1,65 @@
+package evaluation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// WriteJSON 将评估报告以 JSON 写入文件
+func (r *Report) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %v", err)
+	}
+	return nil
+}
+
+// PrintTable 以表格形式打印评估摘要
+func (r *Report) PrintTable(out io.Writer, maxListed int) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "\n========== Evaluation Report ==========")
+	fmt.Fprintf(w, "Samples:\t%d (malicious %d, benign %d)\n", r.Samples, r.Malicious, r.Benign)
+	if len(r.Failed) > 0 {
+		fmt.Fprintf(w, "Failed:\t%d\n", len(r.Failed))
+	}
+	fmt.Fprintf(w, "Duration:\t%s\n\n", r.Duration)
+
+	fmt.Fprintln(w, "Engine\tTP\tFP\tTN\tFN\tPrecision\tRecall\tF1\tFPR\tROC AUC")
+	for _, e := range r.Engines {
+		c := e.Confusion
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\n",
+			e.Engine, c.TP, c.FP, c.TN, c.FN, e.Precision, e.Recall, e.F1, e.FalsePositiveRate, e.ROCAUC)
+	}
+	w.Flush()
+
+	// 完整检测器的误报与漏报
+	for _, e := range r.Engines {
+		if e.Engine != "detector" {
+			continue
+		}
+		printSamples(out, "False Positives", e.FalsePositives, maxListed)
+		printSamples(out, "False Negatives", e.FalseNegatives, maxListed)
+	}
+}
+
+// printSamples 打印样本列表，超过上限时截断
+func printSamples(out io.Writer, title string, samples []SampleScore, maxListed int) {
+	if len(samples) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n%s (%d):\n", title, len(samples))
+	for i, s := range samples {
+		if maxListed > 0 && i >= maxListed {
+			fmt.Fprintf(out, "   ... %d more\n", len(samples)-maxListed)
+			break
+		}
+		fmt.Fprintf(out, "   - %s (score %.2f)\n", s.Path, s.Score)
+	}
+}
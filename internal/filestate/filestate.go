@@ -0,0 +1,203 @@
+package filestate
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DefaultIndexPath 文件状态索引的默认路径
+const DefaultIndexPath = "data/filestate.db"
+
+// State 一个文件上次扫描时的状态和结论
+type State struct {
+	Path          string
+	Size          int64
+	ModTime       time.Time
+	Inode         uint64
+	ContentHash   string
+	RiskLevel     string
+	IsWebshell    bool
+	TotalScore    float64
+	EngineVersion string // 扫描时的检测引擎指纹，引擎变化后需要重新扫描
+	ScanTime      time.Time
+}
+
+// Index 持久化的文件状态索引，用于增量扫描时跳过未变化的文件
+type Index struct {
+	db *sql.DB
+}
+
+// NewIndex 打开文件状态索引
+func NewIndex(dbPath string) (*Index, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create index directory: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	// 遍历和结果处理在不同协程中写入，使用单连接避免 database is locked
+	db.SetMaxOpenConns(1)
+
+	if err := initIndexDatabase(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize database: %v", err)
+	}
+
+	return &Index{db: db}, nil
+}
+
+// initIndexDatabase 初始化索引数据库
+func initIndexDatabase(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS file_state (
+		path TEXT PRIMARY KEY,
+		size INTEGER NOT NULL,
+		mod_time INTEGER NOT NULL,
+		inode INTEGER NOT NULL,
+		content_hash TEXT,
+		risk_level TEXT,
+		is_webshell BOOLEAN,
+		total_score REAL,
+		engine_version TEXT,
+		scan_time INTEGER,
+		last_run TEXT
+	);
+	CREATE INDEX IF NOT EXISTS idx_file_state_run ON file_state(last_run);
+	`)
+	return err
+}
+
+// Get 查询文件的状态，不存在时返回 nil
+func (i *Index) Get(path string) (*State, error) {
+	var (
+		state    State
+		modTime  int64
+		scanTime int64
+		hash     sql.NullString
+		level    sql.NullString
+		webshell sql.NullBool
+		score    sql.NullFloat64
+		engine   sql.NullString
+	)
+	err := i.db.QueryRow(`
+		SELECT path, size, mod_time, inode, content_hash, risk_level,
+		       is_webshell, total_score, engine_version, COALESCE(scan_time, 0)
+		FROM file_state WHERE path = ?
+	`, path).Scan(&state.Path, &state.Size, &modTime, &state.Inode, &hash, &level,
+		&webshell, &score, &engine, &scanTime)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query file state: %v", err)
+	}
+
+	state.ModTime = time.Unix(0, modTime)
+	state.ScanTime = time.Unix(0, scanTime)
+	state.ContentHash = hash.String
+	state.RiskLevel = level.String
+	state.IsWebshell = webshell.Bool
+	state.TotalScore = score.Float64
+	state.EngineVersion = engine.String
+	return &state, nil
+}
+
+// Put 写入文件状态，并标记该文件在 runID 中出现过
+func (i *Index) Put(state *State, runID string) error {
+	_, err := i.db.Exec(`
+		INSERT OR REPLACE INTO file_state (
+			path, size, mod_time, inode, content_hash, risk_level,
+			is_webshell, total_score, engine_version, scan_time, last_run
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		state.Path,
+		state.Size,
+		state.ModTime.UnixNano(),
+		int64(state.Inode),
+		state.ContentHash,
+		state.RiskLevel,
+		state.IsWebshell,
+		state.TotalScore,
+		state.EngineVersion,
+		state.ScanTime.UnixNano(),
+		runID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store file state: %v", err)
+	}
+	return nil
+}
+
+// Touch 标记文件在 runID 中出现过
+func (i *Index) Touch(path, runID string) error {
+	if _, err := i.db.Exec("UPDATE file_state SET last_run = ? WHERE path = ?", runID, path); err != nil {
+		return fmt.Errorf("failed to update file state: %v", err)
+	}
+	return nil
+}
+
+// Prune 删除 scope 范围内未在 runID 中出现的文件（即已删除的文件），返回删除的路径；
+// scope 为 nil 时检查全部记录，多个扫描任务共用索引时用于只清理本任务覆盖的文件。
+// 仍然存在的文件（如同时运行的其他任务最后标记的文件）不视为已删除
+func (i *Index) Prune(runID string, scope func(path string) bool) ([]string, error) {
+	rows, err := i.db.Query("SELECT path FROM file_state WHERE last_run IS NULL OR last_run != ?", runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted files: %v", err)
+	}
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if scope != nil && !scope(path) {
+			continue
+		}
+		if _, err := os.Lstat(path); err == nil {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read deleted files: %v", err)
+	}
+
+	for _, path := range paths {
+		if _, err := i.db.Exec("DELETE FROM file_state WHERE path = ?", path); err != nil {
+			return nil, fmt.Errorf("failed to prune file state: %v", err)
+		}
+	}
+	return paths, nil
+}
+
+// Close 关闭索引
+func (i *Index) Close() error {
+	return i.db.Close()
+}
+
+// HashFile 计算文件内容的 SHA-256，与检测结果中的 ContentHash 一致
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,102 @@
+package filestate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openTestIndex 在临时目录中打开索引
+func openTestIndex(t *testing.T) *Index {
+	t.Helper()
+	index, err := NewIndex(filepath.Join(t.TempDir(), "filestate.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { index.Close() })
+	return index
+}
+
+func TestPutGet(t *testing.T) {
+	index := openTestIndex(t)
+	want := &State{
+		Path:          "/var/www/shell.php",
+		Size:          42,
+		ModTime:       time.Unix(0, 1700000000123456789),
+		Inode:         1234,
+		ContentHash:   strings.Repeat("ab", 32),
+		RiskLevel:     "HIGH",
+		IsWebshell:    true,
+		TotalScore:    87.5,
+		EngineVersion: "engine-1",
+		ScanTime:      time.Unix(0, 1700000100000000000),
+	}
+	if err := index.Put(want, "run-1"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := index.Get(want.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Get = %+v, want %+v", got, want)
+	}
+	if missing, err := index.Get("/var/www/missing.php"); err != nil || missing != nil {
+		t.Errorf("Get of unknown path = %+v, %v; want nil, nil", missing, err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	index := openTestIndex(t)
+	dir := t.TempDir()
+	site := filepath.Join(dir, "site")
+	other := filepath.Join(dir, "other")
+
+	// 本次扫描中出现的文件、已删除的文件、仍存在但未出现的文件和范围外已删除的文件
+	seen := filepath.Join(site, "index.php")
+	deleted := filepath.Join(site, "deleted.php")
+	existing := filepath.Join(site, "existing.php")
+	outside := filepath.Join(other, "deleted.php")
+	if err := os.MkdirAll(site, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(existing, []byte("<?php"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{seen, deleted, existing, outside} {
+		if err := index.Put(&State{Path: path}, "run-1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := index.Touch(seen, "run-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	inSite := func(path string) bool { return strings.HasPrefix(path, site+string(filepath.Separator)) }
+	pruned, err := index.Prune("run-2", inSite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pruned, []string{deleted}) {
+		t.Errorf("Prune in scope = %v, want [%s]", pruned, deleted)
+	}
+	for _, path := range []string{seen, existing, outside} {
+		if state, err := index.Get(path); err != nil || state == nil {
+			t.Errorf("%s was removed from the index: %v", path, err)
+		}
+	}
+
+	// 不限范围时清理所有已删除的文件
+	pruned, err = index.Prune("run-2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(pruned)
+	if !reflect.DeepEqual(pruned, []string{outside}) {
+		t.Errorf("Prune without scope = %v, want [%s]", pruned, outside)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package filestate
+
+import "io/fs"
+
+// Inode 当前平台不提供 inode 号，始终返回 0
+func Inode(info fs.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package filestate
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// Inode 返回文件的 inode 号
+func Inode(info fs.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
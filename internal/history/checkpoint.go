@@ -0,0 +1,119 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 断点状态
+const (
+	CheckpointRunning     = "running"     // 扫描进行中，进程崩溃时保持该状态
+	CheckpointInterrupted = "interrupted" // 扫描被停止，下次启动时继续
+)
+
+// Checkpoint 批量扫描的断点：遍历位置、位置之后已完成的文件和扫描器的中间统计
+type Checkpoint struct {
+	ScanID     string
+	ScanType   string
+	ScanKey    string // 扫描范围的标识，范围相同的扫描才能从断点继续
+	Status     string
+	PID        int
+	WalkTarget int      // 遍历到的扫描目标序号，尚未完成任何文件时为 -1
+	WalkPath   string   // 该目标中此路径及之前的文件均已完成
+	Completed  []string // 遍历位置之后已完成的文件
+	State      json.RawMessage
+	StartTime  time.Time
+	UpdatedAt  time.Time
+}
+
+// SaveCheckpoint 写入或更新扫描的断点
+func (m *Manager) SaveCheckpoint(checkpoint *Checkpoint) error {
+	completed, err := json.Marshal(checkpoint.Completed)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %v", err)
+	}
+	_, err = m.db.Exec(`
+		INSERT OR REPLACE INTO scan_checkpoints (
+			scan_id, scan_type, scan_key, status, pid,
+			walk_target, walk_path, completed, state,
+			start_time, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		checkpoint.ScanID,
+		checkpoint.ScanType,
+		checkpoint.ScanKey,
+		checkpoint.Status,
+		checkpoint.PID,
+		checkpoint.WalkTarget,
+		checkpoint.WalkPath,
+		string(completed),
+		string(checkpoint.State),
+		checkpoint.StartTime,
+		checkpoint.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint %s: %v", checkpoint.ScanID, err)
+	}
+	return nil
+}
+
+// Checkpoints 返回同一扫描范围的全部断点，按更新时间从新到旧排序
+func (m *Manager) Checkpoints(scanKey string) ([]*Checkpoint, error) {
+	rows, err := m.db.Query(`
+		SELECT scan_id, scan_type, scan_key, status, pid,
+			walk_target, walk_path, completed, state,
+			start_time, updated_at
+		FROM scan_checkpoints
+		WHERE scan_key = ?
+		ORDER BY updated_at DESC
+	`, scanKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query checkpoints: %v", err)
+	}
+	defer rows.Close()
+
+	var checkpoints []*Checkpoint
+	for rows.Next() {
+		var checkpoint Checkpoint
+		var completed, state string
+		if err := rows.Scan(
+			&checkpoint.ScanID,
+			&checkpoint.ScanType,
+			&checkpoint.ScanKey,
+			&checkpoint.Status,
+			&checkpoint.PID,
+			&checkpoint.WalkTarget,
+			&checkpoint.WalkPath,
+			&completed,
+			&state,
+			&checkpoint.StartTime,
+			&checkpoint.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan checkpoint: %v", err)
+		}
+		if err := json.Unmarshal([]byte(completed), &checkpoint.Completed); err != nil {
+			return nil, fmt.Errorf("failed to parse checkpoint %s: %v", checkpoint.ScanID, err)
+		}
+		checkpoint.State = json.RawMessage(state)
+		checkpoints = append(checkpoints, &checkpoint)
+	}
+	return checkpoints, rows.Err()
+}
+
+// DeleteCheckpoints 删除同一扫描范围的全部断点，该范围的扫描完成或被取消后调用，
+// 同时运行的其他扫描会在下次写入时重新创建自己的断点
+func (m *Manager) DeleteCheckpoints(scanKey string) error {
+	if _, err := m.db.Exec("DELETE FROM scan_checkpoints WHERE scan_key = ?", scanKey); err != nil {
+		return fmt.Errorf("failed to delete checkpoints: %v", err)
+	}
+	return nil
+}
+
+// DeleteCheckpoint 删除扫描的断点
+func (m *Manager) DeleteCheckpoint(scanID string) error {
+	if _, err := m.db.Exec("DELETE FROM scan_checkpoints WHERE scan_id = ?", scanID); err != nil {
+		return fmt.Errorf("failed to delete checkpoint %s: %v", scanID, err)
+	}
+	return nil
+}
@@ -15,6 +15,7 @@ type Manager struct {
 	retentionDays   int
 	maxRecords      int
 	cleanupInterval time.Duration
+	stop            chan struct{} // 关闭时停止定期清理任务
 }
 
 // Config 历史记录配置
@@ -37,6 +38,7 @@ func NewManager(cfg Config) (*Manager, error) {
 		retentionDays:   cfg.RetentionDays,
 		maxRecords:      cfg.MaxRecords,
 		cleanupInterval: cfg.CleanupInterval,
+		stop:            make(chan struct{}),
 	}
 
 	if err := manager.initDatabase(); err != nil {
@@ -44,8 +46,10 @@ func NewManager(cfg Config) (*Manager, error) {
 		return nil, err
 	}
 
-	// 启动定期清理任务
-	go manager.startCleanupTask()
+	// 启动定期清理任务，未配置清理间隔时不启动（如一次性的手动扫描）
+	if cfg.CleanupInterval > 0 {
+		go manager.startCleanupTask()
+	}
 
 	return manager, nil
 }
@@ -70,6 +74,20 @@ func (m *Manager) initDatabase() error {
 	);
 	CREATE INDEX IF NOT EXISTS idx_scan_time ON scan_history(start_time);
 	CREATE INDEX IF NOT EXISTS idx_scan_type ON scan_history(scan_type);
+	CREATE TABLE IF NOT EXISTS scan_checkpoints (
+		scan_id TEXT PRIMARY KEY,
+		scan_type TEXT NOT NULL,
+		scan_key TEXT NOT NULL,
+		status TEXT NOT NULL,
+		pid INTEGER NOT NULL,
+		walk_target INTEGER NOT NULL,
+		walk_path TEXT NOT NULL,
+		completed TEXT,
+		state TEXT,
+		start_time DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_checkpoint_key ON scan_checkpoints(scan_key);
 	`
 
 	_, err := m.db.Exec(createTable)
@@ -80,30 +80,36 @@ func (m *Manager) GetStatistics(startTime, endTime time.Time) (*Statistics, erro
 	return stats, nil
 }
 
-// startCleanupTask 启动清理任务
+// startCleanupTask 启动清理任务，启动时立即清理一次，之后按间隔清理，直到管理器关闭
 func (m *Manager) startCleanupTask() {
 	ticker := time.NewTicker(m.cleanupInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
 		if err := m.cleanup(); err != nil {
 			fmt.Printf("History cleanup failed: %v\n", err)
 		}
+		select {
+		case <-ticker.C:
+		case <-m.stop:
+			return
+		}
 	}
 }
 
 // cleanup 清理过期记录
 func (m *Manager) cleanup() error {
-	// 清理过期记录
-	cutoff := time.Now().AddDate(0, 0, -m.retentionDays)
-	_, err := m.db.Exec("DELETE FROM scan_history WHERE start_time < ?", cutoff)
-	if err != nil {
-		return fmt.Errorf("failed to delete old records: %v", err)
+	// 清理过期记录，未配置保留天数时不按时间清理
+	if m.retentionDays > 0 {
+		cutoff := time.Now().AddDate(0, 0, -m.retentionDays)
+		if _, err := m.db.Exec("DELETE FROM scan_history WHERE start_time < ?", cutoff); err != nil {
+			return fmt.Errorf("failed to delete old records: %v", err)
+		}
 	}
 
 	// 如果记录数超过限制，删除最旧的记录
 	if m.maxRecords > 0 {
-		_, err = m.db.Exec(`
+		_, err := m.db.Exec(`
 			DELETE FROM scan_history
 			WHERE id IN (
 				SELECT id FROM scan_history
@@ -121,5 +127,6 @@ func (m *Manager) cleanup() error {
 
 // Close 关闭历史记录管理器
 func (m *Manager) Close() error {
+	close(m.stop)
 	return m.db.Close()
 }
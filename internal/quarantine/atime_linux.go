@@ -0,0 +1,17 @@
+//go:build linux
+
+package quarantine
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// accessTime 返回文件的访问时间
+func accessTime(info fs.FileInfo) time.Time {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec))
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package quarantine
+
+import (
+	"io/fs"
+	"time"
+)
+
+// accessTime 当前平台未读取访问时间，使用修改时间代替
+func accessTime(info fs.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package quarantine
+
+import "io/fs"
+
+// fileOwner 当前平台不提供属主信息，始终返回 -1
+func fileOwner(info fs.FileInfo) (int, int) {
+	return -1, -1
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package quarantine
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileOwner 返回文件的属主和属组
+func fileOwner(info fs.FileInfo) (int, int) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(stat.Uid), int(stat.Gid)
+	}
+	return -1, -1
+}
@@ -0,0 +1,509 @@
+package quarantine
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DefaultDir 隔离区的默认目录
+const DefaultDir = "data/quarantine"
+
+// 隔离记录状态
+const (
+	StatusQuarantined = "quarantined" // 文件保存在隔离区中
+	StatusRestored    = "restored"    // 已恢复到原位置或指定位置
+	StatusPurged      = "purged"      // 隔离文件已永久删除，只保留记录
+)
+
+// Detail 隔离原因，自动隔离时来自检测结果
+type Detail struct {
+	RiskLevel  string
+	TotalScore float64
+	IsWebshell bool
+	Reason     string
+	Actor      string // 执行隔离的分析人员或自动隔离的扫描类型
+}
+
+// Entry 一条隔离记录
+type Entry struct {
+	ID           int64
+	OriginalPath string
+	SHA256       string
+	Size         int64
+	Mode         fs.FileMode
+	UID          int // 平台不支持时为 -1
+	GID          int
+	ModTime      time.Time
+	AccessTime   time.Time
+	Detail
+	QuarantinedAt time.Time
+	Status        string
+	RestoredTo    string
+	RestoredAt    time.Time
+	PurgedAt      time.Time
+
+	vaultFile string
+}
+
+// Vault 隔离区：文件内容以 AES-256-GCM 加密保存，无法被 Web 服务器解析执行，
+// 原路径、属主、权限、时间戳和哈希记录在隔离区数据库中
+type Vault struct {
+	dir  string
+	db   *sql.DB
+	aead cipher.AEAD
+}
+
+// Open 打开隔离区，keyFile 为空时使用隔离区目录下的 vault.key，密钥文件不存在时自动生成
+func Open(dir, keyFile string) (*Vault, error) {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	if keyFile == "" {
+		keyFile = filepath.Join(dir, "vault.key")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "files"), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create quarantine directory: %v", err)
+	}
+
+	key, err := loadKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize cipher: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize cipher: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "vault.db"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	// 扫描协程和命令行可能同时写入，使用单连接避免 database is locked
+	db.SetMaxOpenConns(1)
+	if err := initVaultDatabase(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize database: %v", err)
+	}
+
+	return &Vault{dir: dir, db: db, aead: aead}, nil
+}
+
+// initVaultDatabase 初始化隔离记录表
+func initVaultDatabase(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS quarantine (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		original_path TEXT NOT NULL,
+		vault_file TEXT NOT NULL,
+		sha256 TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		mode INTEGER NOT NULL,
+		uid INTEGER NOT NULL,
+		gid INTEGER NOT NULL,
+		mod_time INTEGER NOT NULL,
+		access_time INTEGER NOT NULL,
+		risk_level TEXT,
+		total_score REAL,
+		is_webshell BOOLEAN,
+		reason TEXT,
+		actor TEXT,
+		quarantined_at INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		restored_to TEXT,
+		restored_at INTEGER,
+		purged_at INTEGER
+	);
+	CREATE INDEX IF NOT EXISTS idx_quarantine_status ON quarantine(status);
+	CREATE INDEX IF NOT EXISTS idx_quarantine_sha256 ON quarantine(sha256);
+	`)
+	return err
+}
+
+// loadKey 读取 32 字节的加密密钥，文件不存在时生成新密钥
+func loadKey(keyFile string) ([]byte, error) {
+	data, err := os.ReadFile(keyFile)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("invalid quarantine key file %s: expected 64 hex characters", keyFile)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read quarantine key: %v", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate quarantine key: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %v", err)
+	}
+	// O_EXCL 防止并发启动的进程各自生成不同的密钥
+	f, err := os.OpenFile(keyFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return loadKey(keyFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create quarantine key: %v", err)
+	}
+	if _, err := f.WriteString(hex.EncodeToString(key) + "\n"); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write quarantine key: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write quarantine key: %v", err)
+	}
+	return key, nil
+}
+
+// Quarantine 将文件加密移入隔离区并删除原文件，返回隔离记录。
+// expectedHash 非空时文件内容必须与之相同，避免检测之后被替换的正常文件被隔离
+func (v *Vault) Quarantine(path, expectedHash string, detail Detail) (*Entry, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %v", abs, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", abs)
+	}
+	content, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", abs, err)
+	}
+
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	if expectedHash != "" && hash != expectedHash {
+		return nil, fmt.Errorf("%s changed since it was scanned (sha256 %s, expected %s)", abs, hash, expectedHash)
+	}
+	uid, gid := fileOwner(info)
+	entry := &Entry{
+		OriginalPath:  abs,
+		SHA256:        hash,
+		Size:          int64(len(content)),
+		Mode:          info.Mode().Perm(),
+		UID:           uid,
+		GID:           gid,
+		ModTime:       info.ModTime(),
+		AccessTime:    accessTime(info),
+		Detail:        detail,
+		QuarantinedAt: time.Now(),
+		Status:        StatusQuarantined,
+	}
+
+	vaultFile, err := v.seal(content)
+	if err != nil {
+		return nil, err
+	}
+	entry.vaultFile = vaultFile
+
+	res, err := v.db.Exec(`
+		INSERT INTO quarantine (
+			original_path, vault_file, sha256, size, mode, uid, gid, mod_time, access_time,
+			risk_level, total_score, is_webshell, reason, actor, quarantined_at, status
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		entry.OriginalPath,
+		entry.vaultFile,
+		entry.SHA256,
+		entry.Size,
+		uint32(entry.Mode),
+		entry.UID,
+		entry.GID,
+		entry.ModTime.UnixNano(),
+		entry.AccessTime.UnixNano(),
+		entry.RiskLevel,
+		entry.TotalScore,
+		entry.IsWebshell,
+		entry.Reason,
+		entry.Actor,
+		entry.QuarantinedAt.UnixNano(),
+		entry.Status,
+	)
+	if err != nil {
+		os.Remove(v.vaultPath(vaultFile))
+		return nil, fmt.Errorf("failed to store quarantine record: %v", err)
+	}
+	entry.ID, _ = res.LastInsertId()
+
+	// 原文件删除失败时撤销隔离，避免同一文件既在原位置又在隔离区
+	if err := os.Remove(abs); err != nil {
+		v.db.Exec("DELETE FROM quarantine WHERE id = ?", entry.ID)
+		os.Remove(v.vaultPath(vaultFile))
+		return nil, fmt.Errorf("failed to remove %s: %v", abs, err)
+	}
+	return entry, nil
+}
+
+// seal 加密内容并写入隔离区，返回隔离文件名
+func (v *Vault) seal(content []byte) (string, error) {
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	sealed := v.aead.Seal(nonce, nonce, content, nil)
+
+	tmp, err := os.CreateTemp(filepath.Join(v.dir, "files"), "*.vault")
+	if err != nil {
+		return "", fmt.Errorf("failed to create quarantine file: %v", err)
+	}
+	if _, err := tmp.Write(sealed); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write quarantine file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write quarantine file: %v", err)
+	}
+	return filepath.Base(tmp.Name()), nil
+}
+
+// open 读取并解密隔离文件，校验内容哈希
+func (v *Vault) open(entry *Entry) ([]byte, error) {
+	sealed, err := os.ReadFile(v.vaultPath(entry.vaultFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quarantine file: %v", err)
+	}
+	size := v.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, fmt.Errorf("quarantine file %s is truncated", entry.vaultFile)
+	}
+	content, err := v.aead.Open(nil, sealed[:size], sealed[size:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt quarantine file (wrong key?): %v", err)
+	}
+	sum := sha256.Sum256(content)
+	if hex.EncodeToString(sum[:]) != entry.SHA256 {
+		return nil, fmt.Errorf("quarantine file %s does not match recorded hash", entry.vaultFile)
+	}
+	return content, nil
+}
+
+// vaultPath 返回隔离文件的完整路径
+func (v *Vault) vaultPath(vaultFile string) string {
+	return filepath.Join(v.dir, "files", vaultFile)
+}
+
+// Restore 解密隔离文件并恢复属主、权限和时间戳。dest 为空时恢复到原路径；
+// 目标已存在且 overwrite 为 false 时返回错误
+func (v *Vault) Restore(id int64, dest string, overwrite bool) (*Entry, error) {
+	entry, err := v.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Status != StatusQuarantined {
+		return nil, fmt.Errorf("quarantine entry %d is %s", id, entry.Status)
+	}
+	if dest == "" {
+		dest = entry.OriginalPath
+	}
+	if dest, err = filepath.Abs(dest); err != nil {
+		return nil, err
+	}
+
+	content, err := v.open(entry)
+	if err != nil {
+		return nil, err
+	}
+	if !overwrite {
+		if _, err := os.Lstat(dest); err == nil {
+			return nil, fmt.Errorf("%s already exists (use overwrite to replace it)", dest)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	// 先写临时文件并设置好属性再改名，避免恢复过程中文件以错误的属主或权限出现
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".restore-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create restored file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write restored file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write restored file: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), entry.Mode); err != nil {
+		return nil, fmt.Errorf("failed to restore permissions: %v", err)
+	}
+	if entry.UID >= 0 {
+		if err := os.Lchown(tmp.Name(), entry.UID, entry.GID); err != nil {
+			fmt.Printf("Warning: Failed to restore owner %d:%d of %s: %v\n", entry.UID, entry.GID, dest, err)
+		}
+	}
+	if err := os.Chtimes(tmp.Name(), entry.AccessTime, entry.ModTime); err != nil {
+		return nil, fmt.Errorf("failed to restore timestamps: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return nil, fmt.Errorf("failed to restore %s: %v", dest, err)
+	}
+
+	entry.Status = StatusRestored
+	entry.RestoredTo = dest
+	entry.RestoredAt = time.Now()
+	_, err = v.db.Exec("UPDATE quarantine SET status = ?, restored_to = ?, restored_at = ? WHERE id = ?",
+		entry.Status, entry.RestoredTo, entry.RestoredAt.UnixNano(), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update quarantine record: %v", err)
+	}
+	if err := os.Remove(v.vaultPath(entry.vaultFile)); err != nil {
+		fmt.Printf("Warning: Failed to remove quarantine file %s: %v\n", entry.vaultFile, err)
+	}
+	return entry, nil
+}
+
+// Purge 永久删除隔离文件，保留隔离记录用于审计
+func (v *Vault) Purge(id int64) error {
+	entry, err := v.Get(id)
+	if err != nil {
+		return err
+	}
+	if entry.Status != StatusQuarantined {
+		return fmt.Errorf("quarantine entry %d is %s", id, entry.Status)
+	}
+	if err := os.Remove(v.vaultPath(entry.vaultFile)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove quarantine file: %v", err)
+	}
+	_, err = v.db.Exec("UPDATE quarantine SET status = ?, purged_at = ? WHERE id = ?",
+		StatusPurged, time.Now().UnixNano(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update quarantine record: %v", err)
+	}
+	return nil
+}
+
+// Content 解密并返回指定哈希的隔离中的文件内容，用于归档已被隔离或替换为桩文件的检测样本
+func (v *Vault) Content(hash string) ([]byte, error) {
+	entries, err := v.query(entrySelect+" WHERE sha256 = ? AND status = ? ORDER BY id DESC LIMIT 1", hash, StatusQuarantined)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no quarantined file with sha256 %s", hash)
+	}
+	return v.open(entries[0])
+}
+
+// Restored 判断该内容是否曾被分析人员从隔离区恢复，用于避免自动隔离反复隔离已确认的误报
+func (v *Vault) Restored(hash string) (bool, error) {
+	var count int
+	err := v.db.QueryRow("SELECT COUNT(*) FROM quarantine WHERE sha256 = ? AND status = ?", hash, StatusRestored).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to query quarantine records: %v", err)
+	}
+	return count > 0, nil
+}
+
+// Get 查询一条隔离记录
+func (v *Vault) Get(id int64) (*Entry, error) {
+	entries, err := v.query(entrySelect+" WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("quarantine entry %d not found", id)
+	}
+	return entries[0], nil
+}
+
+// List 按隔离时间倒序列出隔离记录，status 为空时列出全部
+func (v *Vault) List(status string) ([]*Entry, error) {
+	if status == "" {
+		return v.query(entrySelect + " ORDER BY id DESC")
+	}
+	return v.query(entrySelect+" WHERE status = ? ORDER BY id DESC", status)
+}
+
+// Close 关闭隔离区数据库
+func (v *Vault) Close() error {
+	return v.db.Close()
+}
+
+// entrySelect 隔离记录查询
+const entrySelect = `
+	SELECT id, original_path, vault_file, sha256, size, mode, uid, gid, mod_time, access_time,
+	       risk_level, total_score, is_webshell, reason, actor, quarantined_at, status,
+	       restored_to, restored_at, purged_at
+	FROM quarantine`
+
+// query 执行查询并解析隔离记录
+func (v *Vault) query(query string, args ...interface{}) ([]*Entry, error) {
+	rows, err := v.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quarantine records: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []*Entry
+	for rows.Next() {
+		var (
+			e             Entry
+			mode          uint32
+			modTime       int64
+			accessTime    int64
+			quarantinedAt int64
+			level         sql.NullString
+			score         sql.NullFloat64
+			webshell      sql.NullBool
+			reason        sql.NullString
+			actor         sql.NullString
+			restoredTo    sql.NullString
+			restoredAt    sql.NullInt64
+			purgedAt      sql.NullInt64
+		)
+		err := rows.Scan(&e.ID, &e.OriginalPath, &e.vaultFile, &e.SHA256, &e.Size, &mode, &e.UID, &e.GID,
+			&modTime, &accessTime, &level, &score, &webshell, &reason, &actor, &quarantinedAt, &e.Status,
+			&restoredTo, &restoredAt, &purgedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		e.Mode = fs.FileMode(mode)
+		e.ModTime = time.Unix(0, modTime)
+		e.AccessTime = time.Unix(0, accessTime)
+		e.QuarantinedAt = time.Unix(0, quarantinedAt)
+		e.RiskLevel = level.String
+		e.TotalScore = score.Float64
+		e.IsWebshell = webshell.Bool
+		e.Reason = reason.String
+		e.Actor = actor.String
+		e.RestoredTo = restoredTo.String
+		if restoredAt.Valid {
+			e.RestoredAt = time.Unix(0, restoredAt.Int64)
+		}
+		if purgedAt.Valid {
+			e.PurgedAt = time.Unix(0, purgedAt.Int64)
+		}
+		entries = append(entries, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read quarantine records: %v", err)
+	}
+	return entries, nil
+}
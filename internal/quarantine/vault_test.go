@@ -0,0 +1,197 @@
+package quarantine
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openTestVault 在临时目录中打开隔离区
+func openTestVault(t *testing.T) *Vault {
+	t.Helper()
+	vault, err := Open(filepath.Join(t.TempDir(), "quarantine"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { vault.Close() })
+	return vault
+}
+
+// writeSample 写入样本文件并设置权限和修改时间，返回内容哈希
+func writeSample(t *testing.T, path string, content []byte) string {
+	t.Helper()
+	if err := os.WriteFile(path, content, 0640); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestQuarantineAndRestore(t *testing.T) {
+	vault := openTestVault(t)
+	path := filepath.Join(t.TempDir(), "shell.php")
+	content := []byte("<?php @eval($_POST['cmd']); ?>\n")
+	hash := writeSample(t, path, content)
+
+	entry, err := vault.Quarantine(path, hash, Detail{RiskLevel: "HIGH", Reason: "test", Actor: "manual"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Fatalf("original file still present after quarantine: %v", err)
+	}
+
+	// 隔离文件是密文，不包含原内容
+	sealed, err := os.ReadFile(vault.vaultPath(entry.vaultFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("eval")) {
+		t.Error("quarantine file contains the plaintext content")
+	}
+	if got, err := vault.Content(hash); err != nil || !bytes.Equal(got, content) {
+		t.Errorf("Content = %q, %v; want original content", got, err)
+	}
+
+	restored, err := vault.Restore(entry.ID, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Status != StatusRestored || restored.RestoredTo != path {
+		t.Errorf("restored entry = %s to %s, want %s to %s", restored.Status, restored.RestoredTo, StatusRestored, path)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("restored content = %q, want %q", got, content)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 || !info.ModTime().Equal(entry.ModTime) {
+		t.Errorf("restored mode %v mtime %v, want %v %v", info.Mode().Perm(), info.ModTime(), os.FileMode(0640), entry.ModTime)
+	}
+	if _, err := os.Stat(vault.vaultPath(entry.vaultFile)); !os.IsNotExist(err) {
+		t.Errorf("quarantine file kept after restore: %v", err)
+	}
+	if _, err := vault.Restore(entry.ID, "", false); err == nil {
+		t.Error("restored the same entry twice")
+	}
+}
+
+func TestQuarantineRejectsChangedFile(t *testing.T) {
+	vault := openTestVault(t)
+	path := filepath.Join(t.TempDir(), "index.php")
+	writeSample(t, path, []byte("<?php echo 'hello';"))
+
+	wrong := strings.Repeat("0", sha256.Size*2)
+	if _, err := vault.Quarantine(path, wrong, Detail{}); err == nil {
+		t.Fatal("quarantined a file whose content does not match the scanned hash")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("rejected file was removed: %v", err)
+	}
+	entries, err := vault.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("rejected quarantine left %d records", len(entries))
+	}
+}
+
+func TestRestoreRejectsTamperedFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, v *Vault, e *Entry)
+	}{
+		{"flipped byte", func(t *testing.T, v *Vault, e *Entry) {
+			path := v.vaultPath(e.vaultFile)
+			sealed, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sealed[len(sealed)-1] ^= 0xff
+			if err := os.WriteFile(path, sealed, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"truncated", func(t *testing.T, v *Vault, e *Entry) {
+			if err := os.WriteFile(v.vaultPath(e.vaultFile), []byte{1, 2, 3}, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"recorded hash changed", func(t *testing.T, v *Vault, e *Entry) {
+			if _, err := v.db.Exec("UPDATE quarantine SET sha256 = ? WHERE id = ?", strings.Repeat("0", 64), e.ID); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		vault := openTestVault(t)
+		path := filepath.Join(t.TempDir(), "shell.php")
+		hash := writeSample(t, path, []byte("<?php system($_GET['c']);"))
+		entry, err := vault.Quarantine(path, hash, Detail{})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		tt.tamper(t, vault, entry)
+		if _, err := vault.Restore(entry.ID, "", false); err == nil {
+			t.Errorf("%s: restored a tampered quarantine file", tt.name)
+		}
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("%s: rejected restore created %s", tt.name, path)
+		}
+		if got, err := vault.Get(entry.ID); err != nil || got.Status != StatusQuarantined {
+			t.Errorf("%s: entry after rejected restore = %+v, %v; want still quarantined", tt.name, got, err)
+		}
+	}
+}
+
+func TestRestoreKeepsExistingFile(t *testing.T) {
+	vault := openTestVault(t)
+	path := filepath.Join(t.TempDir(), "upload.php")
+	content := []byte("<?php passthru($_REQUEST['x']);")
+	hash := writeSample(t, path, content)
+	entry, err := vault.Quarantine(path, hash, Detail{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 隔离后原位置被写入了新文件
+	replacement := []byte("<?php echo 'new';")
+	if err := os.WriteFile(path, replacement, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vault.Restore(entry.ID, "", false); err == nil {
+		t.Fatal("restore replaced an existing file without overwrite")
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, replacement) {
+		t.Fatalf("existing file changed to %q", got)
+	}
+
+	// 恢复到其他位置不影响原位置的文件
+	dest := filepath.Join(t.TempDir(), "restored", "upload.php")
+	if _, err := vault.Restore(entry.ID, dest, false); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, content) {
+		t.Errorf("restored content = %q, want %q", got, content)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, replacement) {
+		t.Errorf("restore to another path changed the original location to %q", got)
+	}
+}
@@ -0,0 +1,376 @@
+package response
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"webshell-detector/internal/config"
+	"webshell-detector/internal/detector"
+	"webshell-detector/internal/quarantine"
+	"webshell-detector/internal/result"
+)
+
+// 响应动作
+const (
+	ActionLog        = "log"        // 只记录日志
+	ActionAlert      = "alert"      // 发送告警
+	ActionChmod      = "chmod"      // 去掉文件的全部权限（chmod 000）
+	ActionRename     = "rename"     // 改名为 <原文件名>.quarantined，使 Web 服务器不再解析
+	ActionQuarantine = "quarantine" // 加密移入隔离区
+	ActionStub       = "stub"       // 原文件移入隔离区，原位置替换为返回 403 的桩文件
+)
+
+// quarantinedSuffix rename 动作追加的后缀
+const quarantinedSuffix = ".quarantined"
+
+// Engine 响应引擎：按策略对检测结论执行响应动作，并记录每个动作
+type Engine struct {
+	rules     []config.ResponseRule
+	allowlist []string
+	dryRun    bool
+	limit     int
+	storage   *result.Storage
+	vault     *quarantine.Vault
+	alert     func(*detector.DetectionResult) bool
+
+	mu     sync.Mutex
+	recent []time.Time // 最近一小时内执行的修改文件的动作
+}
+
+// NewEngine 按配置创建响应引擎，没有任何规则时返回 nil。
+// storage 为 nil 时动作只记录日志；alert 将结果加入告警队列，没有可用的告警方式时返回 false
+func NewEngine(cfg *config.Config, storage *result.Storage, alert func(*detector.DetectionResult) bool) (*Engine, error) {
+	var rules []config.ResponseRule
+	if cfg.Response.Enabled {
+		rules = append(rules, cfg.Response.Rules...)
+	}
+	if cfg.Quarantine.Auto {
+		// 匹配规则的结果按规则中的动作决定是否告警，自动隔离不应让 webshell 告警消失
+		actions := []string{ActionQuarantine}
+		if cfg.Alert.Email.Enabled || cfg.Alert.SMS.Enabled {
+			actions = append(actions, ActionAlert)
+		}
+		rules = append(rules, config.ResponseRule{
+			Name:     "quarantine.auto",
+			Webshell: true,
+			Actions:  actions,
+		})
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	e := &Engine{
+		rules:     rules,
+		allowlist: cfg.Response.Allowlist,
+		dryRun:    cfg.Response.Enabled && cfg.Response.DryRun,
+		limit:     cfg.Response.MaxActionsPerHour,
+		storage:   storage,
+		alert:     alert,
+	}
+
+	for _, rule := range rules {
+		if contains(rule.Actions, ActionQuarantine) || contains(rule.Actions, ActionStub) {
+			vault, err := quarantine.Open(cfg.Quarantine.Dir, cfg.Quarantine.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open quarantine vault: %v", err)
+			}
+			e.vault = vault
+			break
+		}
+	}
+
+	if e.dryRun {
+		log.Printf("Response engine running in dry-run mode, files will not be modified")
+	}
+	return e, nil
+}
+
+// Respond 对一个检测结果执行第一条匹配规则中的动作。
+// 返回 true 表示规则已生效，是否告警由规则中的 alert 动作决定；没有匹配的规则或处于演练模式时返回 false
+func (e *Engine) Respond(scanType string, detectionResult *detector.DetectionResult) bool {
+	rule := e.match(detectionResult)
+	if rule == nil {
+		return false
+	}
+
+	// 修改文件的动作在执行前统一检查安全限制
+	path := detectionResult.FilePath
+	skipReason := ""
+	if matchPath(path, e.allowlist) {
+		skipReason = "path is allowlisted"
+	} else if e.vault != nil && detectionResult.ContentHash != "" {
+		if restored, err := e.vault.Restored(detectionResult.ContentHash); err != nil {
+			log.Printf("Warning: %v", err)
+		} else if restored {
+			skipReason = "identical content was previously restored by an analyst"
+		}
+	}
+
+	for _, action := range rule.Actions {
+		status, detail := result.ActionDone, ""
+		switch {
+		case !modifiesFile(action):
+			status, detail = e.notify(action, rule, detectionResult)
+		case skipReason != "":
+			status, detail = result.ActionSkipped, skipReason
+		case e.dryRun:
+			status, detail = result.ActionDryRun, describe(action, path)
+		case !e.reserve():
+			status, detail = result.ActionSkipped, fmt.Sprintf("max_actions_per_hour (%d) reached", e.limit)
+		default:
+			var err error
+			path, detail, err = e.apply(action, path, rule, scanType, detectionResult)
+			if err != nil {
+				status, detail = result.ActionFailed, err.Error()
+			}
+		}
+
+		log.Printf("Response %s [%s] %s: %s %s", action, ruleName(rule), detectionResult.FilePath, status, detail)
+		e.record(&result.ActionRecord{
+			FilePath:   detectionResult.FilePath,
+			Rule:       ruleName(rule),
+			Action:     action,
+			Status:     status,
+			Detail:     detail,
+			RiskLevel:  string(detectionResult.RiskLevel),
+			TotalScore: detectionResult.TotalScore,
+			IsWebshell: detectionResult.IsWebshell,
+			ScanType:   scanType,
+		})
+	}
+	return !e.dryRun
+}
+
+// match 返回第一条匹配的规则。未判定为 webshell 的 SAFE 结果只匹配 risk_levels 中明确列出 SAFE 的规则
+func (e *Engine) match(detectionResult *detector.DetectionResult) *config.ResponseRule {
+	safe := detectionResult.RiskLevel == detector.RiskLevelSafe && !detectionResult.IsWebshell
+	for i := range e.rules {
+		rule := &e.rules[i]
+		if safe && !contains(rule.RiskLevels, string(detector.RiskLevelSafe)) {
+			continue
+		}
+		if rule.Webshell && !detectionResult.IsWebshell {
+			continue
+		}
+		if len(rule.RiskLevels) > 0 && !contains(rule.RiskLevels, string(detectionResult.RiskLevel)) {
+			continue
+		}
+		if len(rule.Paths) > 0 && !matchPath(detectionResult.FilePath, rule.Paths) {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// notify 执行不修改文件的动作
+func (e *Engine) notify(action string, rule *config.ResponseRule, detectionResult *detector.DetectionResult) (string, string) {
+	switch action {
+	case ActionLog:
+		return result.ActionDone, fmt.Sprintf("risk %s, score %.2f", detectionResult.RiskLevel, detectionResult.TotalScore)
+	case ActionAlert:
+		if e.dryRun {
+			return result.ActionDryRun, "would send alert"
+		}
+		if e.alert == nil || !e.alert(detectionResult) {
+			return result.ActionFailed, "no alert channel enabled or alert queue full"
+		}
+		return result.ActionDone, "alert queued"
+	}
+	return result.ActionFailed, fmt.Sprintf("unknown action %q", action)
+}
+
+// apply 执行修改文件的动作，返回执行后文件所在的路径和说明
+func (e *Engine) apply(action, path string, rule *config.ResponseRule, scanType string,
+	detectionResult *detector.DetectionResult) (string, string, error) {
+	// 检测结果可能已过时，文件内容变化后不再处置
+	if action != ActionQuarantine && action != ActionStub {
+		if err := verifyContent(path, detectionResult.ContentHash); err != nil {
+			return path, "", err
+		}
+	}
+
+	switch action {
+	case ActionChmod:
+		info, err := os.Stat(path)
+		if err != nil {
+			return path, "", err
+		}
+		// 权限已是 000 时不再修改，避免实时监控收到权限变化事件后重复处理
+		if info.Mode().Perm() == 0 {
+			return path, "mode already 0000", nil
+		}
+		if err := os.Chmod(path, 0); err != nil {
+			return path, "", err
+		}
+		return path, fmt.Sprintf("mode %04o -> 0000", info.Mode().Perm()), nil
+
+	case ActionRename:
+		target := path + quarantinedSuffix
+		if _, err := os.Lstat(target); err == nil {
+			target = fmt.Sprintf("%s.%d", target, time.Now().Unix())
+		}
+		if err := os.Rename(path, target); err != nil {
+			return path, "", err
+		}
+		return target, "renamed to " + target, nil
+
+	case ActionQuarantine, ActionStub:
+		if e.vault == nil {
+			return path, "", fmt.Errorf("quarantine vault is not available")
+		}
+		var stub []byte
+		if action == ActionStub {
+			if stub = stubContent(path); stub == nil {
+				return path, "", fmt.Errorf("no 403 stub for %s files", filepath.Ext(path))
+			}
+		}
+		entry, err := e.vault.Quarantine(path, detectionResult.ContentHash, quarantine.Detail{
+			RiskLevel:  string(detectionResult.RiskLevel),
+			TotalScore: detectionResult.TotalScore,
+			IsWebshell: detectionResult.IsWebshell,
+			Reason:     "response rule " + ruleName(rule),
+			Actor:      scanType,
+		})
+		if err != nil {
+			return path, "", err
+		}
+		if stub == nil {
+			return path, fmt.Sprintf("quarantine ID %d", entry.ID), nil
+		}
+		if err := writeStub(path, stub, entry); err != nil {
+			return path, "", fmt.Errorf("quarantined as ID %d but failed to write stub: %v", entry.ID, err)
+		}
+		return path, fmt.Sprintf("replaced with 403 stub, original is quarantine ID %d", entry.ID), nil
+	}
+	return path, "", fmt.Errorf("unknown action %q", action)
+}
+
+// verifyContent 检查文件内容是否仍与检测时相同，hash 为空时不检查
+func verifyContent(path, hash string) error {
+	if hash == "" {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(content)
+	if current := hex.EncodeToString(sum[:]); current != hash {
+		return fmt.Errorf("%s changed since it was scanned (sha256 %s, expected %s)", path, current, hash)
+	}
+	return nil
+}
+
+// reserve 按每小时动作上限占用一个名额，超出上限时返回 false
+func (e *Engine) reserve() bool {
+	if e.limit <= 0 {
+		return true
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cutoff := time.Now().Add(-time.Hour)
+	kept := e.recent[:0]
+	for _, t := range e.recent {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	e.recent = kept
+	if len(e.recent) >= e.limit {
+		return false
+	}
+	e.recent = append(e.recent, time.Now())
+	return true
+}
+
+// record 写入动作记录
+func (e *Engine) record(record *result.ActionRecord) {
+	if e.storage == nil {
+		return
+	}
+	if err := e.storage.RecordAction(record); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// Close 关闭隔离区
+func (e *Engine) Close() error {
+	if e.vault != nil {
+		return e.vault.Close()
+	}
+	return nil
+}
+
+// modifiesFile 判断动作是否会修改文件，这类动作受白名单、演练模式和频率限制约束
+func modifiesFile(action string) bool {
+	return action != ActionLog && action != ActionAlert
+}
+
+// describe 演练模式下说明将要执行的动作
+func describe(action, path string) string {
+	switch action {
+	case ActionChmod:
+		return "would chmod 000"
+	case ActionRename:
+		return "would rename to " + path + quarantinedSuffix
+	case ActionQuarantine:
+		return "would move to quarantine"
+	case ActionStub:
+		return "would quarantine and replace with 403 stub"
+	}
+	return "would " + action
+}
+
+// ruleName 返回规则名称，未命名时为 unnamed
+func ruleName(rule *config.ResponseRule) string {
+	if rule.Name == "" {
+		return "unnamed"
+	}
+	return rule.Name
+}
+
+// contains 判断列表中是否包含 value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath 判断路径是否位于某个目录下或匹配某个 glob 模式，相对路径按当前目录转换为绝对路径后比较
+func matchPath(path string, patterns []string) bool {
+	candidates := []string{filepath.Clean(path)}
+	if abs, err := filepath.Abs(path); err == nil && abs != candidates[0] {
+		candidates = append(candidates, abs)
+	}
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		pattern = filepath.Clean(pattern)
+		for _, candidate := range candidates {
+			if candidate == pattern || strings.HasPrefix(candidate, pattern+string(filepath.Separator)) {
+				return true
+			}
+			if matched, _ := filepath.Match(pattern, candidate); matched {
+				return true
+			}
+			if matched, _ := filepath.Match(pattern, filepath.Base(candidate)); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package response
+
+import (
+	"testing"
+
+	"webshell-detector/internal/config"
+	"webshell-detector/internal/detector"
+)
+
+func TestMatchSkipsSafeResults(t *testing.T) {
+	e := &Engine{rules: []config.ResponseRule{
+		{Name: "uploads", Paths: []string{"/var/www/uploads"}, Actions: []string{ActionQuarantine}},
+		{Name: "audit-safe", RiskLevels: []string{"SAFE"}, Actions: []string{ActionLog}},
+		{Name: "any", Actions: []string{ActionLog}},
+	}}
+
+	tests := []struct {
+		name   string
+		result detector.DetectionResult
+		want   string
+	}{
+		{"safe file in filtered path", detector.DetectionResult{FilePath: "/var/www/uploads/a.php", RiskLevel: detector.RiskLevelSafe}, "audit-safe"},
+		{"risky file in filtered path", detector.DetectionResult{FilePath: "/var/www/uploads/a.php", RiskLevel: detector.RiskLevelLow}, "uploads"},
+		{"risky file elsewhere", detector.DetectionResult{FilePath: "/var/www/html/a.php", RiskLevel: detector.RiskLevelMedium}, "any"},
+		{"webshell", detector.DetectionResult{FilePath: "/var/www/uploads/a.php", RiskLevel: detector.RiskLevelSafe, IsWebshell: true}, "uploads"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if rule := e.match(&tt.result); rule != nil {
+			got = rule.Name
+		}
+		if got != tt.want {
+			t.Errorf("%s: matched rule %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	unfiltered := &Engine{rules: []config.ResponseRule{{Name: "any", Actions: []string{ActionLog}}}}
+	if rule := unfiltered.match(&detector.DetectionResult{RiskLevel: detector.RiskLevelSafe}); rule != nil {
+		t.Errorf("rule without filters matched a SAFE result")
+	}
+}
@@ -0,0 +1,48 @@
+package response
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"webshell-detector/internal/quarantine"
+)
+
+// stubs 各脚本类型返回 403 的桩文件内容
+var stubs = map[string]string{
+	".php":   "<?php http_response_code(403); exit; ?>\n",
+	".phtml": "<?php http_response_code(403); exit; ?>\n",
+	".php5":  "<?php http_response_code(403); exit; ?>\n",
+	".jsp":   "<% response.sendError(403); %>\n",
+	".asp":   "<% Response.Status = \"403 Forbidden\" : Response.End %>\n",
+	".aspx":  "<%@ Page Language=\"C#\" %>\n<% Response.StatusCode = 403; Response.End(); %>\n",
+}
+
+// stubContent 返回文件类型对应的桩文件内容，不支持的类型返回 nil
+func stubContent(path string) []byte {
+	stub, ok := stubs[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil
+	}
+	return []byte(stub)
+}
+
+// writeStub 在原文件位置写入桩文件，沿用原文件的权限和属主
+func writeStub(path string, stub []byte, entry *quarantine.Entry) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, entry.Mode)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(stub); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if entry.UID >= 0 {
+		// 非 root 运行时无法修改属主，桩文件仍可用
+		os.Lchown(path, entry.UID, entry.GID)
+	}
+	return nil
+}
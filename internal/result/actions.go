@@ -0,0 +1,133 @@
+package result
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// 响应动作执行状态
+const (
+	ActionDone    = "done"    // 已执行
+	ActionFailed  = "failed"  // 执行失败
+	ActionDryRun  = "dry_run" // 演练模式，未执行
+	ActionSkipped = "skipped" // 因白名单、频率限制等安全限制未执行
+)
+
+// ActionRecord 一次自动响应动作的记录
+type ActionRecord struct {
+	ID         int64
+	FilePath   string
+	Rule       string
+	Action     string
+	Status     string
+	Detail     string // 执行结果说明，如改名后的路径、隔离 ID 或失败原因
+	RiskLevel  string
+	TotalScore float64
+	IsWebshell bool
+	ScanType   string
+	ActionTime time.Time
+}
+
+// initActionTable 创建响应动作记录表
+func initActionTable(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS response_actions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		file_path TEXT NOT NULL,
+		rule TEXT,
+		action TEXT NOT NULL,
+		status TEXT NOT NULL,
+		detail TEXT,
+		risk_level TEXT,
+		total_score REAL,
+		is_webshell BOOLEAN,
+		scan_type TEXT,
+		action_time DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_action_file ON response_actions(file_path);
+	CREATE INDEX IF NOT EXISTS idx_action_time ON response_actions(action_time);
+	`)
+	return err
+}
+
+// RecordAction 记录一次响应动作
+func (s *Storage) RecordAction(record *ActionRecord) error {
+	if record.ActionTime.IsZero() {
+		record.ActionTime = time.Now()
+	}
+	res, err := s.db.Exec(`
+		INSERT INTO response_actions (
+			file_path, rule, action, status, detail,
+			risk_level, total_score, is_webshell, scan_type, action_time
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		record.FilePath,
+		record.Rule,
+		record.Action,
+		record.Status,
+		record.Detail,
+		record.RiskLevel,
+		record.TotalScore,
+		record.IsWebshell,
+		record.ScanType,
+		record.ActionTime,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store response action: %v", err)
+	}
+	record.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// ListActions 按时间倒序列出响应动作记录，filePath 非空时只列出该文件的记录
+func (s *Storage) ListActions(limit int, filePath string) ([]*ActionRecord, error) {
+	query := `
+		SELECT id, file_path, rule, action, status, detail, risk_level, total_score, is_webshell, scan_type, action_time
+		FROM response_actions`
+	var args []interface{}
+	if filePath != "" {
+		query += " WHERE file_path = ?"
+		args = append(args, filePath)
+	}
+	query += " ORDER BY id DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query response actions: %v", err)
+	}
+	defer rows.Close()
+
+	var records []*ActionRecord
+	for rows.Next() {
+		var (
+			r        ActionRecord
+			rule     sql.NullString
+			detail   sql.NullString
+			level    sql.NullString
+			score    sql.NullFloat64
+			webshell sql.NullBool
+			scanType sql.NullString
+		)
+		err := rows.Scan(&r.ID, &r.FilePath, &rule, &r.Action, &r.Status, &detail,
+			&level, &score, &webshell, &scanType, &r.ActionTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		r.Rule = rule.String
+		r.Detail = detail.String
+		r.RiskLevel = level.String
+		r.TotalScore = score.Float64
+		r.IsWebshell = webshell.Bool
+		r.ScanType = scanType.String
+		records = append(records, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read response actions: %v", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,306 @@
+package result
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultArchiveDir 标注文件内容的默认归档目录
+const DefaultArchiveDir = "data/archive"
+
+// 分析人员结论
+const (
+	VerdictConfirmed     = "confirmed"      // 确认为 webshell
+	VerdictFalsePositive = "false_positive" // 误报，实为正常文件
+)
+
+// Label 分析人员对一条检测结果的标注
+type Label struct {
+	ResultID    int64
+	Verdict     string
+	Family      string // webshell 家族，如 chopper、behinder
+	Notes       string
+	Analyst     string
+	ContentHash string // 归档内容的 SHA-256，取不到检测时的内容时为空
+	ArchivePath string
+	LabelTime   time.Time
+}
+
+// StoredResult 带数据库 ID 和标注的检测结果摘要
+type StoredResult struct {
+	ID         int64
+	FilePath   string
+	ParentPath string
+	IsWebshell bool
+	RiskLevel  string
+	TotalScore float64
+	ScanTime   time.Time
+	Label      *Label // 未标注时为 nil
+}
+
+// initLabelTable 创建标注表，每条检测结果最多一条标注，重复标注覆盖旧值
+func initLabelTable(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS result_labels (
+		result_id INTEGER PRIMARY KEY REFERENCES scan_results(id),
+		verdict TEXT NOT NULL,
+		family TEXT,
+		notes TEXT,
+		analyst TEXT,
+		content_hash TEXT,
+		archive_path TEXT,
+		label_time DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_label_verdict ON result_labels(verdict);
+	`)
+	return err
+}
+
+// ValidVerdict 判断结论取值是否合法
+func ValidVerdict(verdict string) bool {
+	return verdict == VerdictConfirmed || verdict == VerdictFalsePositive
+}
+
+// ContentSource 按内容哈希取回已不在原位置的文件内容，如隔离区中的文件，找不到时返回错误
+type ContentSource func(hash string) ([]byte, error)
+
+// LabelResult 为检测结果添加标注，并将检测时的文件内容归档到 archiveDir。
+// 文件当前内容与检测时不同（已被修改、隔离或替换为桩文件）时从 source 取回检测时的内容，
+// 仍取不到时仅记录标注，导出数据集时会跳过该条
+func (s *Storage) LabelResult(label *Label, archiveDir string, source ContentSource) error {
+	if !ValidVerdict(label.Verdict) {
+		return fmt.Errorf("invalid verdict %q (expected %s or %s)", label.Verdict, VerdictConfirmed, VerdictFalsePositive)
+	}
+
+	var filePath string
+	var scannedHash sql.NullString
+	err := s.db.QueryRow("SELECT file_path, content_hash FROM scan_results WHERE id = ?",
+		label.ResultID).Scan(&filePath, &scannedHash)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("result %d not found", label.ResultID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query result: %v", err)
+	}
+
+	hash, archivePath, err := archiveScanned(archiveDir, filePath, scannedHash.String, source)
+	if err != nil {
+		fmt.Printf("Warning: Failed to archive %s: %v\n", filePath, err)
+		hash, archivePath = "", ""
+		// 重新标注时文件可能已被隔离，沿用之前归档的检测时内容
+		var prevHash, prevArchive sql.NullString
+		if s.db.QueryRow("SELECT content_hash, archive_path FROM result_labels WHERE result_id = ?",
+			label.ResultID).Scan(&prevHash, &prevArchive) == nil &&
+			(scannedHash.String == "" || prevHash.String == scannedHash.String) {
+			hash, archivePath = prevHash.String, prevArchive.String
+		}
+	}
+	label.ContentHash = hash
+	label.ArchivePath = archivePath
+	label.LabelTime = time.Now()
+
+	_, err = s.db.Exec(`
+		INSERT OR REPLACE INTO result_labels (
+			result_id, verdict, family, notes, analyst,
+			content_hash, archive_path, label_time
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		label.ResultID,
+		label.Verdict,
+		label.Family,
+		label.Notes,
+		label.Analyst,
+		label.ContentHash,
+		label.ArchivePath,
+		label.LabelTime,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store label: %v", err)
+	}
+	return nil
+}
+
+// LatestResultID 返回文件最近一次检测结果的 ID
+func (s *Storage) LatestResultID(filePath string) (int64, error) {
+	var id int64
+	err := s.db.QueryRow("SELECT id FROM scan_results WHERE file_path = ? ORDER BY id DESC LIMIT 1", filePath).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("no stored result for %s", filePath)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query result: %v", err)
+	}
+	return id, nil
+}
+
+// ListResults 按时间倒序列出检测结果及其标注；
+// suspiciousOnly 仅列出判定为 webshell 或非 SAFE 的结果，unlabeledOnly 仅列出未标注的结果
+func (s *Storage) ListResults(limit int, suspiciousOnly, unlabeledOnly bool) ([]*StoredResult, error) {
+	query := storedResultSelect + " WHERE 1=1"
+	if suspiciousOnly {
+		query += " AND (r.is_webshell = 1 OR r.risk_level != 'SAFE')"
+	}
+	if unlabeledOnly {
+		query += " AND l.result_id IS NULL"
+	}
+	query += " ORDER BY r.id DESC"
+
+	var args []interface{}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	return s.queryStoredResults(query, args...)
+}
+
+// LabeledResults 返回全部已标注的检测结果，按结果 ID 排序
+func (s *Storage) LabeledResults() ([]*StoredResult, error) {
+	return s.queryStoredResults(storedResultSelect + " WHERE l.result_id IS NOT NULL ORDER BY r.id")
+}
+
+// storedResultSelect 检测结果与标注的联合查询
+const storedResultSelect = `
+	SELECT r.id, r.file_path, r.parent_path, r.is_webshell, r.risk_level, r.total_score, r.scan_time,
+	       l.result_id, l.verdict, l.family, l.notes, l.analyst, l.content_hash, l.archive_path, l.label_time
+	FROM scan_results r
+	LEFT JOIN result_labels l ON l.result_id = r.id`
+
+// queryStoredResults 执行联合查询并解析结果
+func (s *Storage) queryStoredResults(query string, args ...interface{}) ([]*StoredResult, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query results: %v", err)
+	}
+	defer rows.Close()
+
+	var results []*StoredResult
+	for rows.Next() {
+		var (
+			r          StoredResult
+			parentPath sql.NullString
+			labelID    sql.NullInt64
+			verdict    sql.NullString
+			family     sql.NullString
+			notes      sql.NullString
+			analyst    sql.NullString
+			hash       sql.NullString
+			archive    sql.NullString
+			labelTime  sql.NullTime
+		)
+		err := rows.Scan(
+			&r.ID, &r.FilePath, &parentPath, &r.IsWebshell, &r.RiskLevel, &r.TotalScore, &r.ScanTime,
+			&labelID, &verdict, &family, &notes, &analyst, &hash, &archive, &labelTime,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		r.ParentPath = parentPath.String
+
+		if labelID.Valid {
+			r.Label = &Label{
+				ResultID:    labelID.Int64,
+				Verdict:     verdict.String,
+				Family:      family.String,
+				Notes:       notes.String,
+				Analyst:     analyst.String,
+				ContentHash: hash.String,
+				ArchivePath: archive.String,
+				LabelTime:   labelTime.Time,
+			}
+		}
+		results = append(results, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read results: %v", err)
+	}
+
+	return results, nil
+}
+
+// archiveScanned 归档检测时的文件内容。scannedHash 为空（旧版本的检测结果）时归档文件当前内容
+func archiveScanned(archiveDir, filePath, scannedHash string, source ContentSource) (string, string, error) {
+	content, err := os.ReadFile(filePath)
+	if err == nil {
+		sum := sha256.Sum256(content)
+		current := hex.EncodeToString(sum[:])
+		if scannedHash == "" || current == scannedHash {
+			return archiveContent(archiveDir, filePath, content)
+		}
+		err = fmt.Errorf("content changed since the scan (sha256 %s, scanned %s)", current, scannedHash)
+	}
+	if scannedHash == "" || source == nil {
+		return "", "", err
+	}
+
+	scanned, sourceErr := source(scannedHash)
+	if sourceErr != nil {
+		return "", "", fmt.Errorf("%v; scanned content not found: %v", err, sourceErr)
+	}
+	sum := sha256.Sum256(scanned)
+	if hex.EncodeToString(sum[:]) != scannedHash {
+		return "", "", fmt.Errorf("%v; retrieved content does not match sha256 %s", err, scannedHash)
+	}
+	return archiveContent(archiveDir, filePath, scanned)
+}
+
+// archiveContent 按内容哈希将内容保存到归档目录，相同内容只保存一份；
+// 归档文件保留 filePath 的扩展名，便于按文件类型收集语料
+func archiveContent(archiveDir, filePath string, content []byte) (string, string, error) {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	archivePath := filepath.Join(archiveDir, hash[:2], hash+filepath.Ext(filePath))
+
+	if _, err := os.Stat(archivePath); err == nil {
+		return hash, archivePath, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create archive directory: %v", err)
+	}
+
+	// 归档内容仅供离线分析和训练，去掉执行权限
+	tmp, err := os.CreateTemp(filepath.Dir(archivePath), ".archive-*")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create archive file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return "", "", fmt.Errorf("failed to write archive file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", "", fmt.Errorf("failed to write archive file: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0444); err != nil {
+		return "", "", fmt.Errorf("failed to set archive permissions: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), archivePath); err != nil {
+		return "", "", fmt.Errorf("failed to archive file: %v", err)
+	}
+	return hash, archivePath, nil
+}
+
+// CopyArchived 将归档文件复制到 dst
+func CopyArchived(archivePath, dst string) error {
+	src, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
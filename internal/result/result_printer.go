@@ -6,6 +6,7 @@ import (
 	"text/tabwriter"
 
 	"webshell-detector/internal/detector"
+	"webshell-detector/pkg/mlmodel"
 )
 
 // Printer 结果打印器
@@ -35,6 +36,12 @@ func (p *Printer) PrintResult(result *detector.DetectionResult) {
 	fmt.Fprintf(w, "Risk Level:\t%s\n", p.colorizeRiskLevel(string(result.RiskLevel)))
 	fmt.Fprintf(w, "Is Webshell:\t%s\n", p.colorizeBoolean(result.IsWebshell))
 	fmt.Fprintf(w, "Total Score:\t%.2f\n", result.TotalScore)
+	if result.Process != nil {
+		fmt.Fprintf(w, "Written By:\tpid=%d uid=%d exe=%s\n", result.Process.PID, result.Process.UID, result.Process.Exe)
+		if result.Process.Cmdline != "" {
+			fmt.Fprintf(w, "Command Line:\t%s\n", result.Process.Cmdline)
+		}
+	}
 	fmt.Println()
 
 	// 特征匹配结果
@@ -66,12 +73,45 @@ func (p *Printer) PrintResult(result *detector.DetectionResult) {
 	// 机器学习分析结果
 	fmt.Println("3. Machine Learning Analysis:")
 	fmt.Fprintf(w, "   机器学习分析得分Score:\t%.2f\n", result.MLScore)
+	fmt.Fprintf(w, "   ML Verdict:\t%s\n", p.colorizeBoolean(result.MLVerdict))
+	if result.ModelVersion != "" {
+		fmt.Fprintf(w, "   Model Version:\t%s\n", mlmodel.ShortVersion(result.ModelVersion))
+	}
+	if len(result.MLTopFeatures) > 0 {
+		fmt.Println("   Top Contributing Features:")
+		for _, c := range result.MLTopFeatures {
+			fmt.Fprintf(w, "   - %s = %.4g\t%+.4f\n", c.Name, c.Value, c.Contribution)
+		}
+	}
 	fmt.Println()
 
+	// 释放文件检测结果
+	if len(result.DroppedFiles) > 0 {
+		fmt.Println("4. Dropped Files:")
+		p.printDroppedFiles(w, result.DroppedFiles, "   ")
+		fmt.Println()
+	}
+
 	// 打印分割线
 	fmt.Println("============================================")
 }
 
+// printDroppedFiles 递归打印释放文件的检测结论
+func (p *Printer) printDroppedFiles(w *tabwriter.Writer, results []*detector.DetectionResult, indent string) {
+	for _, dropped := range results {
+		fmt.Fprintf(w, "%s- %s\t%s\tWebshell: %s\tScore: %.2f\n",
+			indent,
+			dropped.FilePath,
+			p.colorizeRiskLevel(string(dropped.RiskLevel)),
+			p.colorizeBoolean(dropped.IsWebshell),
+			dropped.TotalScore,
+		)
+		if len(dropped.DroppedFiles) > 0 {
+			p.printDroppedFiles(w, dropped.DroppedFiles, indent+"  ")
+		}
+	}
+}
+
 // colorizeRiskLevel 为风险等级添加颜色
 func (p *Printer) colorizeRiskLevel(level string) string {
 	if !p.colorize {
@@ -102,44 +142,71 @@ func (p *Printer) colorizeBoolean(value bool) string {
 	return "\033[32mNo\033[0m" // 绿色
 }
 
+// Summary 扫描结果统计
+type Summary struct {
+	TotalFiles int
+	Webshells  int
+	HighRisk   int
+	MediumRisk int
+	LowRisk    int
+	Safe       int
+}
+
+// Add 统计一个检测结果
+func (s *Summary) Add(result *detector.DetectionResult) {
+	s.TotalFiles++
+	if result.IsWebshell {
+		s.Webshells++
+	}
+	switch result.RiskLevel {
+	case detector.RiskLevelHigh:
+		s.HighRisk++
+	case detector.RiskLevelMedium:
+		s.MediumRisk++
+	case detector.RiskLevelLow:
+		s.LowRisk++
+	case detector.RiskLevelSafe:
+		s.Safe++
+	}
+}
+
+// Carry 统计未重新检测、沿用上次结论的文件，不计入检测文件数
+func (s *Summary) Carry(result *detector.DetectionResult) {
+	if result.IsWebshell {
+		s.Webshells++
+	}
+	switch result.RiskLevel {
+	case detector.RiskLevelHigh:
+		s.HighRisk++
+	case detector.RiskLevelMedium:
+		s.MediumRisk++
+	case detector.RiskLevelLow:
+		s.LowRisk++
+	}
+}
+
 // PrintSummary 打印扫描摘要
 func (p *Printer) PrintSummary(results []*detector.DetectionResult) {
-	var (
-		totalFiles = len(results)
-		webshells  = 0
-		highRisk   = 0
-		mediumRisk = 0
-		lowRisk    = 0
-		safe       = 0
-	)
-
 	// 统计结果
+	var summary Summary
 	for _, result := range results {
-		if result.IsWebshell {
-			webshells++
-		}
-		switch result.RiskLevel {
-		case detector.RiskLevelHigh:
-			highRisk++
-		case detector.RiskLevelMedium:
-			mediumRisk++
-		case detector.RiskLevelLow:
-			lowRisk++
-		case detector.RiskLevelSafe:
-			safe++
-		}
+		summary.Add(result)
 	}
+	p.PrintStats(&summary)
+}
 
+// PrintStats 打印已统计好的扫描摘要
+func (p *Printer) PrintStats(summary *Summary) {
 	// 创建格式化输出器
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 
 	fmt.Fprintln(w, "\n=== Scan Summary ===")
-	fmt.Fprintf(w, "Total Files Scanned:\t%d\n", totalFiles)
-	fmt.Fprintf(w, "Webshells Detected:\t%d\n", webshells)
-	fmt.Fprintf(w, "High Risk Files:\t%d\n", highRisk)
-	fmt.Fprintf(w, "Medium Risk Files:\t%d\n", mediumRisk)
-	fmt.Fprintf(w, "Low Risk Files:\t%d\n", lowRisk)
-	fmt.Fprintf(w, "Safe Files:\t%d\n", safe)
+	fmt.Fprintf(w, "Total Files Scanned:\t%d\n", summary.TotalFiles)
+	fmt.Fprintf(w, "Webshells Detected:\t%d\n", summary.Webshells)
+	fmt.Fprintf(w, "High Risk Files:\t%d\n", summary.HighRisk)
+	fmt.Fprintf(w, "Medium Risk Files:\t%d\n", summary.MediumRisk)
+	fmt.Fprintf(w, "Low Risk Files:\t%d\n", summary.LowRisk)
+	fmt.Fprintf(w, "Safe Files:\t%d\n", summary.Safe)
 
 	w.Flush()
 }
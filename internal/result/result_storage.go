@@ -11,6 +11,9 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// DefaultDBPath 默认结果数据库路径
+const DefaultDBPath = "data/results.db"
+
 // Storage 结果存储器
 type Storage struct {
 	db *sql.DB
@@ -47,19 +50,97 @@ func initResultDatabase(db *sql.DB) error {
 		behaviors TEXT,
 		scan_time DATETIME DEFAULT CURRENT_TIMESTAMP,
 		scan_duration INTEGER,
-		scan_type TEXT
+		scan_type TEXT,
+		parent_path TEXT,
+		ml_verdict BOOLEAN,
+		model_version TEXT,
+		ml_top_features TEXT,
+		process TEXT,
+		content_hash TEXT
 	);
 	CREATE INDEX IF NOT EXISTS idx_file_path ON scan_results(file_path);
 	CREATE INDEX IF NOT EXISTS idx_scan_time ON scan_results(scan_time);
 	CREATE INDEX IF NOT EXISTS idx_risk_level ON scan_results(risk_level);
 	`
 
-	_, err := db.Exec(createTable)
-	return err
+	if _, err := db.Exec(createTable); err != nil {
+		return err
+	}
+
+	if err := migrateResultDatabase(db); err != nil {
+		return err
+	}
+
+	if err := initLabelTable(db); err != nil {
+		return err
+	}
+
+	if err := initShadowTable(db); err != nil {
+		return err
+	}
+
+	return initActionTable(db)
 }
 
-// StoreResult 存储检测结果
+// resultColumns 建表后新增的列，旧数据库启动时自动补齐
+var resultColumns = []struct {
+	name       string
+	definition string
+}{
+	{"parent_path", "TEXT"},
+	{"ml_verdict", "BOOLEAN"},
+	{"model_version", "TEXT"},
+	{"ml_top_features", "TEXT"},
+	{"process", "TEXT"},
+	{"content_hash", "TEXT"},
+}
+
+// migrateResultDatabase 为旧版本数据库补齐新增列
+func migrateResultDatabase(db *sql.DB) error {
+	rows, err := db.Query("PRAGMA table_info(scan_results)")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return err
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, col := range resultColumns {
+		if existing[col.name] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE scan_results ADD COLUMN %s %s", col.name, col.definition)); err != nil {
+			return fmt.Errorf("failed to add column %s: %v", col.name, err)
+		}
+	}
+
+	return nil
+}
+
+// StoreResult 存储检测结果，释放文件的检测结果以父文件路径关联一并存储
 func (s *Storage) StoreResult(result *detector.DetectionResult, scanType string, duration time.Duration) error {
+	return s.storeResult(result, scanType, duration, "")
+}
+
+// storeResult 存储单条检测结果并递归存储其释放文件结果
+func (s *Storage) storeResult(result *detector.DetectionResult, scanType string, duration time.Duration, parentPath string) error {
 	// 将特征和行为列表转换为JSON
 	matchedFeatures, err := json.Marshal(result.MatchedFeatures)
 	if err != nil {
@@ -71,13 +152,28 @@ func (s *Storage) StoreResult(result *detector.DetectionResult, scanType string,
 		return fmt.Errorf("failed to marshal behaviors: %v", err)
 	}
 
+	mlTopFeatures, err := json.Marshal(result.MLTopFeatures)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ML top features: %v", err)
+	}
+
+	var process sql.NullString
+	if result.Process != nil {
+		payload, err := json.Marshal(result.Process)
+		if err != nil {
+			return fmt.Errorf("failed to marshal process info: %v", err)
+		}
+		process = sql.NullString{String: string(payload), Valid: true}
+	}
+
 	// 插入结果
-	_, err = s.db.Exec(`
+	res, err := s.db.Exec(`
 		INSERT INTO scan_results (
 			file_path, is_webshell, risk_level, total_score,
 			feature_score, behavior_score, ml_score,
-			matched_features, behaviors, scan_duration, scan_type
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			matched_features, behaviors, scan_duration, scan_type,
+			parent_path, ml_verdict, model_version, ml_top_features, process, content_hash
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		result.FilePath,
 		result.IsWebshell,
@@ -90,12 +186,34 @@ func (s *Storage) StoreResult(result *detector.DetectionResult, scanType string,
 		string(behaviors),
 		duration.Milliseconds(),
 		scanType,
+		sql.NullString{String: parentPath, Valid: parentPath != ""},
+		result.MLVerdict,
+		result.ModelVersion,
+		string(mlTopFeatures),
+		process,
+		sql.NullString{String: result.ContentHash, Valid: result.ContentHash != ""},
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to store result: %v", err)
 	}
 
+	if len(result.Shadow) > 0 {
+		resultID, err := res.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get result id: %v", err)
+		}
+		if err := s.storeShadowResults(resultID, result); err != nil {
+			return err
+		}
+	}
+
+	for _, dropped := range result.DroppedFiles {
+		if err := s.storeResult(dropped, scanType, 0, result.FilePath); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,207 @@
+package result
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"webshell-detector/internal/detector"
+)
+
+// 影子报告的统计周期
+var shadowPeriodFormats = map[string]string{
+	"day":   "%Y-%m-%d",
+	"week":  "%Y-W%W",
+	"month": "%Y-%m",
+}
+
+// ShadowSummary 单个影子引擎在一个统计周期内与当前引擎的分歧统计
+type ShadowSummary struct {
+	Name           string
+	Kind           string
+	Period         string
+	Total          int     // 对比的检测次数
+	Disagreements  int     // 判定不一致次数
+	ShadowOnly     int     // 仅影子引擎判定为 webshell
+	ActiveOnly     int     // 仅当前引擎判定为 webshell
+	RiskChanges    int     // 风险等级不同的次数
+	Errors         int     // 影子引擎执行失败次数
+	MeanScoreDelta float64 // 影子引擎得分与当前引擎得分之差的平均值
+}
+
+// DisagreementRate 判定不一致的比例
+func (s *ShadowSummary) DisagreementRate() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Disagreements) / float64(s.Total)
+}
+
+// ShadowDisagreement 一次判定不一致的记录
+type ShadowDisagreement struct {
+	ResultID         int64
+	FilePath         string
+	Name             string
+	ScanTime         time.Time
+	ActiveIsWebshell bool
+	ActiveRiskLevel  string
+	ActiveTotalScore float64
+	ShadowIsWebshell bool
+	ShadowRiskLevel  string
+	ShadowTotalScore float64
+}
+
+// initShadowTable 创建影子引擎结果表
+func initShadowTable(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS shadow_results (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		result_id INTEGER NOT NULL REFERENCES scan_results(id),
+		name TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		version TEXT,
+		engine_score REAL,
+		active_score REAL,
+		total_score REAL,
+		risk_level TEXT,
+		is_webshell BOOLEAN,
+		disagrees BOOLEAN NOT NULL,
+		error TEXT
+	);
+	CREATE INDEX IF NOT EXISTS idx_shadow_result ON shadow_results(result_id);
+	CREATE INDEX IF NOT EXISTS idx_shadow_name ON shadow_results(name);
+	`)
+	return err
+}
+
+// storeShadowResults 存储一条检测结果的全部影子结论
+func (s *Storage) storeShadowResults(resultID int64, result *detector.DetectionResult) error {
+	for _, shadow := range result.Shadow {
+		_, err := s.db.Exec(`
+			INSERT INTO shadow_results (
+				result_id, name, kind, version, engine_score, active_score,
+				total_score, risk_level, is_webshell, disagrees, error
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`,
+			resultID,
+			shadow.Name,
+			shadow.Kind,
+			shadow.Version,
+			shadow.EngineScore,
+			shadow.ActiveScore,
+			shadow.TotalScore,
+			shadow.RiskLevel,
+			shadow.IsWebshell,
+			shadow.Disagrees,
+			sql.NullString{String: shadow.Error, Valid: shadow.Error != ""},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to store shadow result %s: %v", shadow.Name, err)
+		}
+	}
+	return nil
+}
+
+// ShadowSummaries 按影子引擎和统计周期（day/week/month）汇总 since 之后的分歧
+func (s *Storage) ShadowSummaries(since time.Time, period string) ([]*ShadowSummary, error) {
+	format, ok := shadowPeriodFormats[period]
+	if !ok {
+		return nil, fmt.Errorf("unsupported period %q (expected day, week or month)", period)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT sh.name, sh.kind, strftime(?, r.scan_time) AS period,
+		       COUNT(*),
+		       SUM(CASE WHEN sh.disagrees THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN sh.error IS NULL AND sh.is_webshell AND NOT r.is_webshell THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN sh.error IS NULL AND NOT sh.is_webshell AND r.is_webshell THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN sh.error IS NULL AND sh.risk_level != r.risk_level THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN sh.error IS NOT NULL THEN 1 ELSE 0 END),
+		       COALESCE(AVG(CASE WHEN sh.error IS NULL THEN sh.engine_score - sh.active_score END), 0)
+		FROM shadow_results sh
+		JOIN scan_results r ON r.id = sh.result_id
+		WHERE r.scan_time >= ?
+		GROUP BY sh.name, sh.kind, period
+		ORDER BY sh.name, period
+	`, format, since.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shadow results: %v", err)
+	}
+	defer rows.Close()
+
+	var summaries []*ShadowSummary
+	for rows.Next() {
+		var summary ShadowSummary
+		err := rows.Scan(
+			&summary.Name,
+			&summary.Kind,
+			&summary.Period,
+			&summary.Total,
+			&summary.Disagreements,
+			&summary.ShadowOnly,
+			&summary.ActiveOnly,
+			&summary.RiskChanges,
+			&summary.Errors,
+			&summary.MeanScoreDelta,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		summaries = append(summaries, &summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read shadow results: %v", err)
+	}
+
+	return summaries, nil
+}
+
+// ShadowDisagreements 按时间倒序列出 since 之后判定不一致的检测结果
+func (s *Storage) ShadowDisagreements(since time.Time, limit int) ([]*ShadowDisagreement, error) {
+	query := `
+		SELECT r.id, r.file_path, sh.name, r.scan_time,
+		       r.is_webshell, r.risk_level, r.total_score,
+		       sh.is_webshell, sh.risk_level, sh.total_score
+		FROM shadow_results sh
+		JOIN scan_results r ON r.id = sh.result_id
+		WHERE sh.disagrees AND r.scan_time >= ?
+		ORDER BY r.id DESC, sh.name
+	`
+	args := []interface{}{since.UTC().Format("2006-01-02 15:04:05")}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shadow disagreements: %v", err)
+	}
+	defer rows.Close()
+
+	var disagreements []*ShadowDisagreement
+	for rows.Next() {
+		var d ShadowDisagreement
+		err := rows.Scan(
+			&d.ResultID,
+			&d.FilePath,
+			&d.Name,
+			&d.ScanTime,
+			&d.ActiveIsWebshell,
+			&d.ActiveRiskLevel,
+			&d.ActiveTotalScore,
+			&d.ShadowIsWebshell,
+			&d.ShadowRiskLevel,
+			&d.ShadowTotalScore,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		disagreements = append(disagreements, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read shadow disagreements: %v", err)
+	}
+
+	return disagreements, nil
+}
@@ -0,0 +1,266 @@
+package scanjob
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultDir 扫描任务状态文件的默认目录
+const DefaultDir = "data/jobs"
+
+// 任务状态
+const (
+	StatusRunning     = "running"
+	StatusPaused      = "paused"
+	StatusCancelling  = "cancelling" // 已请求取消，等待遍历和分析协程退出
+	StatusCompleted   = "completed"
+	StatusCancelled   = "cancelled"
+	StatusInterrupted = "interrupted" // 扫描器停止时未完成，启用断点续扫时下次从断点继续
+)
+
+// 控制请求
+const (
+	ControlPause  = "pause"
+	ControlResume = "resume"
+	ControlCancel = "cancel"
+)
+
+// staleAfter 运行中的任务超过该时间未更新状态文件时视为进程已退出
+const staleAfter = 30 * time.Second
+
+// Progress 扫描进度
+type Progress struct {
+	Discovered      int64         `json:"discovered"`       // 遍历发现的待扫描文件数，含跳过的文件
+	Scanned         int64         `json:"scanned"`          // 已完成检测的文件数
+	Skipped         int64         `json:"skipped"`          // 未变化而跳过的文件数
+	Failed          int64         `json:"failed"`           // 检测失败的文件数
+	BytesScanned    int64         `json:"bytes_scanned"`    // 已处理文件的总大小
+	BytesDiscovered int64         `json:"bytes_discovered"` // 需要检测的文件总大小
+	WalkDone        bool          `json:"walk_done"`        // 遍历是否已结束，结束前总数仍在增长
+	Elapsed         time.Duration `json:"elapsed"`          // 不含暂停时间的运行时长
+	FilesPerSec     float64       `json:"files_per_sec"`
+	BytesPerSec     float64       `json:"bytes_per_sec"`
+	ETA             time.Duration `json:"eta"` // 预计剩余时间，遍历结束前为 0
+}
+
+// Remaining 尚未处理的文件数
+func (p Progress) Remaining() int64 {
+	remaining := p.Discovered - p.Skipped - p.Scanned - p.Failed
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Percent 已处理文件占比，遍历结束前为 -1
+func (p Progress) Percent() float64 {
+	if !p.WalkDone {
+		return -1
+	}
+	total := p.Discovered - p.Skipped
+	if total <= 0 {
+		return 100
+	}
+	return float64(p.Scanned+p.Failed) * 100 / float64(total)
+}
+
+// String 进度摘要
+func (p Progress) String() string {
+	var b strings.Builder
+	done, total := p.Scanned+p.Failed, p.Discovered-p.Skipped
+	if p.WalkDone {
+		fmt.Fprintf(&b, "%d/%d files (%.1f%%)", done, total, p.Percent())
+	} else {
+		fmt.Fprintf(&b, "%d files done, %d found so far", done, total)
+	}
+	fmt.Fprintf(&b, ", %d skipped, %d failed, %.1f files/s, %s/s",
+		p.Skipped, p.Failed, p.FilesPerSec, FormatBytes(int64(p.BytesPerSec)))
+	if p.ETA > 0 {
+		fmt.Fprintf(&b, ", ETA %v", p.ETA.Round(time.Second))
+	}
+	return b.String()
+}
+
+// FormatBytes 按 KB、MB、GB 显示字节数
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, suffix := float64(n)/unit, "KB"
+	for _, next := range []string{"MB", "GB", "TB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
+
+// Snapshot 写入状态文件的任务快照
+type Snapshot struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"` // manual 或 scheduled
+	Name      string    `json:"name,omitempty"`
+	PID       int       `json:"pid"`
+	Status    string    `json:"status"`
+	Resumed   bool      `json:"resumed,omitempty"` // 是否从断点继续
+	Targets   []string  `json:"targets"`
+	StartTime time.Time `json:"start_time"`
+	UpdatedAt time.Time `json:"updated_at"`
+	EndTime   time.Time `json:"end_time,omitempty"`
+	Progress  Progress  `json:"progress"`
+}
+
+// Active 判断任务是否仍在运行或暂停中
+func (s *Snapshot) Active() bool {
+	return s.Status == StatusRunning || s.Status == StatusPaused || s.Status == StatusCancelling
+}
+
+// Stale 判断运行中的任务是否已长时间未更新，通常是进程被强制结束
+func (s *Snapshot) Stale(now time.Time) bool {
+	return s.Active() && now.Sub(s.UpdatedAt) > staleAfter
+}
+
+// fileName 任务编号对应的文件名
+func fileName(id string) string {
+	return strings.NewReplacer("/", "_", "\\", "_").Replace(id)
+}
+
+// Save 写入任务快照，先写临时文件再改名，读取方不会读到不完整的内容
+func Save(dir string, snapshot *Snapshot) error {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create job directory: %v", err)
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode job %s: %v", snapshot.ID, err)
+	}
+	path := filepath.Join(dir, fileName(snapshot.ID)+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write job %s: %v", snapshot.ID, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write job %s: %v", snapshot.ID, err)
+	}
+	return nil
+}
+
+// Load 读取任务快照
+func Load(dir, id string) (*Snapshot, error) {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	data, err := os.ReadFile(filepath.Join(dir, fileName(id)+".json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("job %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job %s: %v", id, err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse job %s: %v", id, err)
+	}
+	return &snapshot, nil
+}
+
+// List 列出目录中的全部任务，按开始时间从新到旧排序
+func List(dir string) ([]*Snapshot, error) {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %v", err)
+	}
+
+	snapshots := make([]*Snapshot, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var snapshot Snapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			continue
+		}
+		snapshots = append(snapshots, &snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].StartTime.After(snapshots[j].StartTime)
+	})
+	return snapshots, nil
+}
+
+// Prune 删除结束时间早于 age 之前的任务快照
+func Prune(dir string, age time.Duration) {
+	snapshots, err := List(dir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-age)
+	for _, snapshot := range snapshots {
+		if !snapshot.Active() && snapshot.UpdatedAt.Before(cutoff) {
+			os.Remove(filepath.Join(dir, fileName(snapshot.ID)+".json"))
+		}
+	}
+}
+
+// RequestControl 请求运行中的任务暂停、恢复或取消，任务所在进程定期读取请求
+func RequestControl(dir, id, action string) error {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	switch action {
+	case ControlPause, ControlResume, ControlCancel:
+	default:
+		return fmt.Errorf("unknown job control %q", action)
+	}
+
+	snapshot, err := Load(dir, id)
+	if err != nil {
+		return err
+	}
+	if !snapshot.Active() {
+		return fmt.Errorf("job %s is already %s", id, snapshot.Status)
+	}
+	if snapshot.Stale(time.Now()) {
+		return fmt.Errorf("job %s has not reported progress since %s, its process (PID %d) may have exited",
+			id, snapshot.UpdatedAt.Format("2006-01-02 15:04:05"), snapshot.PID)
+	}
+
+	path := filepath.Join(dir, fileName(id)+".control")
+	if err := os.WriteFile(path, []byte(action+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write control request: %v", err)
+	}
+	return nil
+}
+
+// TakeControl 读取并删除任务的控制请求，没有请求时返回空字符串
+func TakeControl(dir, id string) (string, error) {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	path := filepath.Join(dir, fileName(id)+".control")
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read control request: %v", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return "", fmt.Errorf("failed to remove control request: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
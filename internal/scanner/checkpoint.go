@@ -0,0 +1,211 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"webshell-detector/internal/detector"
+	"webshell-detector/internal/history"
+	"webshell-detector/internal/result"
+)
+
+// defaultCheckpointInterval 默认的断点写入间隔
+const defaultCheckpointInterval = 30 * time.Second
+
+// checkpointState 断点中保存的扫描统计和中间结果，续扫完成后与新结果一起写入扫描历史
+type checkpointState struct {
+	Summary      result.Summary              `json:"summary"`
+	Risky        []*detector.DetectionResult `json:"risky"`
+	Errors       []string                    `json:"errors"`
+	Failed       int                         `json:"failed"`
+	Skipped      int64                       `json:"skipped"`
+	BytesScanned int64                       `json:"bytes_scanned"`
+	Elapsed      time.Duration               `json:"elapsed"`
+	Incremental  *incrementalStats           `json:"incremental,omitempty"`
+}
+
+// checkpointer 定期把批量扫描的遍历位置和已完成的文件写入数据库
+type checkpointer struct {
+	manager  *history.Manager
+	key      string
+	interval time.Duration
+	last     time.Time
+	restored *checkpointState // 从断点继续时中断前的统计，新扫描为 nil
+}
+
+// scanKey 由扫描类型和扫描范围生成标识，范围相同的扫描才能从断点继续
+func scanKey(scanType string, scope interface{}) string {
+	data, _ := json.Marshal(scope)
+	sum := sha256.Sum256(append([]byte(scanType+"\n"), data...))
+	return scanType + "-" + hex.EncodeToString(sum[:8])
+}
+
+// openCheckpoint 打开断点库。存在相同范围的中断扫描时，fresh 为 false 则恢复其扫描编号、统计和遍历位置，
+// 为 true 则丢弃断点重新开始。未启用断点续扫或未配置数据库时返回 nil
+func (s *BaseScanner) openCheckpoint(run *scanRun, key string, fresh bool) *checkpointer {
+	cfg := s.config.Scan.Checkpoint
+	if !cfg.Enabled || s.config.Storage.Database.Path == "" {
+		return nil
+	}
+	manager, err := history.NewManager(history.Config{DBPath: s.config.Storage.Database.Path})
+	if err != nil {
+		log.Printf("Warning: Failed to open checkpoint database, scan will not be resumable: %v", err)
+		return nil
+	}
+	c := &checkpointer{manager: manager, key: key, interval: cfg.Interval, last: time.Now()}
+	if c.interval <= 0 {
+		c.interval = defaultCheckpointInterval
+	}
+
+	checkpoints, err := manager.Checkpoints(key)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return c
+	}
+	for _, checkpoint := range checkpoints {
+		if !c.resumable(checkpoint) {
+			log.Printf("Scan %s with the same targets is still running (PID %d), starting a separate scan", checkpoint.ScanID, checkpoint.PID)
+			continue
+		}
+		if fresh || c.restored != nil {
+			// 只从最新的断点继续，更早的断点已被取代
+			log.Printf("Discarding checkpoint of interrupted scan %s", checkpoint.ScanID)
+			if err := manager.DeleteCheckpoint(checkpoint.ScanID); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+			continue
+		}
+		if err := c.restore(run, checkpoint); err != nil {
+			log.Printf("Warning: Failed to resume scan %s, starting over: %v", checkpoint.ScanID, err)
+			manager.DeleteCheckpoint(checkpoint.ScanID)
+		}
+	}
+	return c
+}
+
+// resumable 判断断点能否继续：扫描已被停止，或所在进程已退出、长时间未更新断点（进程崩溃）
+func (c *checkpointer) resumable(checkpoint *history.Checkpoint) bool {
+	if checkpoint.Status == history.CheckpointInterrupted {
+		return true
+	}
+	return !processAlive(checkpoint.PID) || time.Since(checkpoint.UpdatedAt) > 3*c.interval
+}
+
+// restore 从断点恢复扫描编号、统计和遍历位置
+func (c *checkpointer) restore(run *scanRun, checkpoint *history.Checkpoint) error {
+	var state checkpointState
+	if err := json.Unmarshal(checkpoint.State, &state); err != nil {
+		return err
+	}
+
+	run.mu.Lock()
+	run.scanID = checkpoint.ScanID
+	run.startTime = checkpoint.StartTime
+	run.summary = state.Summary
+	run.risky = state.Risky
+	run.errors = state.Errors
+	run.failed = state.Failed
+	run.skipped = state.Skipped
+	run.bytesScanned = state.BytesScanned
+	run.bytesDiscovered = state.BytesScanned
+	run.priorElapsed = state.Elapsed
+	run.position = walkPosition{target: checkpoint.WalkTarget, path: checkpoint.WalkPath}
+	resumeAt := run.position
+	run.resumeAt = &resumeAt
+	run.resumed = make(map[string]bool, len(checkpoint.Completed))
+	for _, path := range checkpoint.Completed {
+		run.resumed[path] = true
+	}
+	run.mu.Unlock()
+
+	c.restored = &state
+	log.Printf("Resuming scan %s from checkpoint saved at %s (%d files done)",
+		checkpoint.ScanID, checkpoint.UpdatedAt.Format("2006-01-02 15:04:05"), state.Summary.TotalFiles+state.Failed)
+	return nil
+}
+
+// restoreIncremental 将中断前的文件变化统计计入增量扫描
+func (c *checkpointer) restoreIncremental(run *scanRun) {
+	if c == nil || c.restored == nil || c.restored.Incremental == nil || run.incremental == nil {
+		return
+	}
+	run.incremental.restore(*c.restored.Incremental)
+}
+
+// tick 距上次写入超过间隔时写入断点
+func (c *checkpointer) tick(run *scanRun, elapsed time.Duration) {
+	if time.Since(c.last) < c.interval {
+		return
+	}
+	c.last = time.Now()
+	if err := c.save(run, history.CheckpointRunning, elapsed); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// save 写入当前的遍历位置、位置之后已完成的文件和统计
+func (c *checkpointer) save(run *scanRun, status string, elapsed time.Duration) error {
+	run.mu.Lock()
+	checkpoint := &history.Checkpoint{
+		ScanID:     run.id(),
+		ScanType:   run.scanType,
+		ScanKey:    c.key,
+		Status:     status,
+		PID:        os.Getpid(),
+		WalkTarget: run.position.target,
+		WalkPath:   run.position.path,
+		StartTime:  run.startTime,
+		UpdatedAt:  time.Now(),
+	}
+	for _, entry := range run.walking {
+		if entry.done {
+			checkpoint.Completed = append(checkpoint.Completed, entry.pos.path)
+		}
+	}
+	// 续扫时断点位置之后在中断前完成的文件可能还未被遍历到，需要继续保留
+	for path := range run.resumed {
+		checkpoint.Completed = append(checkpoint.Completed, path)
+	}
+	state := checkpointState{
+		Summary:      run.summary,
+		Risky:        append([]*detector.DetectionResult(nil), run.risky...),
+		Errors:       append([]string(nil), run.errors...),
+		Failed:       run.failed,
+		Skipped:      run.skipped,
+		BytesScanned: run.bytesScanned,
+		Elapsed:      elapsed,
+	}
+	if run.incremental != nil {
+		stats := run.incremental.stats()
+		state.Incremental = &stats
+	}
+	run.mu.Unlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	checkpoint.State = data
+	return c.manager.SaveCheckpoint(checkpoint)
+}
+
+// finish 扫描结束时调用：被中断的扫描保存断点供下次继续，返回是否保存成功；
+// 完成或取消的扫描删除同一范围的全部断点，包括进程崩溃后遗留的更早断点
+func (c *checkpointer) finish(run *scanRun, interrupted bool, elapsed time.Duration) bool {
+	defer c.manager.Close()
+	if interrupted {
+		if err := c.save(run, history.CheckpointInterrupted, elapsed); err != nil {
+			log.Printf("Warning: Failed to save checkpoint, scan cannot be resumed: %v", err)
+			return false
+		}
+		return true
+	}
+	if err := c.manager.DeleteCheckpoints(c.key); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return false
+}
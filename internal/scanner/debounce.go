@@ -0,0 +1,183 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"webshell-detector/internal/detector"
+)
+
+const (
+	defaultQuietPeriod = time.Second
+	defaultMaxDelay    = 30 * time.Second
+)
+
+// debouncer 合并同一文件的连续事件：文件在静默期内没有新事件且大小和修改时间不再变化后才提交扫描；
+// 文件有新事件时取消该文件尚未完成的扫描，只保留最新版本的结果
+type debouncer struct {
+	ctx      context.Context
+	quiet    time.Duration
+	maxDelay time.Duration
+	submit   func(job *scanJob) bool
+	finished func(path string, result *detector.DetectionResult)
+
+	mu       sync.Mutex
+	pending  map[string]*pendingFile
+	inflight map[string]*inflightScan
+	nextID   uint64
+	closed   bool
+}
+
+// pendingFile 等待静默期结束的文件
+type pendingFile struct {
+	timer   *time.Timer
+	first   time.Time // 第一次事件的时间
+	size    int64     // 最近一次观察到的大小
+	modTime time.Time // 最近一次观察到的修改时间
+	process *detector.ProcessInfo
+}
+
+// inflightScan 已提交尚未完成的扫描
+type inflightScan struct {
+	id     uint64
+	cancel context.CancelFunc
+}
+
+// newDebouncer 创建事件合并器，submit 将文件提交到扫描流水线，流水线已停止时返回 false；
+// finished 在文件最新版本处理结束时调用，扫描失败或文件已消失时 result 为 nil
+func newDebouncer(ctx context.Context, quiet, maxDelay time.Duration, submit func(job *scanJob) bool,
+	finished func(path string, result *detector.DetectionResult)) *debouncer {
+	if quiet <= 0 {
+		quiet = defaultQuietPeriod
+	}
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxDelay
+	}
+	return &debouncer{
+		ctx:      ctx,
+		quiet:    quiet,
+		maxDelay: maxDelay,
+		submit:   submit,
+		finished: finished,
+		pending:  make(map[string]*pendingFile),
+		inflight: make(map[string]*inflightScan),
+	}
+}
+
+// touch 记录文件的一次事件：取消该文件正在进行的扫描，并重新开始静默期计时；
+// process 为写入文件的进程，未知时为 nil
+func (d *debouncer) touch(path string, process *detector.ProcessInfo) {
+	size, modTime := statFile(path)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+
+	if scan, ok := d.inflight[path]; ok {
+		scan.cancel()
+		delete(d.inflight, path)
+	}
+
+	if p, ok := d.pending[path]; ok {
+		p.size, p.modTime = size, modTime
+		if process != nil {
+			p.process = process
+		}
+		p.timer.Reset(d.quiet)
+		return
+	}
+	d.pending[path] = &pendingFile{
+		timer:   time.AfterFunc(d.quiet, func() { d.fire(path) }),
+		first:   time.Now(),
+		size:    size,
+		modTime: modTime,
+		process: process,
+	}
+}
+
+// fire 静默期结束：文件仍在变化时继续等待（不超过 maxDelay），否则提交扫描
+func (d *debouncer) fire(path string) {
+	info, err := os.Stat(path)
+
+	d.mu.Lock()
+	p, ok := d.pending[path]
+	if !ok || d.closed {
+		d.mu.Unlock()
+		return
+	}
+	if err != nil {
+		// 文件已被删除或移走
+		delete(d.pending, path)
+		d.mu.Unlock()
+		d.finished(path, nil)
+		return
+	}
+	changed := info.Size() != p.size || !info.ModTime().Equal(p.modTime)
+	if changed && time.Since(p.first) < d.maxDelay {
+		p.size, p.modTime = info.Size(), info.ModTime()
+		p.timer.Reset(d.quiet)
+		d.mu.Unlock()
+		return
+	}
+	delete(d.pending, path)
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	d.nextID++
+	scan := &inflightScan{id: d.nextID, cancel: cancel}
+	d.inflight[path] = scan
+	d.mu.Unlock()
+
+	job := &scanJob{
+		path:     path,
+		scanType: "realtime",
+		process:  p.process,
+		ctx:      ctx,
+		done: func(result *detector.DetectionResult) {
+			cancel()
+			d.mu.Lock()
+			current, ok := d.inflight[path]
+			latest := ok && current.id == scan.id
+			if latest {
+				delete(d.inflight, path)
+			}
+			d.mu.Unlock()
+			// 被新版本取代的扫描不通知，由新版本的扫描结束时通知
+			if latest {
+				d.finished(path, result)
+			}
+		},
+	}
+	if !d.submit(job) {
+		job.done(nil)
+	}
+}
+
+// stop 停止计时并丢弃尚未提交的文件，已提交的扫描不受影响
+func (d *debouncer) stop() {
+	d.mu.Lock()
+	d.closed = true
+	var dropped []string
+	for path, p := range d.pending {
+		p.timer.Stop()
+		delete(d.pending, path)
+		dropped = append(dropped, path)
+	}
+	d.mu.Unlock()
+
+	for _, path := range dropped {
+		d.finished(path, nil)
+	}
+}
+
+// statFile 返回文件大小和修改时间，文件不存在时返回零值
+func statFile(path string) (int64, time.Time) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, time.Time{}
+	}
+	return info.Size(), info.ModTime()
+}
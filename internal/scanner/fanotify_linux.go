@@ -0,0 +1,493 @@
+//go:build linux
+
+package scanner
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"webshell-detector/internal/config"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	defaultPermissionTimeout = 10 * time.Second
+	// heldSweepInterval 检查被拦截的文件是否已被删除、移走或替换的间隔，补上漏掉的删除和移动事件
+	heldSweepInterval = time.Minute
+)
+
+// fanotifyWatcher 基于 fanotify 的监控后端：按挂载点监控文件写入并记录写入进程，
+// 启用拦截时对等待结论的文件单独添加打开权限事件
+type fanotifyWatcher struct {
+	fd      int
+	file    *os.File
+	block   bool
+	timeout time.Duration
+	events  chan fileEvent
+	errors  chan error
+
+	// 写入事件先放入队列再由转发协程送入 events，读取协程不会因 events 已满而停止处理权限事件
+	queueMu sync.Mutex
+	queue   []fileEvent
+	queued  chan struct{} // 队列有新事件，读取结束时关闭
+	done    chan struct{} // Close 时关闭，停止转发
+
+	mu     sync.Mutex
+	roots  []string
+	mounts map[string]bool
+	held   map[string]*heldFile
+	closed bool
+}
+
+// heldFile 正在拦截打开请求的文件
+type heldFile struct {
+	fd      int    // 被标记文件的 fd，文件被移走后仍能移除其 inode 上的标记
+	dev     uint64 // 被标记文件的设备号和 inode，用于判断路径是否已指向其他文件
+	ino     uint64
+	waiters []int32 // 等待响应的权限事件 fd
+	denied  bool    // 已判定为 webshell，之后的打开请求（包括写入）一律拒绝，直到文件被删除、移走或替换
+}
+
+// newFanotifyWatcher 创建 fanotify 监控后端
+func newFanotifyWatcher(cfg config.RealtimeConfig) (fileWatcher, error) {
+	class := uint(unix.FAN_CLASS_NOTIF)
+	if cfg.BlockPending {
+		class = unix.FAN_CLASS_CONTENT
+	}
+	fd, err := unix.FanotifyInit(class|unix.FAN_CLOEXEC|unix.FAN_NONBLOCK|unix.FAN_UNLIMITED_QUEUE,
+		unix.O_RDONLY|unix.O_LARGEFILE|unix.O_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("fanotify_init failed: %v", err)
+	}
+
+	timeout := cfg.PermissionTimeout
+	if timeout <= 0 {
+		timeout = defaultPermissionTimeout
+	}
+	w := &fanotifyWatcher{
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "fanotify"),
+		block:   cfg.BlockPending,
+		timeout: timeout,
+		events:  make(chan fileEvent, 256),
+		errors:  make(chan error, 16),
+		queued:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		mounts:  make(map[string]bool),
+		held:    make(map[string]*heldFile),
+	}
+	go w.read()
+	go w.forward()
+	go w.sweep()
+	return w, nil
+}
+
+// Add 监控路径所在的整个挂载点，只报告该路径下的事件；路径经过符号链接时按链接目标监控
+func (w *fanotifyWatcher) Add(path string) error {
+	abs, err := resolvePath(path)
+	if err != nil {
+		return err
+	}
+	mount, err := mountPoint(abs)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.mounts[mount] {
+		// 挂载点标记只支持带 fd 的事件，新建、移入和权限变化需要 FAN_REPORT_FID，
+		// 而 FAN_REPORT_FID 不能与拦截所需的权限模式同时使用，这些事件由 combinedWatcher 中的 inotify 补上
+		if err := unix.FanotifyMark(w.fd, unix.FAN_MARK_ADD|unix.FAN_MARK_MOUNT, unix.FAN_CLOSE_WRITE, unix.AT_FDCWD, mount); err != nil {
+			return fmt.Errorf("failed to mark mount %s: %v", mount, err)
+		}
+		w.mounts[mount] = true
+	}
+	w.roots = append(w.roots, abs)
+	return nil
+}
+
+// Remove 不再报告该路径下的事件，挂载点标记保留
+func (w *fanotifyWatcher) Remove(path string) error {
+	abs, err := resolvePath(path)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, root := range w.roots {
+		if root == abs {
+			w.roots = append(w.roots[:i], w.roots[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (w *fanotifyWatcher) Recursive() bool          { return true }
+func (w *fanotifyWatcher) Events() <-chan fileEvent { return w.events }
+func (w *fanotifyWatcher) Errors() <-chan error     { return w.errors }
+
+// Close 放行所有等待中的打开请求并关闭 fanotify
+func (w *fanotifyWatcher) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.done)
+	for path, held := range w.held {
+		w.unhold(path, held)
+	}
+	w.mu.Unlock()
+	return w.file.Close()
+}
+
+// Hold 对文件添加打开权限事件，结论出来前其他进程的打开请求会被挂起；
+// 已判定为 webshell 的文件被替换为新文件时改为标记新文件，按新内容的结论处理
+func (w *fanotifyWatcher) Hold(path string) error {
+	if !w.block {
+		return nil
+	}
+	abs, err := resolvePath(path)
+	if err != nil {
+		return err
+	}
+
+	// 在加锁前打开文件：文件已被标记时本进程的打开也会产生权限事件，需要读取协程处理
+	fd, err := unix.Open(abs, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_CLOEXEC|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return fmt.Errorf("failed to hold %s: %v", abs, err)
+	}
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		unix.Close(fd)
+		return fmt.Errorf("failed to hold %s: %v", abs, err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	held := w.held[abs]
+	if w.closed || (held != nil && !held.denied) {
+		unix.Close(fd)
+		return nil
+	}
+	if held != nil {
+		if held.dev == uint64(stat.Dev) && held.ino == uint64(stat.Ino) {
+			// 同一个文件再次变化（如修改权限），重新等待结论
+			unix.Close(fd)
+			held.denied = false
+			return nil
+		}
+		w.unhold(abs, held)
+	}
+	if err := unix.FanotifyMark(w.fd, unix.FAN_MARK_ADD, unix.FAN_OPEN_PERM, fd, ""); err != nil {
+		unix.Close(fd)
+		return fmt.Errorf("failed to hold %s: %v", abs, err)
+	}
+	w.held[abs] = &heldFile{fd: fd, dev: uint64(stat.Dev), ino: uint64(stat.Ino)}
+	return nil
+}
+
+// Release 响应等待中的请求。放行时移除文件的打开权限事件；拒绝时保留拦截，
+// 之后的打开请求（包括写入）同样拒绝，直到文件被删除、移走（包括被响应动作隔离）或替换，
+// 此时由 Forget 或定期检查移除拦截
+func (w *fanotifyWatcher) Release(path string, deny bool) {
+	abs, err := resolvePath(path)
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	held := w.held[abs]
+	if held == nil {
+		return
+	}
+	if deny {
+		if len(held.waiters) > 0 {
+			log.Printf("Denied %d pending open(s) of %s", len(held.waiters), abs)
+		}
+		for _, fd := range held.waiters {
+			w.respond(fd, false)
+		}
+		held.waiters = nil
+		held.denied = true
+		return
+	}
+	w.unhold(abs, held)
+}
+
+// Forget 文件被删除或移走后移除其拦截；文件已不存在，按所在目录解析符号链接
+func (w *fanotifyWatcher) Forget(path string) {
+	dir, err := resolvePath(filepath.Dir(path))
+	if err != nil {
+		return
+	}
+	abs := filepath.Join(dir, filepath.Base(path))
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if held := w.held[abs]; held != nil {
+		w.unhold(abs, held)
+	}
+}
+
+// unhold 放行等待中的请求，移除文件 inode 上的打开权限事件并不再拦截该路径，调用方需持有 w.mu
+func (w *fanotifyWatcher) unhold(path string, held *heldFile) {
+	for _, fd := range held.waiters {
+		w.respond(fd, true)
+	}
+	held.waiters = nil
+	// 文件已被删除时内核可能已移除标记，忽略错误
+	unix.FanotifyMark(w.fd, unix.FAN_MARK_REMOVE, unix.FAN_OPEN_PERM, held.fd, "")
+	unix.Close(held.fd)
+	delete(w.held, path)
+}
+
+// sweep 定期移除路径已不再指向被拦截文件的记录，补上未收到删除或移动事件的文件
+func (w *fanotifyWatcher) sweep() {
+	ticker := time.NewTicker(heldSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.prune()
+		}
+	}
+}
+
+// prune 移除路径已不再指向被拦截文件的记录，仍有请求在等待结论的文件除外
+func (w *fanotifyWatcher) prune() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for path, held := range w.held {
+		if len(held.waiters) == 0 && !held.current(path) {
+			w.unhold(path, held)
+		}
+	}
+}
+
+// current 判断路径是否仍指向被拦截的文件
+func (h *heldFile) current(path string) bool {
+	var stat unix.Stat_t
+	if err := unix.Fstat(h.fd, &stat); err != nil || stat.Nlink == 0 {
+		return false
+	}
+	if err := unix.Lstat(path, &stat); err != nil {
+		return false
+	}
+	return uint64(stat.Dev) == h.dev && uint64(stat.Ino) == h.ino
+}
+
+// read 读取 fanotify 事件
+func (w *fanotifyWatcher) read() {
+	defer close(w.queued)
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			w.mu.Lock()
+			closed := w.closed
+			w.mu.Unlock()
+			if !closed {
+				w.reportError(fmt.Errorf("failed to read fanotify events: %v", err))
+			}
+			return
+		}
+
+		for offset := 0; offset+unix.FAN_EVENT_METADATA_LEN <= n; {
+			meta := (*unix.FanotifyEventMetadata)(unsafe.Pointer(&buf[offset]))
+			if meta.Event_len < unix.FAN_EVENT_METADATA_LEN || offset+int(meta.Event_len) > n {
+				break
+			}
+			offset += int(meta.Event_len)
+
+			if meta.Vers != unix.FANOTIFY_METADATA_VERSION {
+				w.reportError(fmt.Errorf("unsupported fanotify metadata version %d", meta.Vers))
+				return
+			}
+			if meta.Mask&unix.FAN_Q_OVERFLOW != 0 {
+				w.reportError(fmt.Errorf("fanotify event queue overflow, some changes were missed"))
+			}
+			if meta.Fd == unix.FAN_NOFD {
+				continue
+			}
+			w.handle(meta)
+		}
+	}
+}
+
+// handle 处理一个带 fd 的事件
+func (w *fanotifyWatcher) handle(meta *unix.FanotifyEventMetadata) {
+	path, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(int(meta.Fd)))
+	pid := int(meta.Pid)
+
+	if meta.Mask&unix.FAN_OPEN_PERM != 0 {
+		w.checkPermission(meta.Fd, pid, path)
+		return
+	}
+	unix.Close(int(meta.Fd))
+
+	if err != nil || pid == os.Getpid() || !w.underRoots(path) {
+		return
+	}
+	w.enqueue(fileEvent{Path: path, Op: opWrite, Process: lookupProcess(pid)})
+}
+
+// enqueue 将写入事件放入转发队列，不阻塞读取协程
+func (w *fanotifyWatcher) enqueue(event fileEvent) {
+	w.queueMu.Lock()
+	w.queue = append(w.queue, event)
+	w.queueMu.Unlock()
+	select {
+	case w.queued <- struct{}{}:
+	default:
+	}
+}
+
+// forward 将队列中的写入事件送入 events，读取结束后转发完剩余事件再关闭 events
+func (w *fanotifyWatcher) forward() {
+	defer close(w.events)
+	for {
+		_, ok := <-w.queued
+		for {
+			w.queueMu.Lock()
+			batch := w.queue
+			w.queue = nil
+			w.queueMu.Unlock()
+			if len(batch) == 0 {
+				break
+			}
+			for _, event := range batch {
+				select {
+				case w.events <- event:
+				case <-w.done:
+					return
+				}
+			}
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+// checkPermission 处理打开权限事件：本进程及子进程和不在拦截中的文件立即放行，
+// 已判定为 webshell 的文件立即拒绝，其余请求等待 Release 或超时放行
+func (w *fanotifyWatcher) checkPermission(fd int32, pid int, path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	held := w.held[path]
+	if held == nil || w.closed || isOwnProcess(pid) {
+		w.respond(fd, true)
+		return
+	}
+	if held.denied {
+		log.Printf("Denied open of %s by pid %d", path, pid)
+		w.respond(fd, false)
+		return
+	}
+	held.waiters = append(held.waiters, fd)
+	time.AfterFunc(w.timeout, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.held[path] != held {
+			return
+		}
+		for i, waiting := range held.waiters {
+			if waiting == fd {
+				held.waiters = append(held.waiters[:i], held.waiters[i+1:]...)
+				log.Printf("Warning: No verdict for %s within %v, allowing open by pid %d", path, w.timeout, pid)
+				w.respond(fd, true)
+				return
+			}
+		}
+	})
+}
+
+// respond 响应权限事件并关闭事件 fd，调用方需持有 w.mu
+func (w *fanotifyWatcher) respond(fd int32, allow bool) {
+	response := unix.FanotifyResponse{Fd: fd, Response: unix.FAN_ALLOW}
+	if !allow {
+		response.Response = unix.FAN_DENY
+	}
+	payload := (*[unsafe.Sizeof(response)]byte)(unsafe.Pointer(&response))[:]
+	if _, err := w.file.Write(payload); err != nil {
+		log.Printf("Warning: Failed to answer fanotify permission event: %v", err)
+	}
+	unix.Close(int(fd))
+}
+
+// underRoots 判断路径是否位于监控目录下
+func (w *fanotifyWatcher) underRoots(path string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, root := range w.roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath 返回解析符号链接后的绝对路径，与事件中经 /proc/self/fd 得到的路径一致；
+// 路径已不存在时返回未解析的绝对路径
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved, nil
+	}
+	return abs, nil
+}
+
+// reportError 报告错误，错误通道已满时丢弃
+func (w *fanotifyWatcher) reportError(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
+
+// mountPoint 返回路径所在的挂载点
+func mountPoint(path string) (string, error) {
+	data, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return "", fmt.Errorf("failed to read mount table: %v", err)
+	}
+
+	best := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		mount := unescapeMountPath(fields[4])
+		if (path == mount || strings.HasPrefix(path, strings.TrimSuffix(mount, "/")+"/")) && len(mount) > len(best) {
+			best = mount
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("no mount point found for %s", path)
+	}
+	return best, nil
+}
+
+// unescapeMountPath 还原 mountinfo 中转义的空白字符
+func unescapeMountPath(path string) string {
+	return strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`).Replace(path)
+}
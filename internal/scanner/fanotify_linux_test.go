@@ -0,0 +1,109 @@
+//go:build linux
+
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"webshell-detector/internal/config"
+)
+
+// newTestFanotify 创建启用拦截的 fanotify 后端，没有权限时跳过
+func newTestFanotify(t *testing.T) *fanotifyWatcher {
+	t.Helper()
+	watcher, err := newFanotifyWatcher(config.RealtimeConfig{BlockPending: true, PermissionTimeout: time.Second})
+	if err != nil {
+		t.Skipf("fanotify unavailable: %v", err)
+	}
+	t.Cleanup(func() { watcher.Close() })
+	return watcher.(*fanotifyWatcher)
+}
+
+// heldCount 正在拦截的文件数
+func heldCount(w *fanotifyWatcher) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.held)
+}
+
+func TestFanotifyForgetsDeniedFiles(t *testing.T) {
+	w := newTestFanotify(t)
+	dir := t.TempDir()
+
+	deleted := filepath.Join(dir, "deleted.php")
+	moved := filepath.Join(dir, "moved.php")
+	allowed := filepath.Join(dir, "allowed.php")
+	for _, path := range []string{deleted, moved, allowed} {
+		if err := os.WriteFile(path, []byte("<?php echo 1;"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Hold(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Release(deleted, true)
+	w.Release(moved, true)
+	w.Release(allowed, false)
+	if got := heldCount(w); got != 2 {
+		t.Fatalf("held %d files after release, want the 2 denied files", got)
+	}
+
+	// 删除事件移除拦截
+	if err := os.Remove(deleted); err != nil {
+		t.Fatal(err)
+	}
+	w.Forget(deleted)
+	if got := heldCount(w); got != 1 {
+		t.Fatalf("held %d files after deleting one, want 1", got)
+	}
+
+	// 移走但没有收到事件的文件由定期检查移除
+	if err := os.Rename(moved, filepath.Join(t.TempDir(), "moved.php")); err != nil {
+		t.Fatal(err)
+	}
+	w.prune()
+	if got := heldCount(w); got != 0 {
+		t.Fatalf("held %d files after the denied file was moved away, want 0", got)
+	}
+}
+
+func TestFanotifyHoldsReplacedFile(t *testing.T) {
+	w := newTestFanotify(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shell.php")
+	if err := os.WriteFile(path, []byte("<?php eval($_POST[1]);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Hold(path); err != nil {
+		t.Fatal(err)
+	}
+	w.Release(path, true)
+
+	// 替换为新文件后重新拦截新文件，旧文件的记录被移除
+	replacement := filepath.Join(dir, "replacement.tmp")
+	if err := os.WriteFile(replacement, []byte("<?php echo 1;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(replacement, path); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Hold(path); err != nil {
+		t.Fatal(err)
+	}
+	w.mu.Lock()
+	held := w.held[path]
+	w.mu.Unlock()
+	if held == nil || held.denied || !held.current(path) {
+		t.Fatalf("replaced file is not held as a pending file: %+v", held)
+	}
+	if got := heldCount(w); got != 1 {
+		t.Errorf("held %d files, want 1", got)
+	}
+	w.Release(path, false)
+	if got := heldCount(w); got != 0 {
+		t.Errorf("held %d files after allowing, want 0", got)
+	}
+}
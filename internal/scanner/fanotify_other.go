@@ -0,0 +1,14 @@
+//go:build !linux
+
+package scanner
+
+import (
+	"fmt"
+
+	"webshell-detector/internal/config"
+)
+
+// newFanotifyWatcher fanotify 仅支持 Linux
+func newFanotifyWatcher(cfg config.RealtimeConfig) (fileWatcher, error) {
+	return nil, fmt.Errorf("fanotify is only supported on Linux")
+}
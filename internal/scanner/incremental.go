@@ -0,0 +1,200 @@
+package scanner
+
+import (
+	"io/fs"
+	"log"
+	"sync"
+	"time"
+
+	"webshell-detector/internal/detector"
+	"webshell-detector/internal/filestate"
+)
+
+// fileChange 文件相对于索引的变化
+type fileChange int
+
+const (
+	changeUnknown   fileChange = iota // 未能对照索引
+	changeAdded                       // 索引中没有的新文件
+	changeModified                    // 内容已变化
+	changeUnchanged                   // 未变化
+)
+
+// incrementalRun 一次增量扫描：对照文件状态索引跳过未变化的文件，并统计新增、修改和删除的文件
+type incrementalRun struct {
+	index       *filestate.Index
+	runID       string
+	fingerprint string                 // 本次扫描的检测引擎指纹
+	rescan      bool                   // 全量扫描：只统计变化并更新索引，不跳过未变化的文件
+	scope       func(path string) bool // 本次扫描覆盖的文件，只有其中未出现的文件计为已删除
+	full        bool                   // 检测引擎已变化，全部文件重新扫描
+
+	mu        sync.Mutex
+	added     int
+	modified  int
+	unchanged int
+	deleted   int
+	risky     []string // 未变化但上次结论有风险的文件
+}
+
+// newIncrementalRun 创建增量扫描，fingerprint 为当前检测引擎指纹；rescan 为 true 时不跳过未变化的文件，
+// scope 为 nil 时索引中所有本次未出现的文件都计为已删除
+func newIncrementalRun(index *filestate.Index, runID, fingerprint string, rescan bool, scope func(path string) bool) *incrementalRun {
+	return &incrementalRun{
+		index:       index,
+		runID:       runID,
+		fingerprint: fingerprint,
+		rescan:      rescan,
+		scope:       scope,
+	}
+}
+
+// check 对照索引判断文件是否需要扫描，并返回文件的变化，文件处理完毕后由 tally 计入统计。
+// 可以跳过时返回文件的上次状态，其中的结论沿用到本次扫描。
+// 大小、修改时间和 inode 都未变化视为未变化；仅元数据变化时比较内容哈希
+func (r *incrementalRun) check(path string, info fs.FileInfo) (*filestate.State, fileChange) {
+	prev, err := r.index.Get(path)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return nil, changeUnknown
+	}
+	if prev == nil {
+		return nil, changeAdded
+	}
+
+	changed := prev.Size != info.Size() || !prev.ModTime.Equal(info.ModTime()) || prev.Inode != filestate.Inode(info)
+	if changed && prev.Size == info.Size() && prev.ContentHash != "" {
+		// 内容未变（如仅被 touch）时更新元数据，沿用上次结论
+		if hash, err := filestate.HashFile(path); err == nil && hash == prev.ContentHash {
+			prev.ModTime = info.ModTime()
+			prev.Inode = filestate.Inode(info)
+			changed = false
+		}
+	}
+
+	change := changeUnchanged
+	if changed {
+		change = changeModified
+	}
+
+	if prev.EngineVersion != r.fingerprint {
+		r.mu.Lock()
+		r.full = true
+		r.mu.Unlock()
+		changed = true
+	}
+
+	if changed || r.rescan {
+		// 保留记录以免扫描失败时被当作已删除
+		if err := r.index.Touch(path, r.runID); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		return nil, change
+	}
+	if err := r.index.Put(prev, r.runID); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return prev, change
+}
+
+// stillRisky 记录上次结论有风险、本次未变化而跳过的文件
+func (r *incrementalRun) stillRisky(path string) {
+	r.mu.Lock()
+	r.risky = append(r.risky, path)
+	r.mu.Unlock()
+}
+
+// tally 文件处理完毕后计入变化统计，未完成的文件在断点续扫时会再次对照索引，不能提前计入
+func (r *incrementalRun) tally(change fileChange) {
+	switch change {
+	case changeAdded:
+		r.count(&r.added)
+	case changeModified:
+		r.count(&r.modified)
+	case changeUnchanged:
+		r.count(&r.unchanged)
+	}
+}
+
+// restore 从断点恢复中断前的变化统计
+func (r *incrementalRun) restore(stats incrementalStats) {
+	r.mu.Lock()
+	r.added += stats.New
+	r.modified += stats.Modified
+	r.unchanged += stats.Unchanged
+	r.risky = append(r.risky, stats.StillRisky...)
+	r.full = r.full || stats.FullRescan
+	r.mu.Unlock()
+}
+
+// record 扫描完成后写入文件的最新状态和结论，job.info 已替换为检测读取内容前的文件信息，
+// 遍历后到读取前文件被修改时记录的是实际检测的版本
+func (r *incrementalRun) record(job *scanJob, detectionResult *detector.DetectionResult) {
+	if job.info == nil {
+		return
+	}
+	state := &filestate.State{
+		Path:          job.path,
+		Size:          job.info.Size(),
+		ModTime:       job.info.ModTime(),
+		Inode:         filestate.Inode(job.info),
+		ContentHash:   detectionResult.ContentHash,
+		RiskLevel:     string(detectionResult.RiskLevel),
+		IsWebshell:    detectionResult.IsWebshell,
+		TotalScore:    detectionResult.TotalScore,
+		EngineVersion: r.fingerprint,
+		ScanTime:      time.Now(),
+	}
+	if err := r.index.Put(state, r.runID); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// finish 从索引中移除本次未出现的文件并计为已删除；遍历被中断时不做删除统计
+func (r *incrementalRun) finish(interrupted bool) {
+	if interrupted {
+		return
+	}
+	deleted, err := r.index.Prune(r.runID, r.scope)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+	r.mu.Lock()
+	r.deleted = len(deleted)
+	r.mu.Unlock()
+	for _, path := range deleted {
+		log.Printf("File removed since last scan: %s", path)
+	}
+}
+
+// count 增加一个计数
+func (r *incrementalRun) count(counter *int) {
+	r.mu.Lock()
+	*counter++
+	r.mu.Unlock()
+}
+
+// incrementalStats 一次增量扫描的文件变化统计
+type incrementalStats struct {
+	New        int      `json:"new"`
+	Modified   int      `json:"modified"`
+	Deleted    int      `json:"deleted"`
+	Unchanged  int      `json:"unchanged"`
+	FullRescan bool     `json:"full_rescan"`           // 检测引擎变化导致全部文件重新扫描
+	StillRisky []string `json:"still_risky,omitempty"` // 未变化而跳过、上次结论有风险的文件，结论已计入本次统计
+}
+
+// stats 返回本次扫描的文件变化统计，用于日志和扫描历史
+func (r *incrementalRun) stats() incrementalStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return incrementalStats{
+		New:        r.added,
+		Modified:   r.modified,
+		Deleted:    r.deleted,
+		Unchanged:  r.unchanged,
+		FullRescan: r.full,
+		StillRisky: append([]string(nil), r.risky...),
+	}
+}
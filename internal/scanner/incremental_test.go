@@ -0,0 +1,190 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"webshell-detector/internal/detector"
+	"webshell-detector/internal/filestate"
+)
+
+// newTestIndex 在临时目录中打开文件状态索引
+func newTestIndex(t *testing.T) *filestate.Index {
+	t.Helper()
+	index, err := filestate.NewIndex(filepath.Join(t.TempDir(), "filestate.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { index.Close() })
+	return index
+}
+
+// writeFileAt 写入文件并设置修改时间
+func writeFileAt(t *testing.T, path, content string, mtime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// scanIncremental 模拟一次增量扫描：对照索引检查每个文件，需要扫描的文件按 level 记录结论
+func scanIncremental(t *testing.T, run *incrementalRun, paths []string, level detector.RiskLevel) map[string]fileChange {
+	t.Helper()
+	changes := make(map[string]fileChange)
+	for _, path := range paths {
+		info, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prev, change := run.check(path, info)
+		if prev == nil {
+			hash, err := filestate.HashFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			run.record(&scanJob{path: path, info: info}, &detector.DetectionResult{ContentHash: hash, RiskLevel: level})
+		}
+		run.tally(change)
+		changes[path] = change
+	}
+	run.finish(false)
+	return changes
+}
+
+func TestIncrementalRunClassifiesChanges(t *testing.T) {
+	index := newTestIndex(t)
+	dir := t.TempDir()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	unchanged := filepath.Join(dir, "unchanged.php")
+	touched := filepath.Join(dir, "touched.php")
+	rewritten := filepath.Join(dir, "rewritten.php")
+	grown := filepath.Join(dir, "grown.php")
+	deleted := filepath.Join(dir, "deleted.php")
+	for _, path := range []string{unchanged, touched, rewritten, grown, deleted} {
+		writeFileAt(t, path, "<?php echo 1;", base)
+	}
+	first := newIncrementalRun(index, "run-1", "engine-1", false, nil)
+	for path, change := range scanIncremental(t, first, []string{unchanged, touched, rewritten, grown, deleted}, detector.RiskLevelSafe) {
+		if change != changeAdded {
+			t.Errorf("first scan: %s change = %d, want added", filepath.Base(path), change)
+		}
+	}
+
+	// 仅修改时间变化、同大小内容变化、大小变化、删除和新增
+	later := base.Add(time.Hour)
+	writeFileAt(t, touched, "<?php echo 1;", later)
+	writeFileAt(t, rewritten, "<?php echo 2;", later)
+	writeFileAt(t, grown, "<?php echo 1; echo 2;", base)
+	if err := os.Remove(deleted); err != nil {
+		t.Fatal(err)
+	}
+	added := filepath.Join(dir, "added.php")
+	writeFileAt(t, added, "<?php echo 3;", later)
+
+	second := newIncrementalRun(index, "run-2", "engine-1", false, nil)
+	paths := []string{unchanged, touched, rewritten, grown, added}
+	var skipped []string
+	for _, path := range paths {
+		info, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prev, _ := second.check(path, info); prev != nil {
+			skipped = append(skipped, filepath.Base(path))
+		}
+	}
+	if len(skipped) != 2 || skipped[0] != "unchanged.php" || skipped[1] != "touched.php" {
+		t.Errorf("skipped %v, want the unchanged and touched files", skipped)
+	}
+
+	third := newIncrementalRun(index, "run-3", "engine-1", false, nil)
+	writeFileAt(t, rewritten, "<?php echo 3;", later.Add(time.Hour))
+	writeFileAt(t, grown, "<?php echo 1; echo 2; echo 3;", base)
+	changes := scanIncremental(t, third, paths, detector.RiskLevelSafe)
+	want := map[string]fileChange{
+		unchanged: changeUnchanged,
+		touched:   changeUnchanged,
+		rewritten: changeModified,
+		grown:     changeModified,
+		added:     changeAdded,
+	}
+	for path, change := range want {
+		if changes[path] != change {
+			t.Errorf("%s change = %d, want %d", filepath.Base(path), changes[path], change)
+		}
+	}
+	stats := third.stats()
+	if stats.New != 1 || stats.Modified != 2 || stats.Unchanged != 2 || stats.Deleted != 1 || stats.FullRescan {
+		t.Errorf("stats = %+v, want 1 new, 2 modified, 2 unchanged, 1 deleted", stats)
+	}
+
+	// 仅修改时间变化时索引更新为新的修改时间，下次无需再计算哈希
+	state, err := index.Get(touched)
+	if err != nil || state == nil {
+		t.Fatalf("touched file missing from index: %v", err)
+	}
+	if !state.ModTime.Equal(later) {
+		t.Errorf("indexed mtime of touched file = %v, want %v", state.ModTime, later)
+	}
+}
+
+func TestIncrementalRunEngineChange(t *testing.T) {
+	index := newTestIndex(t)
+	dir := t.TempDir()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	risky := filepath.Join(dir, "risky.php")
+	clean := filepath.Join(dir, "clean.php")
+	writeFileAt(t, risky, "<?php eval($_POST[1]);", base)
+	writeFileAt(t, clean, "<?php echo 1;", base)
+	scanIncremental(t, newIncrementalRun(index, "run-1", "engine-1", false, nil), []string{risky, clean}, detector.RiskLevelHigh)
+
+	// 相同引擎下未变化的文件沿用上次结论
+	info, err := os.Lstat(risky)
+	if err != nil {
+		t.Fatal(err)
+	}
+	same := newIncrementalRun(index, "run-2", "engine-1", false, nil)
+	prev, change := same.check(risky, info)
+	if prev == nil || change != changeUnchanged || prev.RiskLevel != string(detector.RiskLevelHigh) {
+		t.Fatalf("check with the same engine = %+v, %d; want previous HIGH state", prev, change)
+	}
+
+	// 引擎指纹变化后全部重新扫描，但变化统计仍按文件本身计算
+	upgraded := newIncrementalRun(index, "run-3", "engine-2", false, nil)
+	changes := scanIncremental(t, upgraded, []string{risky, clean}, detector.RiskLevelSafe)
+	if changes[risky] != changeUnchanged || changes[clean] != changeUnchanged {
+		t.Errorf("changes after engine upgrade = %v, want both unchanged", changes)
+	}
+	stats := upgraded.stats()
+	if !stats.FullRescan || stats.Unchanged != 2 || stats.Deleted != 0 {
+		t.Errorf("stats after engine upgrade = %+v, want full rescan of 2 unchanged files", stats)
+	}
+	state, err := index.Get(risky)
+	if err != nil || state == nil {
+		t.Fatalf("risky file missing from index: %v", err)
+	}
+	if state.EngineVersion != "engine-2" || state.RiskLevel != string(detector.RiskLevelSafe) {
+		t.Errorf("indexed state = %s by %s, want SAFE by engine-2", state.RiskLevel, state.EngineVersion)
+	}
+
+	// 重新扫描后的文件在新引擎下再次被跳过
+	rescanned := newIncrementalRun(index, "run-4", "engine-2", false, nil)
+	if prev, _ := rescanned.check(risky, info); prev == nil {
+		t.Error("file rescanned by the new engine was not skipped")
+	}
+	if rescanned.stats().FullRescan {
+		t.Error("full rescan reported although the engine did not change")
+	}
+
+	// 全量扫描不跳过未变化的文件
+	forced := newIncrementalRun(index, "run-5", "engine-2", true, nil)
+	if prev, change := forced.check(risky, info); prev != nil || change != changeUnchanged {
+		t.Errorf("check in a full scan = %+v, %d; want rescan of an unchanged file", prev, change)
+	}
+}
@@ -0,0 +1,368 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"webshell-detector/internal/scanjob"
+)
+
+const (
+	// jobUpdateInterval 刷新任务状态文件和读取控制请求的间隔
+	jobUpdateInterval = time.Second
+	// progressLogInterval 输出扫描进度日志的间隔
+	progressLogInterval = 30 * time.Second
+	// jobRetention 已结束任务的状态文件保留时间
+	jobRetention = 7 * 24 * time.Hour
+)
+
+// activeJobs 本进程中正在运行的扫描任务，供信号处理统一暂停、恢复
+var (
+	activeMu   sync.Mutex
+	activeJobs = make(map[string]*Job)
+)
+
+// Job 一次批量扫描任务：提供进度查询和暂停、恢复、取消操作，
+// 状态定期写入任务目录，其他进程通过 scanjob.RequestControl 发出控制请求
+type Job struct {
+	id      string
+	run     *scanRun
+	targets []string
+	dir     string
+	ctx     context.Context // 取消后遍历停止，未检测的文件被丢弃
+	cancel  context.CancelFunc
+	stop    chan struct{}
+	done    chan struct{}
+	began   time.Time     // 本次运行的开始时间，从断点继续时晚于扫描的开始时间
+	resumed bool          // 是否从断点继续
+	saved   bool          // 被中断后断点已保存，下次可以继续
+	ckpt    *checkpointer // 未启用断点续扫时为 nil
+
+	mu        sync.Mutex
+	status    string
+	cancelled bool          // 通过 Cancel 取消，区别于随扫描器停止而中断
+	resume    chan struct{} // 暂停期间非 nil，恢复时关闭
+	pausedAt  time.Time
+	pausedFor time.Duration
+	endTime   time.Time
+
+	saveFailed bool // 状态文件写入失败时只告警一次，仅由 track 协程访问
+}
+
+// startJob 为批量扫描创建任务并开始定期写入状态和断点，parent 取消时任务中断。
+// ckpt 为 openCheckpoint 的返回值，run 已从断点恢复时沿用原扫描编号
+func (s *BaseScanner) startJob(parent context.Context, run *scanRun, targets []string, ckpt *checkpointer) *Job {
+	ctx, cancel := context.WithCancel(parent)
+	job := &Job{
+		id:      run.id(),
+		run:     run,
+		targets: targets,
+		dir:     s.config.Scan.JobDir,
+		ctx:     ctx,
+		cancel:  cancel,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		began:   time.Now(),
+		resumed: ckpt != nil && ckpt.restored != nil,
+		ckpt:    ckpt,
+		status:  scanjob.StatusRunning,
+	}
+	if job.dir == "" {
+		job.dir = scanjob.DefaultDir
+	}
+	run.job = job
+
+	activeMu.Lock()
+	activeJobs[job.id] = job
+	activeMu.Unlock()
+
+	scanjob.Prune(job.dir, jobRetention)
+	if job.resumed {
+		log.Printf("Scan job %s resumed from checkpoint (pid %d)", job.id, os.Getpid())
+	} else {
+		log.Printf("Scan job %s started (pid %d)", job.id, os.Getpid())
+	}
+	go job.track()
+	return job
+}
+
+// ActiveJobs 返回本进程中正在运行的扫描任务
+func ActiveJobs() []*Job {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	jobs := make([]*Job, 0, len(activeJobs))
+	for _, job := range activeJobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].id < jobs[j].id })
+	return jobs
+}
+
+// ID 任务编号，与扫描历史中的扫描编号相同
+func (j *Job) ID() string {
+	return j.id
+}
+
+// Status 任务状态
+func (j *Job) Status() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// Pause 暂停任务：遍历在提交下一个文件前停下，队列中的文件暂存到恢复后再检测，
+// 不占用其他扫描共用的分析协程；正在检测的文件会完成
+func (j *Job) Pause() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status != scanjob.StatusRunning {
+		return false
+	}
+	j.status = scanjob.StatusPaused
+	j.resume = make(chan struct{})
+	j.pausedAt = time.Now()
+	log.Printf("Scan job %s paused", j.id)
+	return true
+}
+
+// Resume 恢复暂停的任务
+func (j *Job) Resume() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status != scanjob.StatusPaused {
+		return false
+	}
+	j.status = scanjob.StatusRunning
+	j.pausedFor += time.Since(j.pausedAt)
+	close(j.resume)
+	j.resume = nil
+	log.Printf("Scan job %s resumed", j.id)
+	return true
+}
+
+// Cancel 取消任务：遍历停止，队列中未检测的文件被丢弃，已有结果照常记录
+func (j *Job) Cancel() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status != scanjob.StatusRunning && j.status != scanjob.StatusPaused {
+		return false
+	}
+	if j.status == scanjob.StatusPaused {
+		j.pausedFor += time.Since(j.pausedAt)
+		close(j.resume)
+		j.resume = nil
+	}
+	j.status = scanjob.StatusCancelling
+	j.cancelled = true
+	j.cancel()
+	log.Printf("Scan job %s cancelling", j.id)
+	return true
+}
+
+// Cancelled 判断任务是否通过 Cancel 取消
+func (j *Job) Cancelled() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.cancelled
+}
+
+// Interrupted 判断任务是否随扫描器停止而中断，在 finish 之后调用
+func (j *Job) Interrupted() bool {
+	return j.Status() == scanjob.StatusInterrupted
+}
+
+// Resumable 判断被中断的任务是否已保存断点，下次扫描相同范围时可以继续
+func (j *Job) Resumable() bool {
+	return j.saved
+}
+
+// stopped 判断任务是否未完成就结束，在 finish 之后调用
+func (j *Job) stopped() bool {
+	status := j.Status()
+	return status == scanjob.StatusCancelled || status == scanjob.StatusInterrupted
+}
+
+// wait 任务暂停时阻塞到恢复或取消，返回任务是否仍可继续
+func (j *Job) wait() bool {
+	j.mu.Lock()
+	resume := j.resume
+	j.mu.Unlock()
+	if resume != nil {
+		select {
+		case <-resume:
+		case <-j.ctx.Done():
+		}
+	}
+	return j.ctx.Err() == nil
+}
+
+// finish 在扫描结束后调用：记录最终状态，写入最后一次快照和断点并从活动任务中移除
+func (j *Job) finish() {
+	// finish 最后会取消 ctx，需要先判断
+	stopped := j.ctx.Err() != nil
+	j.mu.Lock()
+	if j.status == scanjob.StatusPaused {
+		j.pausedFor += time.Since(j.pausedAt)
+		close(j.resume)
+		j.resume = nil
+	}
+	switch {
+	case j.cancelled:
+		j.status = scanjob.StatusCancelled
+	case stopped:
+		j.status = scanjob.StatusInterrupted
+	default:
+		j.status = scanjob.StatusCompleted
+	}
+	status := j.status
+	j.endTime = time.Now()
+	j.mu.Unlock()
+	j.cancel()
+
+	close(j.stop)
+	<-j.done
+	if j.ckpt != nil {
+		j.saved = j.ckpt.finish(j.run, status == scanjob.StatusInterrupted, j.activeTime())
+	}
+	if j.saved {
+		log.Printf("Scan job %s interrupted, checkpoint saved; scanning the same targets again resumes it", j.id)
+	} else if status != scanjob.StatusCompleted {
+		j.run.warn(fmt.Errorf("scan %s", status))
+	}
+
+	activeMu.Lock()
+	delete(activeJobs, j.id)
+	activeMu.Unlock()
+	log.Printf("Scan job %s %s: %s", j.id, j.Status(), j.Progress())
+}
+
+// Progress 返回当前进度，速率按不含暂停的运行时长计算，遍历结束后按剩余字节数估算剩余时间
+func (j *Job) Progress() scanjob.Progress {
+	r := j.run
+	r.mu.Lock()
+	p := scanjob.Progress{
+		Discovered:      r.discovered,
+		Scanned:         int64(r.summary.TotalFiles),
+		Skipped:         r.skipped,
+		Failed:          int64(r.failed),
+		BytesScanned:    r.bytesScanned,
+		BytesDiscovered: r.bytesDiscovered,
+		WalkDone:        r.walkDone,
+	}
+	r.mu.Unlock()
+
+	p.Elapsed = j.activeTime()
+	if seconds := p.Elapsed.Seconds(); seconds > 0 {
+		p.FilesPerSec = float64(p.Scanned+p.Failed) / seconds
+		p.BytesPerSec = float64(p.BytesScanned) / seconds
+	}
+	status := j.Status()
+	if p.WalkDone && (status == scanjob.StatusRunning || status == scanjob.StatusPaused) {
+		switch {
+		case p.BytesPerSec > 0 && p.BytesDiscovered > p.BytesScanned:
+			p.ETA = time.Duration(float64(p.BytesDiscovered-p.BytesScanned) / p.BytesPerSec * float64(time.Second))
+		case p.FilesPerSec > 0:
+			p.ETA = time.Duration(float64(p.Remaining()) / p.FilesPerSec * float64(time.Second))
+		}
+	}
+	return p
+}
+
+// activeTime 不含暂停时间的运行时长
+func (j *Job) activeTime() time.Duration {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	end := time.Now()
+	if !j.endTime.IsZero() {
+		end = j.endTime
+	}
+	paused := j.pausedFor
+	if j.status == scanjob.StatusPaused {
+		paused += end.Sub(j.pausedAt)
+	}
+	return end.Sub(j.began) - paused + j.run.priorElapsed
+}
+
+// Snapshot 返回任务快照
+func (j *Job) Snapshot() *scanjob.Snapshot {
+	progress := j.Progress()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return &scanjob.Snapshot{
+		ID:        j.id,
+		Type:      j.run.scanType,
+		Name:      j.run.name,
+		PID:       os.Getpid(),
+		Status:    j.status,
+		Resumed:   j.resumed,
+		Targets:   j.targets,
+		StartTime: j.run.startTime,
+		UpdatedAt: time.Now(),
+		EndTime:   j.endTime,
+		Progress:  progress,
+	}
+}
+
+// track 定期写入状态文件、处理控制请求并输出进度日志，任务结束后写入最终状态
+func (j *Job) track() {
+	defer close(j.done)
+	ticker := time.NewTicker(jobUpdateInterval)
+	defer ticker.Stop()
+
+	lastLog := time.Now()
+	j.save()
+	for {
+		select {
+		case <-j.stop:
+			j.save()
+			return
+		case now := <-ticker.C:
+			j.control()
+			j.save()
+			if j.ckpt != nil {
+				j.ckpt.tick(j.run, j.activeTime())
+			}
+			if now.Sub(lastLog) >= progressLogInterval {
+				lastLog = now
+				log.Printf("Scan job %s %s: %s", j.id, j.Status(), j.Progress())
+			}
+		}
+	}
+}
+
+// control 执行其他进程发来的控制请求
+func (j *Job) control() {
+	action, err := scanjob.TakeControl(j.dir, j.id)
+	if err != nil {
+		log.Printf("Warning: Scan job %s: %v", j.id, err)
+		return
+	}
+	switch action {
+	case "":
+	case scanjob.ControlPause:
+		j.Pause()
+	case scanjob.ControlResume:
+		j.Resume()
+	case scanjob.ControlCancel:
+		j.Cancel()
+	default:
+		log.Printf("Warning: Scan job %s: unknown control request %q", j.id, action)
+	}
+}
+
+// save 写入任务快照
+func (j *Job) save() {
+	if err := scanjob.Save(j.dir, j.Snapshot()); err != nil {
+		if !j.saveFailed {
+			log.Printf("Warning: Scan job %s progress will not be visible to the jobs command: %v", j.id, err)
+		}
+		j.saveFailed = true
+		return
+	}
+	j.saveFailed = false
+}
@@ -3,9 +3,10 @@ package scanner
 import (
 	"context"
 	"fmt"
-	"log"
+	"os"
 	"path/filepath"
-	"time"
+	"strings"
+	"sync"
 
 	"webshell-detector/internal/config"
 	"webshell-detector/internal/result"
@@ -13,137 +14,130 @@ import (
 	"webshell-detector/pkg/signature"
 )
 
-// ManualScanner 手动扫描器
+// ManualScanner 手动扫描器，支持多个文件、目录和 glob 模式
 type ManualScanner struct {
 	*BaseScanner
-	filePath string
+	targets []string
+	workers int
+
+	fresh bool // 忽略中断扫描的断点，重新开始
+
+	mu        sync.Mutex
+	interrupt context.CancelFunc // Stop 时中断正在进行的扫描
 }
 
-// NewManualScanner 创建手动扫描器
-func NewManualScanner(cfg *config.Config, sigMgr *signature.Manager, model *mlmodel.Model, filePath string) (*ManualScanner, error) {
+// NewManualScanner 创建手动扫描器，workers 为 0 时使用流水线配置的分析协程数
+func NewManualScanner(cfg *config.Config, sigMgr *signature.Manager, model *mlmodel.Model, targets []string, workers int) (*ManualScanner, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no scan targets specified")
+	}
+
 	baseScanner := NewBaseScanner(cfg, sigMgr, model)
 	return &ManualScanner{
 		BaseScanner: baseScanner,
-		filePath:    filePath,
+		targets:     targets,
+		workers:     workers,
 	}, nil
 }
 
-// Start 开始扫描
+// Start 开始扫描：展开目标后经流水线检测，结束时打印摘要并记录扫描历史
 func (s *ManualScanner) Start() error {
 	if s.isRunning {
 		return fmt.Errorf("scanner is already running")
 	}
 
 	s.isRunning = true
-	startTime := time.Now()
-
-	// 创建上下文
-	ctx := context.Background()
+	defer func() { s.isRunning = false }()
+	defer s.sink.Close()
+
+	// 单个文件时打印完整报告，批量扫描时只打印有风险的文件
+	quiet := true
+	if len(s.targets) == 1 && !hasGlobMeta(s.targets[0]) {
+		if info, err := os.Stat(s.targets[0]); err == nil && info.Mode().IsRegular() {
+			quiet = false
+		}
+	}
 
-	// 执行扫描
-	detectionResult, err := s.detector.Detect(ctx, s.filePath)
-	if err != nil {
-		s.isRunning = false
-		return fmt.Errorf("scan failed: %v", err)
+	run := newScanRun("manual", "")
+	ckpt := s.openCheckpoint(run, scanKey(run.scanType, s.scope()), s.fresh)
+	ctx, interrupt := context.WithCancel(context.Background())
+	defer interrupt()
+	s.mu.Lock()
+	s.interrupt = interrupt
+	s.mu.Unlock()
+
+	job := s.startJob(ctx, run, s.targets, ckpt)
+
+	pipeline := s.newPipeline(s.workers)
+	s.submitTargets(job.ctx, pipeline, run, s.targets, quiet)
+	pipeline.Close()
+	run.wait()
+	job.finish()
+
+	if quiet {
+		result.NewPrinter(true, true).PrintStats(&run.summary)
+		fmt.Printf("Files failed:\t%d\n", run.failed)
 	}
 
-	// 创建结果打印器并打印结果
-	printer := result.NewPrinter(true, true)
-	printer.PrintResult(detectionResult)
-
-	// 创建结果存储器
-	storage, err := result.NewStorage("data/results.db")
-	if err != nil {
-		log.Printf("Warning: Failed to create result storage: %v", err)
-	} else {
-		defer storage.Close()
-		// 存储扫描结果
-		duration := time.Since(startTime)
-		if err := storage.StoreResult(detectionResult, "manual", duration); err != nil {
-			log.Printf("Warning: Failed to store result: %v", err)
+	// 保存了断点的扫描在续扫完成后再记录扫描历史
+	if job.Resumable() {
+		return fmt.Errorf("scan interrupted, run the same command again to resume")
+	}
+	s.recordHistory(run, map[string]interface{}{
+		"targets":      s.targets,
+		"exclude_dirs": s.config.Scan.ExcludeDirs,
+		"file_types":   s.config.Scan.FileTypes,
+		"workers":      s.workers,
+		"resumed":      job.resumed,
+	})
+
+	if job.Cancelled() {
+		return fmt.Errorf("scan cancelled")
+	}
+	if job.Interrupted() {
+		return fmt.Errorf("scan interrupted")
+	}
+	// 续扫时断点之前已完成的文件同样计入，断点已覆盖全部文件时直接成功
+	run.mu.Lock()
+	scanned := run.summary.TotalFiles
+	run.mu.Unlock()
+	if scanned == 0 {
+		if len(run.errors) > 0 {
+			return fmt.Errorf("scan failed: %s", strings.Join(run.errors, "; "))
 		}
+		return fmt.Errorf("no files to scan")
 	}
-
-	s.isRunning = false
 	return nil
 }
 
-// Stop 停止扫描
+// Stop 中断正在进行的扫描，启用断点续扫时保存断点，Start 在已检测的结果输出完毕后返回
 func (s *ManualScanner) Stop() error {
-	if !s.isRunning {
-		return nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.interrupt != nil {
+		s.interrupt()
 	}
-	s.isRunning = false
 	return nil
 }
 
-// // scanDirectory 扫描目录
-// func (s *ManualScanner) scanDirectory(dir string) {
-// 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-// 		if err != nil {
-// 			return err
-// 		}
-
-// 		if info.IsDir() {
-// 			// 检查是否在排除目录列表中
-// 			for _, excludeDir := range s.config.Scan.ExcludeDirs {
-// 				if path == excludeDir {
-// 					return filepath.SkipDir
-// 				}
-// 			}
-// 			return nil
-// 		}
-
-// 		// 检查文件扩展名
-// 		ext := filepath.Ext(path)
-// 		for _, fileType := range s.config.Scan.FileTypes {
-// 			if ext == fileType {
-// 				s.waitGroup.Add(1)
-// 				go func(filePath string) {
-// 					defer s.waitGroup.Done()
-// 					s.workerPool <- struct{}{}        // 获取工作槽
-// 					defer func() { <-s.workerPool }() // 释放工作槽
-
-// 					ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-// 					defer cancel()
-
-// 					if err := s.Scan(ctx, filePath); err != nil {
-// 						log.Printf("Error scanning file %s: %v", filePath, err)
-// 					}
-// 				}(path)
-// 				break
-// 			}
-// 		}
-
-// 		return nil
-// 	})
-
-// 	if err != nil {
-// 		log.Printf("Error walking directory %s: %v", dir, err)
-// 	}
-// }
-
-// scanSingleFile 扫描单个文件
-func (s *ManualScanner) scanSingleFile(filePath string) {
-	// 检查文件扩展名
-	ext := filepath.Ext(filePath)
-	validExt := false
-	for _, fileType := range s.config.Scan.FileTypes {
-		if ext == fileType {
-			validExt = true
-			break
-		}
-	}
+// SetFresh 设置为 true 时忽略相同扫描范围的中断扫描的断点，重新开始
+func (s *ManualScanner) SetFresh(fresh bool) {
+	s.fresh = fresh
+}
 
-	if !validExt {
-		log.Printf("Unsupported file type: %s", filePath)
-		return
+// scope 扫描范围，用于匹配中断扫描的断点
+func (s *ManualScanner) scope() interface{} {
+	targets := make([]string, len(s.targets))
+	for i, target := range s.targets {
+		targets[i] = target
+		if abs, err := filepath.Abs(target); err == nil {
+			targets[i] = abs
+		}
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-
-	if err := s.Scan(ctx, filePath); err != nil {
-		log.Printf("Error scanning file %s: %v", filePath, err)
+	return map[string]interface{}{
+		"targets":      targets,
+		"exclude_dirs": s.config.Scan.ExcludeDirs,
+		"file_types":   s.config.Scan.FileTypes,
+		"max_filesize": s.config.Scan.Schedule.MaxFileSize,
 	}
 }
@@ -0,0 +1,75 @@
+package scanner
+
+import (
+	"time"
+
+	"webshell-detector/internal/detector"
+)
+
+// defaultBatchSize 未配置批处理大小时的默认值
+const defaultBatchSize = 100
+
+// mlBatcher 收集已完成特征匹配和行为分析的文件，攒满一批后统一进行机器学习打分
+type mlBatcher struct {
+	detector *detector.Detector
+	size     int
+	pending  []*batchItem
+	oldest   time.Time
+	handle   func(item *batchItem, duration time.Duration)
+}
+
+// batchItem 等待打分的文件
+type batchItem struct {
+	job       *scanJob
+	analysis  *detector.Analysis
+	startTime time.Time
+}
+
+// newMLBatcher 创建批量打分器，handle 在每个文件得到最终结果后调用
+func newMLBatcher(d *detector.Detector, size int, handle func(*batchItem, time.Duration)) *mlBatcher {
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	return &mlBatcher{
+		detector: d,
+		size:     size,
+		handle:   handle,
+	}
+}
+
+// add 加入一个文件，批次已满时立即打分
+func (b *mlBatcher) add(item *batchItem) {
+	if len(b.pending) == 0 {
+		b.oldest = time.Now()
+	}
+	b.pending = append(b.pending, item)
+	if len(b.pending) >= b.size {
+		b.flush()
+	}
+}
+
+// waited 当前批次中最早加入的文件已等待的时间，批次为空时返回 0
+func (b *mlBatcher) waited() time.Duration {
+	if len(b.pending) == 0 {
+		return 0
+	}
+	return time.Since(b.oldest)
+}
+
+// flush 对当前批次打分并处理结果
+func (b *mlBatcher) flush() {
+	if len(b.pending) == 0 {
+		return
+	}
+
+	analyses := make([]*detector.Analysis, len(b.pending))
+	for i, item := range b.pending {
+		analyses[i] = item.analysis
+	}
+	b.detector.Finalize(analyses)
+
+	for _, item := range b.pending {
+		b.handle(item, time.Since(item.startTime))
+	}
+	b.pending = b.pending[:0]
+}
@@ -0,0 +1,494 @@
+package scanner
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"sync"
+	"time"
+
+	"webshell-detector/internal/config"
+	"webshell-detector/internal/detector"
+	"webshell-detector/internal/filestate"
+	"webshell-detector/internal/result"
+	"webshell-detector/internal/scanjob"
+)
+
+const (
+	defaultPipelineWorkers = 4
+	defaultQueueSize       = 1000
+	defaultResultQueueSize = 100
+
+	// fileScanTimeout 单个文件特征匹配和行为分析的超时时间
+	fileScanTimeout = 5 * time.Minute
+	// batchFlushDelay 未攒满的批次最多等待的时间，避免实时扫描的结果长时间得不到打分
+	batchFlushDelay = 500 * time.Millisecond
+)
+
+// ErrPipelineClosed 流水线已关闭
+var ErrPipelineClosed = fmt.Errorf("scan pipeline is closed")
+
+// scanJob 流水线中的一个待扫描文件
+type scanJob struct {
+	path     string
+	info     fs.FileInfo // 遍历时的文件信息，实时扫描为 nil
+	scanType string
+	run      *scanRun   // 所属的批量扫描，实时扫描为 nil
+	entry    *walkEntry // 在所属批量扫描遍历顺序中的记录
+	quiet    bool       // 只打印有风险的结果
+
+	process *detector.ProcessInfo // 写入文件的进程，监控后端能提供时记录到结果中
+
+	ctx  context.Context                        // 取消后扫描中止且结果被丢弃，用于文件有新版本时放弃旧版本的扫描
+	done func(result *detector.DetectionResult) // 文件处理结束后调用，失败或被取消时 result 为 nil
+}
+
+// cancelled 判断扫描是否已被取消
+func (j *scanJob) cancelled() bool {
+	return j.ctx != nil && j.ctx.Err() != nil
+}
+
+// scanRun 一次批量扫描的进度和统计，明细只保留有风险的文件
+type scanRun struct {
+	scanID      string
+	scanType    string
+	name        string   // 定时扫描的任务名称
+	fileTypes   []string // 扫描的文件类型，为空时使用 scan.file_types
+	startTime   time.Time
+	pending     sync.WaitGroup
+	incremental *incrementalRun // 增量扫描状态，全量扫描为 nil
+	job         *Job            // 进度和暂停、取消控制
+
+	mu      sync.Mutex
+	summary result.Summary
+	risky   []*detector.DetectionResult
+	errors  []string
+	failed  int
+
+	discovered      int64 // 遍历发现的文件数，含增量扫描跳过的文件
+	skipped         int64
+	bytesDiscovered int64 // 需要检测的文件总大小
+	bytesScanned    int64
+	walkDone        bool
+	parked          []*scanJob // 任务暂停期间分析协程取到的文件，恢复后重新入队
+
+	// 断点续扫
+	walking      []*walkEntry    // 按遍历顺序排列的文件，最前面的文件完成后移出
+	position     walkPosition    // 此位置及之前遍历到的文件均已完成，target 为 -1 表示尚无
+	resumeAt     *walkPosition   // 从断点继续时，此位置及之前的文件已在中断前完成
+	resumed      map[string]bool // 从断点继续时，断点位置之后已在中断前完成的文件
+	priorElapsed time.Duration   // 从断点继续时，中断前已运行的时长
+}
+
+// walkEntry 遍历到的一个文件
+type walkEntry struct {
+	pos    walkPosition
+	change fileChange // 增量扫描中文件的变化，完成后计入统计
+	done   bool
+}
+
+// newScanRun 创建批量扫描，name 为定时扫描的任务名称
+func newScanRun(scanType, name string) *scanRun {
+	r := &scanRun{scanType: scanType, name: name, startTime: time.Now(), position: walkPosition{target: -1}}
+	r.scanID = newScanID(scanType, name, r.startTime)
+	return r
+}
+
+// newScanID 生成扫描编号：开始时间只精确到秒，加随机后缀避免同一秒开始的扫描
+// 共用任务状态文件、断点和扫描历史记录
+func newScanID(scanType, name string, start time.Time) string {
+	prefix := scanType
+	if name != "" {
+		prefix += "-" + name
+	}
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Sprintf("%s-%s-%09d", prefix, start.Format("20060102-150405"), start.Nanosecond())
+	}
+	return fmt.Sprintf("%s-%s-%s", prefix, start.Format("20060102-150405"), hex.EncodeToString(suffix))
+}
+
+// id 扫描编号，从断点继续时沿用中断前的编号
+func (r *scanRun) id() string {
+	return r.scanID
+}
+
+// discover 按遍历顺序记录发现的文件。unchanged 非 nil 表示文件未变化而不需要检测，
+// 直接视为完成并沿用其中的上次结论
+func (r *scanRun) discover(pos walkPosition, info fs.FileInfo, change fileChange, unchanged *filestate.State) *walkEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry := &walkEntry{pos: pos, change: change}
+	r.walking = append(r.walking, entry)
+	r.discovered++
+	if unchanged != nil {
+		r.skipped++
+		r.carry(unchanged)
+		r.complete(entry)
+	} else if info != nil {
+		r.bytesDiscovered += info.Size()
+	}
+	return entry
+}
+
+// carry 沿用未变化文件的上次结论：有风险的文件计入统计和风险明细，
+// 避免仍未处置的 webshell 因内容未变而从扫描结果中消失。调用方需持有 r.mu
+func (r *scanRun) carry(prev *filestate.State) {
+	level := detector.RiskLevel(prev.RiskLevel)
+	if !prev.IsWebshell && (level == "" || level == detector.RiskLevelSafe) {
+		return
+	}
+	previous := &detector.DetectionResult{
+		FilePath:    prev.Path,
+		ContentHash: prev.ContentHash,
+		IsWebshell:  prev.IsWebshell,
+		RiskLevel:   level,
+		TotalScore:  prev.TotalScore,
+	}
+	r.summary.Carry(previous)
+	r.risky = append(r.risky, previous)
+	if r.incremental != nil {
+		r.incremental.stillRisky(prev.Path)
+	}
+	log.Printf("Warning: %s is unchanged since %s and still %s (webshell: %v)",
+		prev.Path, prev.ScanTime.Format("2006-01-02 15:04:05"), level, prev.IsWebshell)
+}
+
+// completedBefore 从断点继续时判断文件是否已在中断前完成，已完成的文件只计入发现数
+func (r *scanRun) completedBefore(pos walkPosition) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.resumeAt == nil || (pos.after(*r.resumeAt) && !r.resumed[pos.path]) {
+		return false
+	}
+	r.discovered++
+	return true
+}
+
+// complete 标记文件已完成并推进遍历位置，调用方需持有 r.mu
+func (r *scanRun) complete(entry *walkEntry) {
+	if entry == nil {
+		return
+	}
+	entry.done = true
+	if r.incremental != nil {
+		r.incremental.tally(entry.change)
+	}
+	for len(r.walking) > 0 && r.walking[0].done {
+		r.position = r.walking[0].pos
+		r.walking[0] = nil
+		r.walking = r.walking[1:]
+	}
+}
+
+// walked 记录遍历结束，此后发现的文件总数不再变化
+func (r *scanRun) walked() {
+	r.mu.Lock()
+	r.walkDone = true
+	r.mu.Unlock()
+}
+
+// hold 所属任务暂停时阻塞，直到恢复或取消，返回扫描是否仍可继续
+func (r *scanRun) hold() bool {
+	if r.job == nil {
+		return true
+	}
+	return r.job.wait()
+}
+
+// paused 判断所属任务是否暂停中
+func (r *scanRun) paused() bool {
+	return r.job != nil && r.job.Status() == scanjob.StatusPaused
+}
+
+// park 暂存任务暂停期间分析协程取到的文件，返回是否是第一个暂存的文件
+func (r *scanRun) park(job *scanJob) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.parked = append(r.parked, job)
+	return len(r.parked) == 1
+}
+
+// unpark 取出暂存的文件
+func (r *scanRun) unpark() []*scanJob {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	parked := r.parked
+	r.parked = nil
+	return parked
+}
+
+// add 记录一个检测结果
+func (r *scanRun) add(job *scanJob, detectionResult *detector.DetectionResult) {
+	if r.incremental != nil {
+		r.incremental.record(job, detectionResult)
+	}
+	r.mu.Lock()
+	r.summary.Add(detectionResult)
+	r.complete(job.entry)
+	if job.info != nil {
+		r.bytesScanned += job.info.Size()
+	}
+	if detectionResult.RiskLevel != detector.RiskLevelSafe {
+		r.risky = append(r.risky, detectionResult)
+	}
+	r.mu.Unlock()
+	r.pending.Done()
+}
+
+// fail 记录一个扫描失败的文件
+func (r *scanRun) fail(job *scanJob, err error) {
+	r.mu.Lock()
+	r.failed++
+	r.complete(job.entry)
+	if job.info != nil {
+		r.bytesScanned += job.info.Size()
+	}
+	r.errors = append(r.errors, fmt.Sprintf("%s: %v", job.path, err))
+	r.mu.Unlock()
+	r.pending.Done()
+}
+
+// warn 记录不对应具体文件的错误，如目录遍历失败
+func (r *scanRun) warn(err error) {
+	r.mu.Lock()
+	r.errors = append(r.errors, err.Error())
+	r.mu.Unlock()
+}
+
+// wait 等待已提交的文件全部处理完毕
+func (r *scanRun) wait() {
+	r.pending.Wait()
+}
+
+// Pipeline 扫描流水线：文件经有界队列交给分析协程做特征匹配和行为分析，
+// 再由打分协程按批次做机器学习打分，最终结果统一交给 ResultSink。
+// 队列满时 Submit 阻塞，使遍历和文件事件处理的速度受分析速度约束
+type Pipeline struct {
+	detector  *detector.Detector
+	sink      *ResultSink
+	batchSize int
+
+	files    chan *scanJob
+	analyzed chan *batchItem
+	workers  sync.WaitGroup
+	inflight sync.WaitGroup // 已提交、尚未完成分析的文件，含暂停任务暂存的文件
+	done     chan struct{}
+	closing  chan struct{} // Close 开始时关闭，使阻塞在队列上的 Submit 放弃并释放读锁
+	once     sync.Once
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewPipeline 创建并启动流水线，workers 为 0 时依次使用 scan.pipeline.workers、
+// scan.realtime.max_concurrency 和默认值
+func NewPipeline(cfg *config.Config, d *detector.Detector, sink *ResultSink, workers int) *Pipeline {
+	if workers <= 0 {
+		workers = cfg.Scan.Pipeline.Workers
+	}
+	if workers <= 0 {
+		workers = cfg.Scan.Realtime.MaxConcurrency
+	}
+	if workers <= 0 {
+		workers = defaultPipelineWorkers
+	}
+	queueSize := cfg.Scan.Pipeline.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	resultQueueSize := cfg.Scan.Pipeline.ResultQueueSize
+	if resultQueueSize <= 0 {
+		resultQueueSize = defaultResultQueueSize
+	}
+
+	p := &Pipeline{
+		detector:  d,
+		sink:      sink,
+		batchSize: cfg.Detection.MachineLearning.BatchSize,
+		files:     make(chan *scanJob, queueSize),
+		analyzed:  make(chan *batchItem, resultQueueSize),
+		done:      make(chan struct{}),
+		closing:   make(chan struct{}),
+	}
+
+	for i := 0; i < workers; i++ {
+		p.workers.Add(1)
+		go p.analyze()
+	}
+	go func() {
+		p.workers.Wait()
+		close(p.analyzed)
+	}()
+	go p.collect()
+
+	return p
+}
+
+// Submit 提交一个文件，队列满时阻塞直到有空位、ctx 取消或流水线关闭
+func (p *Pipeline) Submit(ctx context.Context, job *scanJob) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrPipelineClosed
+	}
+
+	if job.run != nil {
+		job.run.pending.Add(1)
+	}
+	p.inflight.Add(1)
+	select {
+	case p.files <- job:
+		return nil
+	case <-ctx.Done():
+		p.inflight.Done()
+		if job.run != nil {
+			job.run.pending.Done()
+		}
+		return ctx.Err()
+	case <-p.closing:
+		p.inflight.Done()
+		if job.run != nil {
+			job.run.pending.Done()
+		}
+		return ErrPipelineClosed
+	}
+}
+
+// Close 停止接收新文件，等待已提交的文件全部处理完毕。
+// 暂停任务暂存的文件在任务恢复后重新入队并检测，或在任务取消、中断后丢弃，之后才关闭队列
+func (p *Pipeline) Close() {
+	p.once.Do(func() {
+		close(p.closing)
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+		// closed 之后不再有新文件计入 inflight
+		p.inflight.Wait()
+		close(p.files)
+	})
+	<-p.done
+}
+
+// analyze 分析协程：特征匹配和行为分析
+func (p *Pipeline) analyze() {
+	defer p.workers.Done()
+	for job := range p.files {
+		// 分析协程由所有扫描共用，不能停下等待暂停的任务恢复，
+		// 否则一个任务暂停会使其他扫描全部停顿；暂停任务的文件暂存到恢复后重新入队
+		if job.run != nil && job.run.paused() {
+			if job.run.park(job) {
+				go p.requeue(job.run)
+			}
+			continue
+		}
+		p.analyzeJob(job)
+		p.inflight.Done()
+	}
+}
+
+// analyzeJob 对一个文件做特征匹配和行为分析，结果交给打分协程
+func (p *Pipeline) analyzeJob(job *scanJob) {
+	if job.cancelled() {
+		p.drop(job)
+		return
+	}
+
+	parent := job.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(parent, fileScanTimeout)
+	startTime := time.Now()
+	analysis, err := p.detector.Analyze(ctx, job.path)
+	cancel()
+	if err != nil {
+		if job.cancelled() {
+			p.drop(job)
+			return
+		}
+		p.sink.Fail(job, err)
+		p.finish(job, nil)
+		return
+	}
+	// 增量扫描索引记录与检测内容对应的文件信息，而不是遍历时的文件信息
+	if job.info != nil && analysis.Info != nil {
+		job.info = analysis.Info
+	}
+	p.analyzed <- &batchItem{job: job, analysis: analysis, startTime: startTime}
+}
+
+// requeue 任务恢复后把暂停期间暂存的文件重新放回队列，任务取消或中断时丢弃。
+// 暂存的文件仍计入 inflight，Close 会等到它们重新入队后才关闭队列
+func (p *Pipeline) requeue(run *scanRun) {
+	resumed := run.hold()
+	for _, job := range run.unpark() {
+		if !resumed || job.cancelled() || !p.enqueue(job) {
+			p.drop(job)
+			p.inflight.Done()
+		}
+	}
+}
+
+// enqueue 把暂存的文件重新放回队列，文件取消时返回 false
+func (p *Pipeline) enqueue(job *scanJob) bool {
+	var cancelled <-chan struct{}
+	if job.ctx != nil {
+		cancelled = job.ctx.Done()
+	}
+	select {
+	case p.files <- job:
+		return true
+	case <-cancelled:
+		return false
+	}
+}
+
+// drop 丢弃已取消的文件
+func (p *Pipeline) drop(job *scanJob) {
+	if job.run != nil {
+		job.run.pending.Done()
+	}
+	p.finish(job, nil)
+}
+
+// finish 通知文件处理结束
+func (p *Pipeline) finish(job *scanJob, result *detector.DetectionResult) {
+	if job.done != nil {
+		job.done(result)
+	}
+}
+
+// collect 打分协程：攒批做机器学习打分，批次未满但等待过久时也会打分
+func (p *Pipeline) collect() {
+	defer close(p.done)
+
+	batcher := newMLBatcher(p.detector, p.batchSize, func(item *batchItem, duration time.Duration) {
+		if item.job.cancelled() {
+			p.drop(item.job)
+			return
+		}
+		p.sink.Handle(item.job, item.analysis.Result, duration)
+		p.finish(item.job, item.analysis.Result)
+	})
+	ticker := time.NewTicker(batchFlushDelay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case item, ok := <-p.analyzed:
+			if !ok {
+				batcher.flush()
+				return
+			}
+			batcher.add(item)
+		case <-ticker.C:
+			if batcher.waited() >= batchFlushDelay {
+				batcher.flush()
+			}
+		}
+	}
+}
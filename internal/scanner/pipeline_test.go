@@ -0,0 +1,182 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"webshell-detector/internal/config"
+	"webshell-detector/internal/detector"
+	"webshell-detector/internal/result"
+	"webshell-detector/internal/scanjob"
+)
+
+// newTestPipeline 创建只做正则匹配、结果不落盘的流水线
+func newTestPipeline(t *testing.T, workers int) (*config.Config, *Pipeline) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Detection.Yara.MaxFileSize = 1 << 20
+	cfg.Scan.JobDir = t.TempDir()
+	sink := &ResultSink{config: cfg, printer: result.NewPrinter(false, false)}
+	return cfg, NewPipeline(cfg, detector.NewDetector(cfg, nil, nil), sink, workers)
+}
+
+// newTestRun 创建带任务控制的批量扫描
+func newTestRun(name string) *scanRun {
+	run := newScanRun("manual", name)
+	ctx, cancel := context.WithCancel(context.Background())
+	run.job = &Job{id: run.id(), run: run, ctx: ctx, cancel: cancel, status: scanjob.StatusRunning}
+	return run
+}
+
+// writeFiles 在临时目录中创建 n 个 PHP 文件
+func writeFiles(t *testing.T, n int) []string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make([]string, n)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("f%d.php", i))
+		if err := os.WriteFile(paths[i], []byte("<?php echo 1;"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+// submitAll 提交 run 的全部文件
+func submitAll(t *testing.T, p *Pipeline, run *scanRun, paths []string) {
+	t.Helper()
+	for _, path := range paths {
+		job := &scanJob{path: path, scanType: run.scanType, run: run, quiet: true, ctx: run.job.ctx}
+		if err := p.Submit(context.Background(), job); err != nil {
+			t.Fatalf("Submit(%s): %v", path, err)
+		}
+	}
+}
+
+// waitRun 等待 run 的文件全部处理完毕，超时返回 false
+func waitRun(run *scanRun, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		run.wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func TestPausedRunDoesNotStallOtherRuns(t *testing.T) {
+	_, p := newTestPipeline(t, 1)
+
+	a, b := newTestRun("a"), newTestRun("b")
+	a.job.Pause()
+	submitAll(t, p, a, writeFiles(t, 5))
+	submitAll(t, p, b, writeFiles(t, 3))
+
+	if !waitRun(b, 10*time.Second) {
+		t.Fatal("run b did not finish while run a was paused")
+	}
+	if got := b.summary.TotalFiles; got != 3 {
+		t.Errorf("run b scanned %d files, want 3", got)
+	}
+	a.mu.Lock()
+	scanned := a.summary.TotalFiles
+	a.mu.Unlock()
+	if scanned != 0 {
+		t.Errorf("paused run a scanned %d files, want 0", scanned)
+	}
+
+	a.job.Resume()
+	if !waitRun(a, 10*time.Second) {
+		t.Fatal("run a did not finish after resume")
+	}
+	if got := a.summary.TotalFiles; got != 5 {
+		t.Errorf("run a scanned %d files after resume, want 5", got)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Close did not return")
+	}
+}
+
+func TestCancelledPausedRunDropsParkedFiles(t *testing.T) {
+	_, p := newTestPipeline(t, 1)
+	defer p.Close()
+
+	run := newTestRun("a")
+	run.job.Pause()
+	submitAll(t, p, run, writeFiles(t, 4))
+
+	run.job.Cancel()
+	if !waitRun(run, 10*time.Second) {
+		t.Fatal("cancelled run did not finish")
+	}
+	if got := run.summary.TotalFiles; got != 0 {
+		t.Errorf("cancelled run scanned %d files, want 0", got)
+	}
+}
+
+func TestCloseWaitsForPausedRun(t *testing.T) {
+	_, p := newTestPipeline(t, 1)
+
+	// 遍历结束后任务暂停，扫描器随即关闭流水线
+	run := newTestRun("a")
+	run.job.Pause()
+	submitAll(t, p, run, writeFiles(t, 5))
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returned while the paused run still had parked files")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	run.job.Resume()
+	select {
+	case <-closed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Close did not return after the run was resumed")
+	}
+	if !waitRun(run, 10*time.Second) {
+		t.Fatal("run did not finish after resume")
+	}
+	if got := run.summary.TotalFiles; got != 5 || run.failed != 0 {
+		t.Errorf("run scanned %d files with %d failures, want 5 scanned", got, run.failed)
+	}
+	if err := p.Submit(context.Background(), &scanJob{path: "x.php"}); err != ErrPipelineClosed {
+		t.Errorf("Submit after Close = %v, want ErrPipelineClosed", err)
+	}
+}
+
+func TestScanIDsDifferWithinSameSecond(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := newScanID("manual", "", start)
+		if seen[id] {
+			t.Fatalf("duplicate scan ID %s", id)
+		}
+		seen[id] = true
+	}
+	if id := newScanID("scheduled", "nightly", start); !strings.HasPrefix(id, "scheduled-nightly-20240501-100000-") {
+		t.Errorf("scan ID = %s, want scheduled-nightly-20240501-100000-<suffix>", id)
+	}
+}
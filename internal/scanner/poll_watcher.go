@@ -0,0 +1,223 @@
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"webshell-detector/internal/filestate"
+)
+
+const defaultPollInterval = 10 * time.Second
+
+// pollWatcher 轮询监控后端：定期遍历目录树，比较大小、修改时间和 inode 快照得到文件变化，
+// 用于 inotify 不可用的 NFS、容器 overlay 等文件系统或 inotify 监控数耗尽时
+type pollWatcher struct {
+	interval  time.Duration
+	excludes  []string
+	fileTypes []string
+	events    chan fileEvent
+	errors    chan error
+	done      chan struct{}
+
+	mu        sync.Mutex
+	roots     map[string]map[string]fileSnapshot // 监控目录 -> 上次快照
+	closeOnce sync.Once
+}
+
+// fileSnapshot 文件在一次轮询时的状态
+type fileSnapshot struct {
+	size    int64
+	modTime time.Time
+	inode   uint64
+}
+
+// newPollWatcher 创建轮询监控后端，只跟踪未排除且符合扫描类型的文件
+func newPollWatcher(interval time.Duration, excludes, fileTypes []string) *pollWatcher {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	w := &pollWatcher{
+		interval:  interval,
+		excludes:  excludes,
+		fileTypes: fileTypes,
+		events:    make(chan fileEvent, 256),
+		errors:    make(chan error, 16),
+		done:      make(chan struct{}),
+		roots:     make(map[string]map[string]fileSnapshot),
+	}
+	go w.poll()
+	return w
+}
+
+// Add 监控目录树，已有文件作为基线快照，不产生事件
+func (w *pollWatcher) Add(path string) error {
+	root := filepath.Clean(path)
+	snapshot, err := w.snapshot(root)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.roots[root]; !ok {
+		w.roots[root] = snapshot
+	}
+	return nil
+}
+
+// Remove 取消该目录及其下所有监控目录树的监控
+func (w *pollWatcher) Remove(path string) error {
+	path = filepath.Clean(path)
+	prefix := path + string(filepath.Separator)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for root := range w.roots {
+		if root == path || strings.HasPrefix(root, prefix) {
+			delete(w.roots, root)
+		}
+	}
+	return nil
+}
+
+func (w *pollWatcher) Recursive() bool          { return true }
+func (w *pollWatcher) Events() <-chan fileEvent { return w.events }
+func (w *pollWatcher) Errors() <-chan error     { return w.errors }
+
+// Close 停止轮询
+func (w *pollWatcher) Close() error {
+	w.closeOnce.Do(func() { close(w.done) })
+	return nil
+}
+
+// poll 按间隔轮询所有监控目录
+func (w *pollWatcher) poll() {
+	defer close(w.events)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		w.mu.Lock()
+		roots := make([]string, 0, len(w.roots))
+		for root := range w.roots {
+			roots = append(roots, root)
+		}
+		w.mu.Unlock()
+
+		for _, root := range roots {
+			if !w.pollRoot(root) {
+				return
+			}
+		}
+	}
+}
+
+// pollRoot 重新遍历一个监控目录并报告与上次快照的差异，停止时返回 false
+func (w *pollWatcher) pollRoot(root string) bool {
+	current, err := w.snapshot(root)
+	gone := false
+	if err != nil {
+		// 目录整体消失时其中的文件都视为已删除，并停止轮询该目录
+		if _, statErr := os.Stat(root); os.IsNotExist(statErr) {
+			gone = true
+		} else {
+			w.reportError(err)
+		}
+		current = map[string]fileSnapshot{}
+	}
+
+	w.mu.Lock()
+	previous, ok := w.roots[root]
+	if ok {
+		if gone {
+			delete(w.roots, root)
+		} else {
+			w.roots[root] = current
+		}
+	}
+	w.mu.Unlock()
+	if !ok {
+		return true
+	}
+
+	var events []fileEvent
+	for path, state := range current {
+		old, existed := previous[path]
+		switch {
+		case !existed:
+			events = append(events, fileEvent{Path: path, Op: opCreate})
+		case old != state:
+			events = append(events, fileEvent{Path: path, Op: opWrite})
+		}
+	}
+	for path := range previous {
+		if _, exists := current[path]; !exists {
+			events = append(events, fileEvent{Path: path, Op: opRemove})
+		}
+	}
+
+	for _, event := range events {
+		select {
+		case w.events <- event:
+		case <-w.done:
+			return false
+		}
+	}
+	return true
+}
+
+// snapshot 遍历目录树，记录符合条件的文件状态
+func (w *pollWatcher) snapshot(root string) (map[string]fileSnapshot, error) {
+	snapshot := make(map[string]fileSnapshot)
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isExcluded(path, w.excludes) {
+			if entry.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !entry.Type().IsRegular() || !matchFileType(path, w.fileTypes) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		snapshot[path] = fileSnapshot{
+			size:    info.Size(),
+			modTime: info.ModTime(),
+			inode:   filestate.Inode(info),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to poll %s: %v", root, err)
+	}
+	return snapshot, nil
+}
+
+// reportError 报告错误，错误通道已满时丢弃
+func (w *pollWatcher) reportError(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
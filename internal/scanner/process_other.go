@@ -0,0 +1,18 @@
+//go:build !unix
+
+package scanner
+
+import "os"
+
+// processAlive 判断进程是否仍在运行；Windows 上进程不存在时 FindProcess 返回错误，其他平台总是视为运行中
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
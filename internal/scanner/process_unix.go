@@ -0,0 +1,14 @@
+//go:build unix
+
+package scanner
+
+import "syscall"
+
+// processAlive 判断进程是否仍在运行
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -3,38 +3,49 @@ package scanner
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
-	"time"
 
 	"webshell-detector/internal/config"
+	"webshell-detector/internal/detector"
+	"webshell-detector/internal/history"
 	"webshell-detector/pkg/mlmodel"
 	"webshell-detector/pkg/signature"
-
-	"github.com/fsnotify/fsnotify"
 )
 
 // RealtimeScanner 实时扫描器
 type RealtimeScanner struct {
 	*BaseScanner
-	watcher    *fsnotify.Watcher
-	workerPool chan struct{}
-	waitGroup  sync.WaitGroup
+	watcher   fileWatcher
+	gate      accessGate      // 支持拦截且启用 block_pending 时非空
+	poller    *pollWatcher    // inotify 监控数耗尽后接管剩余目录的轮询后端，只在 Start 和 watch 协程中创建
+	watched   map[string]bool // 已添加监控的目录，只在 Start 和 watch 协程中访问
+	debouncer *debouncer
+	pipeline  *Pipeline
+	history   *history.Manager // 定期清理过期扫描历史，打开失败时为 nil
+	ctx       context.Context
+	cancel    context.CancelFunc
+	waitGroup sync.WaitGroup
 }
 
 // NewRealtimeScanner 创建实时扫描器
 func NewRealtimeScanner(cfg *config.Config, sigMgr *signature.Manager, mlModel *mlmodel.Model) (*RealtimeScanner, error) {
-	watcher, err := fsnotify.NewWatcher()
+	watcher, err := newFileWatcher(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create file watcher: %v", err)
+		return nil, err
 	}
 
 	scanner := &RealtimeScanner{
 		BaseScanner: NewBaseScanner(cfg, sigMgr, mlModel),
 		watcher:     watcher,
-		workerPool:  make(chan struct{}, cfg.Scan.Realtime.MaxConcurrency),
+		watched:     make(map[string]bool),
+	}
+	if gate, ok := watcher.(accessGate); ok && cfg.Scan.Realtime.BlockPending {
+		scanner.gate = gate
 	}
 
 	return scanner, nil
@@ -52,28 +63,26 @@ func (s *RealtimeScanner) Start() error {
 
 	s.isRunning = true
 
-	// 添加监控目录
+	// 添加监控目录，按挂载点监控的后端只需添加顶层目录
 	for _, dir := range s.config.Scan.Directories {
-		if err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if info.IsDir() {
-				// 检查是否在排除目录列表中
-				for _, excludeDir := range s.config.Scan.ExcludeDirs {
-					if path == excludeDir {
-						return filepath.SkipDir
-					}
-				}
-				return s.watcher.Add(path)
-			}
-			return nil
-		}); err != nil {
+		add := s.watchTree
+		if s.watcher.Recursive() {
+			add = func(dir string, _ bool) error { return s.watcher.Add(dir) }
+		}
+		if err := add(dir, false); err != nil {
+			s.isRunning = false
 			return fmt.Errorf("failed to add directory to watcher: %v", err)
 		}
 	}
 
-	// 启动文件监控
+	// 启动扫描流水线和文件监控
+	s.history = s.startHistoryCleanup()
+	s.pipeline = s.newPipeline(0)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.debouncer = newDebouncer(s.ctx, s.config.Scan.Realtime.QuietPeriod, s.config.Scan.Realtime.MaxDelay, func(job *scanJob) bool {
+		return s.pipeline.Submit(s.ctx, job) == nil
+	}, s.finished)
+	s.waitGroup.Add(1)
 	go s.watch()
 
 	return nil
@@ -86,57 +95,187 @@ func (s *RealtimeScanner) Stop() error {
 	}
 
 	s.isRunning = false
+	s.cancel()
+	err := s.watcher.Close()
 	s.waitGroup.Wait()
-	return s.watcher.Close()
+	if s.poller != nil {
+		s.poller.Close()
+	}
+	s.debouncer.stop()
+	s.pipeline.Close()
+	if s.history != nil {
+		s.history.Close()
+	}
+	if closeErr := s.sink.Close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
 
-// watch 监控文件变化
+// watch 监控文件变化，变化的文件经事件合并后提交到扫描流水线
 func (s *RealtimeScanner) watch() {
+	defer s.waitGroup.Done()
 	for {
+		// 轮询后端可能在处理事件时才创建，每轮重新取其通道
+		var pollEvents <-chan fileEvent
+		var pollErrors <-chan error
+		if s.poller != nil {
+			pollEvents, pollErrors = s.poller.Events(), s.poller.Errors()
+		}
+
 		select {
-		case event, ok := <-s.watcher.Events:
+		case event, ok := <-s.watcher.Events():
 			if !ok {
 				return
 			}
+			s.handleEvent(event)
 
-			// 检查文件操作类型
-			if event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
-				continue
+		case err, ok := <-s.watcher.Errors():
+			if !ok {
+				return
 			}
+			log.Printf("Watcher error: %v", err)
 
-			// 检查文件扩展名
-			ext := filepath.Ext(event.Name)
-			validExt := false
-			for _, fileType := range s.config.Scan.FileTypes {
-				if ext == fileType {
-					validExt = true
-					break
-				}
-			}
-			if !validExt {
-				continue
+		case event, ok := <-pollEvents:
+			if !ok {
+				return
 			}
+			s.handleEvent(event)
 
-			// 启动扫描任务
-			s.waitGroup.Add(1)
-			go func(path string) {
-				defer s.waitGroup.Done()
-				s.workerPool <- struct{}{}        // 获取工作槽
-				defer func() { <-s.workerPool }() // 释放工作槽
+		case err := <-pollErrors:
+			log.Printf("Watcher error: %v", err)
+		}
+	}
+}
 
-				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-				defer cancel()
+// handleEvent 处理一个文件事件。
+// 新建或移入的目录添加监控并扫描其中已有的文件；删除或移出的目录取消监控；
+// 文件的新建、写入、移入和权限变化都会触发扫描
+func (s *RealtimeScanner) handleEvent(event fileEvent) {
+	path := filepath.Clean(event.Path)
+	if isExcluded(path, s.config.Scan.ExcludeDirs) {
+		return
+	}
 
-				if err := s.Scan(ctx, path); err != nil {
-					log.Printf("Error scanning file %s: %v", path, err)
-				}
-			}(event.Name)
+	// 删除或移出（包括被隔离）：原路径已不存在，取消该目录及其子目录的监控和对该文件的拦截
+	if event.Op&(opRemove|opRename) != 0 {
+		if s.watched[path] || s.poller != nil {
+			s.unwatchTree(path)
+		}
+		if s.gate != nil {
+			s.gate.Forget(path)
+		}
+		return
+	}
 
-		case err, ok := <-s.watcher.Errors:
-			if !ok {
-				return
+	if event.Op&(opCreate|opWrite|opChmod) == 0 {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		// 事件到达前文件已被删除或移走
+		return
+	}
+
+	if info.IsDir() {
+		if event.Op&opCreate != 0 && !s.watched[path] && !s.watcher.Recursive() {
+			if err := s.watchTree(path, true); err != nil {
+				log.Printf("Warning: Failed to watch new directory %s: %v", path, err)
 			}
-			log.Printf("Watcher error: %v", err)
 		}
+		return
+	}
+
+	s.enqueue(path, info, event.Process)
+}
+
+// watchTree 为目录及其未排除的子目录添加监控；scanFiles 为 true 时同时扫描其中已有的文件，
+// 用于在监控添加前就已写入新目录的文件
+func (s *RealtimeScanner) watchTree(root string, scanFiles bool) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			log.Printf("Warning: %s: %v", path, err)
+			return nil
+		}
+		if entry.IsDir() {
+			if path != root && isExcluded(path, s.config.Scan.ExcludeDirs) {
+				return filepath.SkipDir
+			}
+			if err := s.watcher.Add(path); err != nil {
+				if isWatchLimit(err) {
+					return s.pollFallback(path, scanFiles)
+				}
+				return fmt.Errorf("%s: %v", path, err)
+			}
+			s.watched[filepath.Clean(path)] = true
+			return nil
+		}
+		if !scanFiles || isExcluded(path, s.config.Scan.ExcludeDirs) {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			s.enqueue(path, info, nil)
+		}
+		return nil
+	})
+}
+
+// pollFallback inotify 监控数耗尽时改为轮询该目录树，返回 filepath.SkipDir 使 watchTree 跳过该目录
+func (s *RealtimeScanner) pollFallback(dir string, scanFiles bool) error {
+	if s.poller == nil {
+		log.Printf("Warning: inotify watch limit reached at %s, polling remaining directories every %v",
+			dir, pollInterval(s.config.Scan.Realtime))
+		s.poller = newPollWatcher(s.config.Scan.Realtime.PollInterval, s.config.Scan.ExcludeDirs, s.config.Scan.FileTypes)
+	}
+	if err := s.poller.Add(dir); err != nil {
+		return err
+	}
+	if scanFiles {
+		for _, err := range s.walkTargets([]string{dir}, nil, func(_ int, path string, info fs.FileInfo) bool {
+			s.enqueue(path, info, nil)
+			return true
+		}) {
+			log.Printf("Warning: %v", err)
+		}
+	}
+	return filepath.SkipDir
+}
+
+// unwatchTree 取消目录及其子目录的监控
+func (s *RealtimeScanner) unwatchTree(root string) {
+	prefix := root + string(filepath.Separator)
+	for dir := range s.watched {
+		if dir == root || strings.HasPrefix(dir, prefix) {
+			// 目录已删除时内核已自动移除监控，忽略错误
+			s.watcher.Remove(dir)
+			delete(s.watched, dir)
+		}
+	}
+	if s.poller != nil {
+		s.poller.Remove(root)
+	}
+}
+
+// enqueue 将符合扫描类型的普通文件交给事件合并器，启用拦截时在结论出来前拦截其他进程打开该文件
+func (s *RealtimeScanner) enqueue(path string, info fs.FileInfo, process *detector.ProcessInfo) {
+	if !info.Mode().IsRegular() || !matchFileType(path, s.config.Scan.FileTypes) {
+		return
+	}
+	if s.gate != nil {
+		if err := s.gate.Hold(path); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+	s.debouncer.touch(path, process)
+}
+
+// finished 文件最新版本的扫描结束（result 为 nil 表示扫描失败或文件已消失），结束拦截
+func (s *RealtimeScanner) finished(path string, result *detector.DetectionResult) {
+	if s.gate != nil {
+		s.gate.Release(path, result != nil && result.IsWebshell)
 	}
 }
@@ -8,12 +8,15 @@ package scanner
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"strings"
 	"time"
 	"webshell-detector/internal/config"
 	"webshell-detector/internal/detector"
-	"webshell-detector/internal/result"
+	"webshell-detector/internal/filestate"
+	"webshell-detector/internal/history"
 	"webshell-detector/pkg/mlmodel"
 	"webshell-detector/pkg/signature"
 )
@@ -30,6 +33,7 @@ type BaseScanner struct {
 	config    *config.Config
 	sigMgr    *signature.Manager
 	detector  *detector.Detector
+	sink      *ResultSink
 	isRunning bool
 }
 
@@ -39,6 +43,7 @@ func NewBaseScanner(cfg *config.Config, sigMgr *signature.Manager, model *mlmode
 		config:    cfg,
 		sigMgr:    sigMgr,
 		detector:  detector.NewDetector(cfg, sigMgr, model),
+		sink:      NewResultSink(cfg),
 		isRunning: false,
 	}
 }
@@ -51,28 +56,13 @@ func (s *BaseScanner) Scan(ctx context.Context, path string) error {
 	}
 
 	// 使用检测器执行扫描
+	startTime := time.Now()
 	detectionResult, err := s.detector.Detect(ctx, path)
 	if err != nil {
 		return fmt.Errorf("detection failed: %v", err)
 	}
 
-	// 创建结果打印器
-	printer := result.NewPrinter(true, true)
-
-	// 打印检测结果
-	printer.PrintResult(detectionResult)
-
-	// 创建结果存储器
-	storage, err := result.NewStorage("data/results.db")
-	if err != nil {
-		log.Printf("Warning: Failed to create result storage: %v", err)
-	} else {
-		defer storage.Close()
-		// 存储扫描结果
-		if err := storage.StoreResult(detectionResult, "manual", time.Since(time.Now())); err != nil {
-			log.Printf("Warning: Failed to store result: %v", err)
-		}
-	}
+	s.sink.Handle(&scanJob{path: path, scanType: "manual"}, detectionResult, time.Since(startTime))
 
 	// 如果检测到 webshell，返回错误
 	if detectionResult.IsWebshell {
@@ -82,6 +72,115 @@ func (s *BaseScanner) Scan(ctx context.Context, path string) error {
 	return nil
 }
 
+// newPipeline 创建使用本扫描器检测器和结果出口的流水线
+func (s *BaseScanner) newPipeline(workers int) *Pipeline {
+	return NewPipeline(s.config, s.detector, s.sink, workers)
+}
+
+// submitTargets 展开扫描目标并逐个提交到流水线，遍历错误记入 run。
+// 任务暂停时遍历停下，ctx 取消后停止遍历，已提交但未检测的文件被丢弃
+func (s *BaseScanner) submitTargets(ctx context.Context, pipeline *Pipeline, run *scanRun, targets []string, quiet bool) {
+	defer run.walked()
+	errs := s.walkTargets(targets, run.fileTypes, func(target int, path string, info fs.FileInfo) bool {
+		if !run.hold() || ctx.Err() != nil {
+			return false
+		}
+		pos := walkPosition{target: target, path: path}
+		if run.completedBefore(pos) {
+			return true
+		}
+		var unchanged *filestate.State
+		change := changeUnknown
+		if run.incremental != nil {
+			unchanged, change = run.incremental.check(path, info)
+		}
+		entry := run.discover(pos, info, change, unchanged)
+		if unchanged != nil {
+			return true
+		}
+		job := &scanJob{path: path, info: info, scanType: run.scanType, run: run, entry: entry, quiet: quiet, ctx: ctx}
+		if err := pipeline.Submit(ctx, job); err != nil {
+			if ctx.Err() == nil {
+				run.warn(fmt.Errorf("%s: %v", path, err))
+			}
+			return false
+		}
+		return true
+	})
+	for _, err := range errs {
+		log.Printf("Warning: %v", err)
+		run.warn(err)
+	}
+}
+
+// defaultHistoryCleanupInterval 实时/定时模式下清理过期扫描历史的默认间隔
+const defaultHistoryCleanupInterval = 24 * time.Hour
+
+// startHistoryCleanup 实时/定时模式下定期按 retention_days 和 max_records 清理扫描历史，
+// 返回的管理器由调用方在停止扫描时关闭；一次性的手动扫描不清理
+func (s *BaseScanner) startHistoryCleanup() *history.Manager {
+	if s.config.Storage.Database.Path == "" {
+		return nil
+	}
+
+	interval := s.config.Storage.History.CleanupInterval
+	if interval <= 0 {
+		interval = defaultHistoryCleanupInterval
+	}
+	manager, err := history.NewManager(history.Config{
+		DBPath:          s.config.Storage.Database.Path,
+		RetentionDays:   s.config.Storage.History.RetentionDays,
+		MaxRecords:      int(s.config.Storage.History.MaxRecords),
+		CleanupInterval: interval,
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to open scan history, old records will not be cleaned up: %v", err)
+		return nil
+	}
+	return manager
+}
+
+// recordHistory 将批量扫描的汇总写入扫描历史，明细只保留有风险的文件
+func (s *BaseScanner) recordHistory(run *scanRun, scanConfig map[string]interface{}) {
+	if s.config.Storage.Database.Path == "" {
+		return
+	}
+
+	manager, err := history.NewManager(history.Config{
+		DBPath:        s.config.Storage.Database.Path,
+		RetentionDays: s.config.Storage.History.RetentionDays,
+		MaxRecords:    int(s.config.Storage.History.MaxRecords),
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to open scan history: %v", err)
+		return
+	}
+	defer manager.Close()
+
+	run.mu.Lock()
+	record := &history.ScanRecord{
+		ScanID:          run.id(),
+		ScanType:        run.scanType,
+		StartTime:       run.startTime,
+		EndTime:         time.Now(),
+		TotalFiles:      run.summary.TotalFiles,
+		WebshellCount:   run.summary.Webshells,
+		HighRiskCount:   run.summary.HighRisk,
+		MediumRiskCount: run.summary.MediumRisk,
+		LowRiskCount:    run.summary.LowRisk,
+		ScanResults:     run.risky,
+		ScanConfig:      scanConfig,
+	}
+	if len(run.errors) > 0 {
+		record.ErrorMessage = strings.Join(run.errors, "\n")
+	}
+	run.mu.Unlock()
+
+	if err := manager.RecordScan(record); err != nil {
+		log.Printf("Warning: Failed to record scan history: %v", err)
+	}
+}
+
 // Stop 停止扫描
 func (s *BaseScanner) Stop() error {
 	if !s.isRunning {
@@ -4,29 +4,54 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
+	"math/rand"
 	"sync"
 	"time"
 
 	"webshell-detector/internal/config"
+	"webshell-detector/internal/filestate"
+	"webshell-detector/internal/history"
+	"webshell-detector/internal/schedule"
 	"webshell-detector/pkg/mlmodel"
 	"webshell-detector/pkg/signature"
 )
 
-// ScheduledScanner 定时扫描器
+// 定时扫描深度
+const (
+	depthQuick = "quick" // 跳过上次扫描后未变化的文件
+	depthFull  = "full"  // 重新扫描全部文件
+)
+
+// 任务运行状态
+const (
+	jobCompleted   = "completed"
+	jobCancelled   = "cancelled"   // 被 jobs cancel 取消，不补跑
+	jobInterrupted = "interrupted" // 扫描器停止时未完成，重启后补跑
+)
+
+// ScheduledScanner 定时扫描器，按 cron 表达式运行多个扫描任务
 type ScheduledScanner struct {
 	*BaseScanner
-	ticker     *time.Ticker
-	workerPool chan struct{}
-	waitGroup  sync.WaitGroup
+	jobs      []*scheduledJob
+	store     *schedule.Store // 各任务上次运行时间
+	pipeline  *Pipeline
+	index     *filestate.Index // 增量扫描的文件状态索引，未启用时为 nil
+	history   *history.Manager // 定期清理过期扫描历史，打开失败时为 nil
+	ctx       context.Context
+	cancel    context.CancelFunc
+	waitGroup sync.WaitGroup
+}
+
+// scheduledJob 一个已解析的定时扫描任务
+type scheduledJob struct {
+	config.ScheduleJob
+	schedule schedule.Schedule
 }
 
 // NewScheduledScanner 创建定时扫描器
 func NewScheduledScanner(cfg *config.Config, sigMgr *signature.Manager, mlModel *mlmodel.Model) (*ScheduledScanner, error) {
 	scanner := &ScheduledScanner{
 		BaseScanner: NewBaseScanner(cfg, sigMgr, mlModel),
-		workerPool:  make(chan struct{}, cfg.Scan.Realtime.MaxConcurrency),
 	}
 	return scanner, nil
 }
@@ -41,114 +66,252 @@ func (s *ScheduledScanner) Start() error {
 		return fmt.Errorf("scanner is already running")
 	}
 
+	jobs, err := s.loadJobs()
+	if err != nil {
+		return err
+	}
+	store, err := schedule.OpenStore(s.config.Scan.Schedule.StatePath)
+	if err != nil {
+		return err
+	}
+	s.jobs = jobs
+	s.store = store
 	s.isRunning = true
 
-	// 解析首次扫描时间
-	startTime, err := time.Parse("15:04", s.config.Scan.Schedule.StartTime)
-	if err != nil {
-		return fmt.Errorf("invalid start time format: %v", err)
+	// 有 quick 任务时打开文件状态索引，失败时退回全量扫描
+	for _, job := range jobs {
+		if job.Depth != depthQuick {
+			continue
+		}
+		indexPath := s.config.Scan.Schedule.StateIndex
+		if indexPath == "" {
+			indexPath = filestate.DefaultIndexPath
+		}
+		index, err := filestate.NewIndex(indexPath)
+		if err != nil {
+			log.Printf("Warning: Failed to open file state index, falling back to full scans: %v", err)
+		} else {
+			s.index = index
+		}
+		break
 	}
 
-	// 计算首次扫描的延迟时间
-	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), startTime.Hour(), startTime.Minute(), 0, 0, now.Location())
-	if today.Before(now) {
-		today = today.Add(24 * time.Hour)
+	// 创建扫描流水线，每个任务一个调度协程
+	s.history = s.startHistoryCleanup()
+	s.pipeline = s.newPipeline(0)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	for _, job := range jobs {
+		s.waitGroup.Add(1)
+		go s.runJob(job)
 	}
-	delay := today.Sub(now)
 
-	// 创建定时器
-	s.ticker = time.NewTicker(s.config.Scan.Schedule.Interval)
+	return nil
+}
 
-	// 启动定时扫描
-	go func() {
-		// 等待首次扫描时间
-		time.Sleep(delay)
+// loadJobs 解析配置的扫描任务，未配置任务时按 start_time 和 interval 生成 default 任务
+func (s *ScheduledScanner) loadJobs() ([]*scheduledJob, error) {
+	cfg := s.config.Scan.Schedule
+	defaultDepth := depthFull
+	if cfg.Incremental {
+		defaultDepth = depthQuick
+	}
 
-		// 执行首次扫描
-		s.scanAll()
+	if len(cfg.Jobs) == 0 {
+		sched, err := schedule.Daily(cfg.StartTime, cfg.Interval)
+		if err != nil {
+			return nil, err
+		}
+		return []*scheduledJob{{
+			ScheduleJob: config.ScheduleJob{
+				Name:        "default",
+				Directories: s.config.Scan.Directories,
+				FileTypes:   s.config.Scan.FileTypes,
+				Depth:       defaultDepth,
+			},
+			schedule: sched,
+		}}, nil
+	}
 
-		// 按间隔执行后续扫描
-		for range s.ticker.C {
-			if !s.isRunning {
-				return
+	jobs := make([]*scheduledJob, 0, len(cfg.Jobs))
+	for _, jobConfig := range cfg.Jobs {
+		sched, err := schedule.Parse(jobConfig.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("schedule job %s: %v", jobConfig.Name, err)
+		}
+		job := &scheduledJob{ScheduleJob: jobConfig, schedule: sched}
+		if len(job.Directories) == 0 {
+			job.Directories = s.config.Scan.Directories
+		}
+		if len(job.FileTypes) == 0 {
+			job.FileTypes = s.config.Scan.FileTypes
+		}
+		if job.Depth == "" {
+			job.Depth = defaultDepth
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// runJob 任务的调度循环：启动时补跑停机期间错过的运行，之后按计划运行；
+// 一次运行结束后才计算下一次运行时间，运行时间超过计划间隔时跳过错过的运行而不是重叠执行
+func (s *ScheduledScanner) runJob(job *scheduledJob) {
+	defer s.waitGroup.Done()
+
+	now := time.Now()
+	next := job.schedule.Next(now)
+	if last := s.store.Get(job.Name).LastRun; !last.IsZero() {
+		next = job.schedule.Next(last)
+		if !next.IsZero() && next.Before(now) {
+			if job.SkipMissed {
+				log.Printf("Job %s missed its run at %s, skipping (skip_missed)", job.Name, next.Format("2006-01-02 15:04"))
+				next = job.schedule.Next(now)
+			} else {
+				log.Printf("Job %s missed its run at %s, catching up now", job.Name, next.Format("2006-01-02 15:04"))
+				next = now
 			}
-			s.scanAll()
 		}
-	}()
+	}
 
-	return nil
+	for {
+		if next.IsZero() {
+			log.Printf("Job %s has no future runs, stopping", job.Name)
+			return
+		}
+		delay := time.Until(next)
+		if job.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(job.Jitter)))
+		}
+		log.Printf("Job %s: next run at %s", job.Name, time.Now().Add(delay).Format("2006-01-02 15:04:05"))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		started := s.execute(job)
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		// 运行期间错过的计划时间不再补跑
+		now := time.Now()
+		next = job.schedule.Next(started)
+		skipped := 0
+		for !next.IsZero() && !next.After(now) && skipped < 1000 {
+			skipped++
+			next = job.schedule.Next(next)
+		}
+		if skipped > 0 {
+			log.Printf("Job %s ran for %v, longer than its schedule; skipped %d overlapping run(s)",
+				job.Name, now.Sub(started).Round(time.Second), skipped)
+			next = job.schedule.Next(now)
+		}
+	}
 }
 
-// Stop 停止定时扫描
+// execute 运行一次任务并保存运行时间，返回开始运行的时间。
+// 不同任务可以同时运行，共用扫描流水线，由流水线的分析协程数限制并发；
+// 暂停或取消一个任务只停止该任务的文件，不占用共用的分析协程，其他任务照常运行
+func (s *ScheduledScanner) execute(job *scheduledJob) time.Time {
+	started := time.Now()
+	if s.ctx.Err() != nil {
+		return started
+	}
+	log.Printf("Job %s: starting %s scan of %v", job.Name, job.Depth, job.Directories)
+	cancelled := s.scanAll(job)
+
+	state := s.store.Get(job.Name)
+	state.LastFinish = time.Now()
+	switch {
+	case s.ctx.Err() != nil:
+		// 被中断的运行不计入，重启后补跑
+		state.LastStatus = jobInterrupted
+	case cancelled:
+		state.LastStatus = jobCancelled
+		state.LastRun = started
+	default:
+		state.LastStatus = jobCompleted
+		state.LastRun = started
+	}
+	if err := s.store.Put(job.Name, state); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return started
+}
+
+// Stop 停止定时扫描，正在进行的扫描被取消，已完成检测的结果记录完毕后返回
 func (s *ScheduledScanner) Stop() error {
 	if !s.isRunning {
 		return nil
 	}
 
 	s.isRunning = false
-	if s.ticker != nil {
-		s.ticker.Stop()
-	}
+	s.cancel()
 	s.waitGroup.Wait()
-	return nil
-}
-
-// scanAll 扫描所有配置的目录
-func (s *ScheduledScanner) scanAll() {
-	for _, dir := range s.config.Scan.Directories {
-		s.scanDirectory(dir)
+	s.pipeline.Close()
+	if s.index != nil {
+		s.index.Close()
+	}
+	if s.history != nil {
+		s.history.Close()
 	}
+	return s.sink.Close()
 }
 
-// scanDirectory 扫描指定目录
-func (s *ScheduledScanner) scanDirectory(dir string) {
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+// scanAll 扫描任务的目录，quick 任务跳过未变化的文件，完成后记录扫描历史，返回扫描是否被取消
+func (s *ScheduledScanner) scanAll(job *scheduledJob) bool {
+	run := newScanRun("scheduled", job.Name)
+	run.fileTypes = job.FileTypes
+	ckpt := s.openCheckpoint(run, scanKey(run.scanType, map[string]interface{}{
+		"job":          job.Name,
+		"directories":  job.Directories,
+		"file_types":   job.FileTypes,
+		"depth":        job.Depth,
+		"exclude_dirs": s.config.Scan.ExcludeDirs,
+	}), false)
+	if s.index != nil {
+		// 只有本任务覆盖的目录和文件类型中未出现的文件才计为已删除
+		scope := func(path string) bool {
+			return isExcluded(path, job.Directories) && matchFileType(path, job.FileTypes)
 		}
+		run.incremental = newIncrementalRun(s.index, run.id(), s.detector.Fingerprint(), job.Depth == depthFull, scope)
+		ckpt.restoreIncremental(run)
+	}
+	scan := s.startJob(s.ctx, run, job.Directories, ckpt)
+	s.submitTargets(scan.ctx, s.pipeline, run, job.Directories, false)
+	run.wait()
+	scan.finish()
 
-		// 检查是否是目录
-		if info.IsDir() {
-			// 检查是否在排除目录列表中
-			for _, excludeDir := range s.config.Scan.ExcludeDirs {
-				if path == excludeDir {
-					return filepath.SkipDir
-				}
-			}
-			return nil
+	scanConfig := map[string]interface{}{
+		"job":          job.Name,
+		"depth":        job.Depth,
+		"directories":  job.Directories,
+		"exclude_dirs": s.config.Scan.ExcludeDirs,
+		"file_types":   job.FileTypes,
+	}
+	if run.incremental != nil {
+		run.incremental.finish(scan.stopped())
+		stats := run.incremental.stats()
+		if stats.FullRescan {
+			log.Printf("Detection engine changed since last scan, all files rescanned")
 		}
-
-		// 检查文件大小
-		if info.Size() > s.config.Scan.Schedule.MaxFileSize {
-			return nil
+		log.Printf("Job %s: %d new, %d modified, %d deleted, %d unchanged",
+			job.Name, stats.New, stats.Modified, stats.Deleted, stats.Unchanged)
+		if len(stats.StillRisky) > 0 {
+			log.Printf("Job %s: %d unchanged file(s) still flagged by earlier scans, counted in this scan's results",
+				job.Name, len(stats.StillRisky))
 		}
+		scanConfig["incremental"] = stats
+	}
 
-		// 检查文件扩展名
-		ext := filepath.Ext(path)
-		for _, fileType := range s.config.Scan.FileTypes {
-			if ext == fileType {
-				s.waitGroup.Add(1)
-				go func(filePath string) {
-					defer s.waitGroup.Done()
-					s.workerPool <- struct{}{}        // 获取工作槽
-					defer func() { <-s.workerPool }() // 释放工作槽
-
-					ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-					defer cancel()
-
-					if err := s.Scan(ctx, filePath); err != nil {
-						log.Printf("Error scanning file %s: %v", filePath, err)
-					}
-				}(path)
-				break
-			}
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		log.Printf("Error walking directory %s: %v", dir, err)
+	// 保存了断点的扫描在续扫完成后再记录扫描历史
+	if !scan.Resumable() {
+		scanConfig["resumed"] = scan.resumed
+		s.recordHistory(run, scanConfig)
 	}
+	return scan.Cancelled()
 }
@@ -0,0 +1,90 @@
+package scanner
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"webshell-detector/internal/config"
+	"webshell-detector/internal/detector"
+	"webshell-detector/internal/result"
+	"webshell-detector/internal/schedule"
+)
+
+func TestPausedScheduledJobDoesNotBlockOtherJobs(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Detection.Yara.MaxFileSize = 1 << 20
+	cfg.Scan.JobDir = t.TempDir()
+	store, err := schedule.OpenStore(filepath.Join(t.TempDir(), "schedule.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := &BaseScanner{
+		config:   cfg,
+		detector: detector.NewDetector(cfg, nil, nil),
+		sink:     &ResultSink{config: cfg, printer: result.NewPrinter(false, false)},
+	}
+	s := &ScheduledScanner{BaseScanner: base, store: store}
+	s.pipeline = s.newPipeline(1)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	defer s.cancel()
+
+	newJob := func(name string, files int) *scheduledJob {
+		dir := filepath.Dir(writeFiles(t, files)[0])
+		return &scheduledJob{ScheduleJob: config.ScheduleJob{
+			Name:        name,
+			Directories: []string{dir},
+			FileTypes:   []string{".php"},
+			Depth:       depthFull,
+		}}
+	}
+	slow, fast := newJob("slow", 500), newJob("fast", 3)
+
+	slowDone := make(chan struct{})
+	go func() {
+		s.execute(slow)
+		close(slowDone)
+	}()
+	var paused *Job
+	for deadline := time.Now().Add(10 * time.Second); paused == nil && time.Now().Before(deadline); {
+		for _, job := range ActiveJobs() {
+			if job.run.name == slow.Name && job.Pause() {
+				paused = job
+			}
+		}
+	}
+	if paused == nil {
+		t.Fatal("slow job was not paused")
+	}
+
+	fastDone := make(chan struct{})
+	go func() {
+		s.execute(fast)
+		close(fastDone)
+	}()
+	select {
+	case <-fastDone:
+	case <-time.After(10 * time.Second):
+		t.Fatal("fast job did not finish while the slow job was paused")
+	}
+	if got := store.Get(fast.Name).LastStatus; got != jobCompleted {
+		t.Errorf("fast job status = %q, want %q", got, jobCompleted)
+	}
+	select {
+	case <-slowDone:
+		t.Fatal("paused job finished before it was resumed")
+	default:
+	}
+
+	paused.Resume()
+	select {
+	case <-slowDone:
+	case <-time.After(60 * time.Second):
+		t.Fatal("slow job did not finish after resume")
+	}
+	if got := store.Get(slow.Name).LastStatus; got != jobCompleted {
+		t.Errorf("slow job status = %q, want %q", got, jobCompleted)
+	}
+	s.pipeline.Close()
+}
@@ -0,0 +1,186 @@
+package scanner
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"webshell-detector/internal/alert"
+	"webshell-detector/internal/config"
+	"webshell-detector/internal/detector"
+	"webshell-detector/internal/response"
+	"webshell-detector/internal/result"
+)
+
+const (
+	defaultAlertWorkers   = 2
+	defaultAlertQueueSize = 100
+	defaultAlertRateLimit = time.Hour
+)
+
+// ResultSink 流水线的结果出口，统一负责打印、存储、自动响应和告警
+type ResultSink struct {
+	config    *config.Config
+	printer   *result.Printer
+	storage   *result.Storage
+	alerts    *alert.Manager
+	responder *response.Engine // 配置了响应策略或自动隔离时非空
+
+	printMu    sync.Mutex
+	alertQueue chan *detector.DetectionResult
+	alertWG    sync.WaitGroup
+	mu         sync.RWMutex
+	closed     bool
+}
+
+// NewResultSink 创建结果出口：打开结果数据库，注册已启用的告警方式并启动告警协程，按策略创建响应引擎
+func NewResultSink(cfg *config.Config) *ResultSink {
+	sink := &ResultSink{
+		config:  cfg,
+		printer: result.NewPrinter(true, true),
+	}
+
+	storage, err := result.NewStorage(result.DefaultDBPath)
+	if err != nil {
+		log.Printf("Warning: Failed to create result storage: %v", err)
+	} else {
+		sink.storage = storage
+	}
+
+	if cfg.Alert.Email.Enabled || cfg.Alert.SMS.Enabled {
+		rateLimit := cfg.Alert.RateLimit
+		if rateLimit <= 0 {
+			rateLimit = defaultAlertRateLimit
+		}
+		sink.alerts = alert.NewManager(rateLimit)
+		if cfg.Alert.Email.Enabled {
+			sink.alerts.RegisterAlert(alert.AlertTypeEmail, alert.NewEmailAlert(cfg.Alert.Email))
+		}
+		if cfg.Alert.SMS.Enabled {
+			sink.alerts.RegisterAlert(alert.AlertTypeSMS, alert.NewSMSAlert(cfg.Alert.SMS))
+		}
+
+		workers := cfg.Scan.Pipeline.AlertWorkers
+		if workers <= 0 {
+			workers = defaultAlertWorkers
+		}
+		queueSize := cfg.Scan.Pipeline.AlertQueueSize
+		if queueSize <= 0 {
+			queueSize = defaultAlertQueueSize
+		}
+		sink.alertQueue = make(chan *detector.DetectionResult, queueSize)
+		for i := 0; i < workers; i++ {
+			sink.alertWG.Add(1)
+			go sink.sendAlerts()
+		}
+	}
+
+	responder, err := response.NewEngine(cfg, sink.storage, sink.enqueueAlert)
+	if err != nil {
+		log.Printf("Warning: Failed to create response engine, automatic response disabled: %v", err)
+	} else {
+		sink.responder = responder
+	}
+
+	return sink
+}
+
+// Handle 处理一个最终检测结果：计入所属扫描，按需打印，存储，执行响应策略，没有生效的规则时在达到阈值时告警
+func (s *ResultSink) Handle(job *scanJob, detectionResult *detector.DetectionResult, duration time.Duration) {
+	if job.process != nil {
+		detectionResult.Process = job.process
+	}
+
+	if !job.quiet || detectionResult.RiskLevel != detector.RiskLevelSafe {
+		s.printMu.Lock()
+		s.printer.PrintResult(detectionResult)
+		s.printMu.Unlock()
+	}
+
+	s.mu.RLock()
+	if !s.closed && s.storage != nil {
+		if err := s.storage.StoreResult(detectionResult, job.scanType, duration); err != nil {
+			log.Printf("Warning: Failed to store result: %v", err)
+		}
+	}
+	// 匹配响应规则的结果由规则中的 alert 动作告警，其余结果按阈值告警
+	handled := false
+	if !s.closed && s.responder != nil {
+		handled = s.responder.Respond(job.scanType, detectionResult)
+	}
+	if !s.closed && !handled && s.shouldAlert(detectionResult) {
+		s.enqueueAlert(detectionResult)
+	}
+	s.mu.RUnlock()
+
+	if job.run != nil {
+		job.run.add(job, detectionResult)
+	}
+}
+
+// Fail 记录一个扫描失败的文件
+func (s *ResultSink) Fail(job *scanJob, err error) {
+	log.Printf("Error scanning file %s: %v", job.path, err)
+	if job.run != nil {
+		job.run.fail(job, err)
+	}
+}
+
+// enqueueAlert 将结果加入告警队列，没有启用告警或队列已满时返回 false；调用方需持有 s.mu 读锁
+func (s *ResultSink) enqueueAlert(detectionResult *detector.DetectionResult) bool {
+	if s.alertQueue == nil || s.closed {
+		return false
+	}
+	select {
+	case s.alertQueue <- detectionResult:
+		return true
+	default:
+		log.Printf("Warning: Alert queue full, dropping alert for %s", detectionResult.FilePath)
+		return false
+	}
+}
+
+// shouldAlert 判定为 webshell 或总分达到高风险阈值时告警
+func (s *ResultSink) shouldAlert(detectionResult *detector.DetectionResult) bool {
+	if s.alertQueue == nil {
+		return false
+	}
+	return detectionResult.IsWebshell || detectionResult.TotalScore >= s.config.Alert.Threshold.HighRisk
+}
+
+// sendAlerts 告警协程，逐个发送队列中的告警
+func (s *ResultSink) sendAlerts() {
+	defer s.alertWG.Done()
+	for detectionResult := range s.alertQueue {
+		if err := s.alerts.SendAlert(detectionResult); err != nil {
+			log.Printf("Warning: Failed to send alert for %s: %v", detectionResult.FilePath, err)
+		}
+	}
+}
+
+// Close 等待队列中的告警发送完毕并关闭结果数据库和隔离区，之后的结果只打印不再存储、响应和告警
+func (s *ResultSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	storage := s.storage
+	s.mu.Unlock()
+
+	if s.responder != nil {
+		if err := s.responder.Close(); err != nil {
+			log.Printf("Warning: Failed to close response engine: %v", err)
+		}
+	}
+
+	if s.alertQueue != nil {
+		close(s.alertQueue)
+		s.alertWG.Wait()
+	}
+	if storage != nil {
+		return storage.Close()
+	}
+	return nil
+}
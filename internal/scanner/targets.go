@@ -0,0 +1,179 @@
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isExcluded 判断路径是否被排除：与排除项相同、位于排除目录下，或匹配排除项中的 glob 模式
+func isExcluded(path string, excludes []string) bool {
+	cleaned := filepath.Clean(path)
+	for _, exclude := range excludes {
+		if exclude == "" {
+			continue
+		}
+		pattern := filepath.Clean(exclude)
+		if cleaned == pattern || strings.HasPrefix(cleaned, pattern+string(filepath.Separator)) {
+			return true
+		}
+		if matched, _ := filepath.Match(pattern, cleaned); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(pattern, filepath.Base(cleaned)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFileType 检查文件扩展名是否在配置的扫描类型中，未配置类型时全部匹配
+func matchFileType(path string, fileTypes []string) bool {
+	if len(fileTypes) == 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, fileType := range fileTypes {
+		if ext == strings.ToLower(fileType) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasGlobMeta 判断目标是否为 glob 模式
+func hasGlobMeta(target string) bool {
+	return strings.ContainsAny(target, "*?[")
+}
+
+// errStopWalk visit 要求停止遍历
+var errStopWalk = fmt.Errorf("stop walking")
+
+// walkTargets 将文件、目录和 glob 模式展开为待扫描文件，按发现顺序交给 visit，
+// 同一文件只访问一次；目录和 glob 匹配到的文件按排除项、文件类型和大小过滤，
+// 直接指定的文件只检查排除项。fileTypes 为空时使用 scan.file_types。visit 收到文件所属目标的序号，返回 false 时停止遍历。
+// 同一目标中的文件按路径逐级的字典序访问，见 walkPosition。返回各目标遇到的错误
+func (s *BaseScanner) walkTargets(targets, fileTypes []string, visit func(target int, path string, info fs.FileInfo) bool) []error {
+	var errs []error
+	seen := make(map[string]bool)
+	maxSize := s.config.Scan.Schedule.MaxFileSize
+	if len(fileTypes) == 0 {
+		fileTypes = s.config.Scan.FileTypes
+	}
+	stopped := false
+	current := 0
+
+	emit := func(path string, info fs.FileInfo, explicit bool) {
+		if !info.Mode().IsRegular() || isExcluded(path, s.config.Scan.ExcludeDirs) {
+			return
+		}
+		if !explicit {
+			if !matchFileType(path, fileTypes) || (maxSize > 0 && info.Size() > maxSize) {
+				return
+			}
+		}
+		key := path
+		if abs, err := filepath.Abs(path); err == nil {
+			key = abs
+		}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		if !visit(current, path, info) {
+			stopped = true
+		}
+	}
+
+	walkDir := func(dir string) {
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", path, err))
+				if entry != nil && entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if entry.IsDir() {
+				if path != dir && isExcluded(path, s.config.Scan.ExcludeDirs) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", path, err))
+				return nil
+			}
+			emit(path, info, false)
+			if stopped {
+				return errStopWalk
+			}
+			return nil
+		})
+		if err != nil && err != errStopWalk {
+			errs = append(errs, fmt.Errorf("failed to walk %s: %v", dir, err))
+		}
+	}
+
+	for i, target := range targets {
+		current = i
+		paths := []string{target}
+		explicit := true
+		if hasGlobMeta(target) {
+			matches, err := filepath.Glob(target)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid pattern %s: %v", target, err))
+				continue
+			}
+			if len(matches) == 0 {
+				errs = append(errs, fmt.Errorf("no files match %s", target))
+				continue
+			}
+			paths = matches
+			explicit = false
+		}
+
+		for _, path := range paths {
+			if stopped {
+				return errs
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("file not found: %v", err))
+				continue
+			}
+			if info.IsDir() {
+				walkDir(path)
+			} else {
+				emit(path, info, explicit)
+			}
+		}
+	}
+
+	return errs
+}
+
+// walkPosition 批量扫描的遍历位置：目标序号和路径
+type walkPosition struct {
+	target int
+	path   string
+}
+
+// after 判断 p 是否在 q 之后被遍历。WalkDir 和 Glob 都按目录逐级排序，
+// 所以同一目标中按路径分量逐个比较，而不是直接比较整个字符串（"a/b" 在 "a-c" 之前）
+func (p walkPosition) after(q walkPosition) bool {
+	if p.target != q.target {
+		return p.target > q.target
+	}
+	a := strings.Split(filepath.Clean(p.path), string(filepath.Separator))
+	b := strings.Split(filepath.Clean(q.path), string(filepath.Separator))
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] > b[i]
+		}
+	}
+	return len(a) > len(b)
+}
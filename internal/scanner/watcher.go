@@ -0,0 +1,355 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"webshell-detector/internal/config"
+	"webshell-detector/internal/detector"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// 实时监控后端
+const (
+	backendInotify  = "inotify"
+	backendFanotify = "fanotify"
+	backendPoll     = "poll"
+)
+
+// eventOp 文件事件类型
+type eventOp uint32
+
+const (
+	opCreate eventOp = 1 << iota
+	opWrite
+	opRemove
+	opRename
+	opChmod
+)
+
+// fileEvent 监控后端报告的文件事件
+type fileEvent struct {
+	Path    string
+	Op      eventOp
+	Process *detector.ProcessInfo // 触发事件的进程，后端不支持时为 nil
+}
+
+// fileWatcher 实时扫描的文件监控后端
+type fileWatcher interface {
+	// Add 监控路径；Recursive 为 false 时只监控该目录本身
+	Add(path string) error
+	// Remove 取消监控
+	Remove(path string) error
+	// Recursive 一次 Add 是否覆盖整个目录树
+	Recursive() bool
+	Events() <-chan fileEvent
+	Errors() <-chan error
+	Close() error
+}
+
+// accessGate 能在文件得出结论前拦截其他进程打开文件的监控后端
+type accessGate interface {
+	// Hold 开始拦截对文件的打开请求
+	Hold(path string) error
+	// Release 结束拦截；deny 为 true 时拒绝正在等待的打开请求，并继续拒绝之后的打开请求直到文件被删除、移走或替换
+	Release(path string, deny bool)
+	// Forget 文件被删除或移走后不再拦截该路径
+	Forget(path string)
+}
+
+// newFileWatcher 按配置创建监控后端，fanotify 不可用时退回 inotify，inotify 不可用时退回轮询
+func newFileWatcher(cfg *config.Config) (fileWatcher, error) {
+	realtime := cfg.Scan.Realtime
+	switch realtime.Backend {
+	case "", backendInotify:
+	case backendFanotify:
+		watcher, err := newFanotifyWatcher(realtime)
+		if err != nil {
+			log.Printf("Warning: fanotify backend unavailable, falling back to inotify: %v", err)
+			break
+		}
+		dirs, err := newInotifyWatcher()
+		if err != nil {
+			log.Printf("Warning: inotify unavailable, files created by rename or only chmod'ed will not be scanned: %v", err)
+			return watcher, nil
+		}
+		return newCombinedWatcher(watcher, dirs), nil
+	case backendPoll:
+		return newPollWatcher(realtime.PollInterval, cfg.Scan.ExcludeDirs, cfg.Scan.FileTypes), nil
+	default:
+		return nil, fmt.Errorf("unknown realtime backend %q (expected %s, %s or %s)",
+			realtime.Backend, backendInotify, backendFanotify, backendPoll)
+	}
+
+	watcher, err := newInotifyWatcher()
+	if err != nil {
+		log.Printf("Warning: inotify unavailable, falling back to polling every %v: %v", pollInterval(realtime), err)
+		return newPollWatcher(realtime.PollInterval, cfg.Scan.ExcludeDirs, cfg.Scan.FileTypes), nil
+	}
+	return watcher, nil
+}
+
+// pollInterval 轮询间隔，未配置时使用默认值
+func pollInterval(cfg config.RealtimeConfig) time.Duration {
+	if cfg.PollInterval > 0 {
+		return cfg.PollInterval
+	}
+	return defaultPollInterval
+}
+
+// isWatchLimit 判断错误是否由 inotify 监控数或实例数耗尽引起
+func isWatchLimit(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE)
+}
+
+// inotifyWatcher 基于 fsnotify 的监控后端，每个目录需要单独添加监控
+type inotifyWatcher struct {
+	watcher *fsnotify.Watcher
+	events  chan fileEvent
+}
+
+// newInotifyWatcher 创建 inotify 监控后端
+func newInotifyWatcher() (*inotifyWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %v", err)
+	}
+	w := &inotifyWatcher{
+		watcher: watcher,
+		events:  make(chan fileEvent),
+	}
+	go w.forward()
+	return w, nil
+}
+
+// forward 将 fsnotify 事件转换为 fileEvent
+func (w *inotifyWatcher) forward() {
+	defer close(w.events)
+	for event := range w.watcher.Events {
+		var op eventOp
+		if event.Op&fsnotify.Create != 0 {
+			op |= opCreate
+		}
+		if event.Op&fsnotify.Write != 0 {
+			op |= opWrite
+		}
+		if event.Op&fsnotify.Remove != 0 {
+			op |= opRemove
+		}
+		if event.Op&fsnotify.Rename != 0 {
+			op |= opRename
+		}
+		if event.Op&fsnotify.Chmod != 0 {
+			op |= opChmod
+		}
+		w.events <- fileEvent{Path: event.Name, Op: op}
+	}
+}
+
+func (w *inotifyWatcher) Add(path string) error    { return w.watcher.Add(path) }
+func (w *inotifyWatcher) Remove(path string) error { return w.watcher.Remove(path) }
+func (w *inotifyWatcher) Recursive() bool          { return false }
+func (w *inotifyWatcher) Events() <-chan fileEvent { return w.events }
+func (w *inotifyWatcher) Errors() <-chan error     { return w.watcher.Errors }
+func (w *inotifyWatcher) Close() error             { return w.watcher.Close() }
+
+// combinedWatcher fanotify 的挂载点标记只报告写入后关闭，收不到新建、移入（如在别处写好后 rename 进网站目录）、
+// 删除和权限变化事件，也无法在需要拦截的权限模式下改用 FAN_REPORT_FID 获取这些事件；
+// 因此同时运行 inotify 逐目录补上这些事件，写入事件仍以 fanotify 为准（带写入进程）
+type combinedWatcher struct {
+	primary fileWatcher // fanotify，按挂载点监控
+	dirs    *inotifyWatcher
+	events  chan fileEvent
+	errors  chan error
+	done    chan struct{} // Close 时关闭；fanotify 不关闭错误通道，错误转发随之停止
+	once    sync.Once
+
+	mu    sync.Mutex
+	roots []string // 已交给 primary 的顶层目录
+}
+
+// newCombinedWatcher 合并 fanotify 和 inotify 两个后端
+func newCombinedWatcher(primary fileWatcher, dirs *inotifyWatcher) *combinedWatcher {
+	w := &combinedWatcher{
+		primary: primary,
+		dirs:    dirs,
+		events:  make(chan fileEvent),
+		errors:  make(chan error),
+		done:    make(chan struct{}),
+	}
+
+	var forwarding sync.WaitGroup
+	forwarding.Add(4)
+	go func() {
+		defer forwarding.Done()
+		for event := range primary.Events() {
+			w.events <- event
+		}
+	}()
+	go func() {
+		defer forwarding.Done()
+		for event := range dirs.Events() {
+			// 写入由 fanotify 报告，避免同一次写入产生两个事件
+			if event.Op &^= opWrite; event.Op != 0 {
+				w.events <- event
+			}
+		}
+	}()
+	for _, errs := range []<-chan error{primary.Errors(), dirs.Errors()} {
+		go func(errs <-chan error) {
+			defer forwarding.Done()
+			for {
+				select {
+				case err, ok := <-errs:
+					if !ok {
+						return
+					}
+					select {
+					case w.errors <- err:
+					case <-w.done:
+						return
+					}
+				case <-w.done:
+					return
+				}
+			}
+		}(errs)
+	}
+	go func() {
+		forwarding.Wait()
+		close(w.events)
+		close(w.errors)
+	}()
+	return w
+}
+
+// Add 为目录添加 inotify 监控，不在已监控目录树下的目录同时交给 fanotify；
+// inotify 监控数耗尽时返回其错误，由调用方改为轮询，fanotify 仍报告该目录下的写入
+func (w *combinedWatcher) Add(path string) error {
+	path = filepath.Clean(path)
+	w.mu.Lock()
+	covered := false
+	for _, root := range w.roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			covered = true
+			break
+		}
+	}
+	if !covered {
+		if err := w.primary.Add(path); err != nil {
+			w.mu.Unlock()
+			return err
+		}
+		w.roots = append(w.roots, path)
+	}
+	w.mu.Unlock()
+	return w.dirs.Add(path)
+}
+
+// Remove 取消目录的 inotify 监控，目录是顶层目录时同时从 fanotify 移除
+func (w *combinedWatcher) Remove(path string) error {
+	path = filepath.Clean(path)
+	w.mu.Lock()
+	for i, root := range w.roots {
+		if root == path {
+			w.roots = append(w.roots[:i], w.roots[i+1:]...)
+			w.primary.Remove(path)
+			break
+		}
+	}
+	w.mu.Unlock()
+	return w.dirs.Remove(path)
+}
+
+// Recursive inotify 需要逐目录添加
+func (w *combinedWatcher) Recursive() bool          { return false }
+func (w *combinedWatcher) Events() <-chan fileEvent { return w.events }
+func (w *combinedWatcher) Errors() <-chan error     { return w.errors }
+
+// Close 关闭两个后端
+func (w *combinedWatcher) Close() error {
+	w.once.Do(func() { close(w.done) })
+	err := w.primary.Close()
+	if dirsErr := w.dirs.Close(); err == nil {
+		err = dirsErr
+	}
+	return err
+}
+
+// Hold 由 fanotify 拦截
+func (w *combinedWatcher) Hold(path string) error {
+	if gate, ok := w.primary.(accessGate); ok {
+		return gate.Hold(path)
+	}
+	return nil
+}
+
+// Release 由 fanotify 结束拦截
+func (w *combinedWatcher) Release(path string, deny bool) {
+	if gate, ok := w.primary.(accessGate); ok {
+		gate.Release(path, deny)
+	}
+}
+
+// Forget 由 fanotify 移除拦截
+func (w *combinedWatcher) Forget(path string) {
+	if gate, ok := w.primary.(accessGate); ok {
+		gate.Forget(path)
+	}
+}
+
+// lookupProcess 从 /proc 读取进程信息，进程已退出时只返回 PID
+func lookupProcess(pid int) *detector.ProcessInfo {
+	info := &detector.ProcessInfo{PID: pid, UID: -1}
+	procDir := filepath.Join("/proc", strconv.Itoa(pid))
+
+	if exe, err := os.Readlink(filepath.Join(procDir, "exe")); err == nil {
+		info.Exe = exe
+	}
+	if cmdline, err := os.ReadFile(filepath.Join(procDir, "cmdline")); err == nil {
+		info.Cmdline = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+	}
+	if status, err := os.ReadFile(filepath.Join(procDir, "status")); err == nil {
+		for _, line := range strings.Split(string(status), "\n") {
+			if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "Uid:" {
+				if uid, err := strconv.Atoi(fields[1]); err == nil {
+					info.UID = uid
+				}
+				break
+			}
+		}
+	}
+	return info
+}
+
+// isOwnProcess 判断进程是否为本进程或其子孙进程（如行为分析的沙箱）
+func isOwnProcess(pid int) bool {
+	self := os.Getpid()
+	for depth := 0; depth < 16 && pid > 1; depth++ {
+		if pid == self {
+			return true
+		}
+		status, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "status"))
+		if err != nil {
+			return false
+		}
+		parent := 0
+		for _, line := range strings.Split(string(status), "\n") {
+			if strings.HasPrefix(line, "PPid:") {
+				parent, _ = strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "PPid:")))
+				break
+			}
+		}
+		pid = parent
+	}
+	return false
+}
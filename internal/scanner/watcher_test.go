@@ -0,0 +1,98 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeWatcher 按挂载点监控的后端替身，只记录添加的目录
+type fakeWatcher struct {
+	added  []string
+	events chan fileEvent
+	errors chan error
+}
+
+func newFakeWatcher() *fakeWatcher {
+	return &fakeWatcher{events: make(chan fileEvent), errors: make(chan error)}
+}
+
+func (w *fakeWatcher) Add(path string) error    { w.added = append(w.added, path); return nil }
+func (w *fakeWatcher) Remove(path string) error { return nil }
+func (w *fakeWatcher) Recursive() bool          { return true }
+func (w *fakeWatcher) Events() <-chan fileEvent { return w.events }
+func (w *fakeWatcher) Errors() <-chan error     { return w.errors }
+func (w *fakeWatcher) Close() error             { close(w.events); return nil }
+
+// nextEvent 读取下一个事件，超时返回 false
+func nextEvent(t *testing.T, events <-chan fileEvent) (fileEvent, bool) {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		return event, ok
+	case <-time.After(5 * time.Second):
+		return fileEvent{}, false
+	}
+}
+
+func TestCombinedWatcherReportsRenamedFiles(t *testing.T) {
+	dirs, err := newInotifyWatcher()
+	if err != nil {
+		t.Skipf("inotify unavailable: %v", err)
+	}
+	primary := newFakeWatcher()
+	w := newCombinedWatcher(primary, dirs)
+
+	root := t.TempDir()
+	sub := filepath.Join(root, "uploads")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{root, sub} {
+		if err := w.Add(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(primary.added) != 1 || primary.added[0] != root {
+		t.Errorf("fanotify roots = %v, want only %s", primary.added, root)
+	}
+
+	// 在监控目录外写好后重命名移入，只有 inotify 能看到
+	staged := filepath.Join(t.TempDir(), "shell.php")
+	if err := os.WriteFile(staged, []byte("<?php eval($_POST[1]);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(sub, "shell.php")
+	if err := os.Rename(staged, target); err != nil {
+		t.Fatal(err)
+	}
+	event, ok := nextEvent(t, w.Events())
+	if !ok {
+		t.Fatal("no event for a file renamed into the watched tree")
+	}
+	if event.Path != target || event.Op&opCreate == 0 {
+		t.Errorf("event = %+v, want create of %s", event, target)
+	}
+
+	// 写入只由 fanotify 报告
+	if err := os.WriteFile(target, []byte("<?php echo 1;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(target, 0600); err != nil {
+		t.Fatal(err)
+	}
+	event, ok = nextEvent(t, w.Events())
+	if !ok {
+		t.Fatal("no event for chmod")
+	}
+	if event.Op&opWrite != 0 || event.Op&opChmod == 0 {
+		t.Errorf("event = %+v, want chmod without write", event)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for range w.Events() {
+	}
+}
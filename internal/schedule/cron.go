@@ -0,0 +1,263 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule 计算下一次运行时间
+type Schedule interface {
+	// Next 返回严格晚于 t 的下一次运行时间，不存在时返回零值
+	Next(t time.Time) time.Time
+}
+
+// cronSchedule 标准 5 字段 cron 表达式：分 时 日 月 周
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool // 日、周字段为 * 时按另一字段匹配，都有限定时任一匹配即可
+}
+
+// everySchedule 固定间隔，@every <duration>
+type everySchedule struct {
+	interval time.Duration
+}
+
+// cronField 字段取值范围和名称
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// macros 预定义的表达式
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse 解析 cron 表达式，支持 5 字段格式（* , - / 和月份、星期英文缩写）、
+// @daily 等预定义表达式以及 @every <duration>
+func Parse(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expr, "@every ")))
+		if err != nil || interval < time.Minute {
+			return nil, fmt.Errorf("invalid cron expression %q: @every requires a duration of at least 1m", expr)
+		}
+		return everySchedule{interval: interval}, nil
+	}
+	if macro, ok := macros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields (minute hour day month weekday)", expr)
+	}
+
+	var s cronSchedule
+	var err error
+	if s.minute, err = parseField(fields[0], minuteField); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %v", expr, err)
+	}
+	if s.hour, err = parseField(fields[1], hourField); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %v", expr, err)
+	}
+	if s.dom, err = parseField(fields[2], domField); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %v", expr, err)
+	}
+	if s.month, err = parseField(fields[3], monthField); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %v", expr, err)
+	}
+	if s.dow, err = parseField(fields[4], dowField); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %v", expr, err)
+	}
+	// 星期 7 与 0 都表示周日
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny = fields[2] == "*" || fields[2] == "?"
+	s.dowAny = fields[4] == "*" || fields[4] == "?"
+	return &s, nil
+}
+
+// parseField 解析一个字段，返回取值的位图
+func parseField(field string, f cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", f.name, part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case part == "*" || part == "?":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(bounds[1]); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := f.value(part)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			// 单个值带步长时表示从该值开始到最大值，如 5/15
+			if step == 1 {
+				hi = v
+			}
+		}
+		if lo > hi {
+			return 0, fmt.Errorf("invalid range in %s field %q", f.name, part)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value 解析字段中的单个值
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s value %q out of range %d-%d", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next 在挂钟时间上逐级查找满足条件的月、日、时、分，最多向后查找 5 年。
+// 夏令时跳过的时刻顺延到跳变之后，回拨时重复的挂钟时间只运行一次
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	// 以 UTC 表示挂钟时间，查找过程不受夏令时跳变影响
+	w := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC).Add(time.Minute)
+	limit := w.AddDate(5, 0, 0)
+
+	for w.Before(limit) {
+		if s.month&(1<<uint(w.Month())) == 0 {
+			w = time.Date(w.Year(), w.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(w) {
+			w = time.Date(w.Year(), w.Month(), w.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(w.Hour())) == 0 {
+			w = time.Date(w.Year(), w.Month(), w.Day(), w.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.minute&(1<<uint(w.Minute())) == 0 {
+			w = w.Add(time.Minute)
+			continue
+		}
+		next := time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), 0, 0, loc)
+		if next.Hour() != w.Hour() || next.Minute() != w.Minute() {
+			// 夏令时跳过的挂钟时间可能被换算到跳变之前，按跳变前的偏移顺延到跳变之后
+			_, offset := next.Zone()
+			if shifted := w.Add(-time.Duration(offset) * time.Second).In(loc); shifted.After(next) {
+				next = shifted
+			}
+		}
+		// t 位于回拨后重复的一小时内时，相同挂钟时间的第一次出现早于 t，跳过
+		if next.After(t) {
+			return next
+		}
+		w = w.Add(time.Minute)
+	}
+	return time.Time{}
+}
+
+// dayMatches 按 cron 语义匹配日期：日和周都有限定时满足其一即可
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// Next 上次运行时间加上间隔
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+// Daily 每天从 start（HH:MM）开始按 interval 运行，兼容旧的 start_time + interval 配置
+func Daily(start string, interval time.Duration) (Schedule, error) {
+	at, err := time.Parse("15:04", start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start time format: %v", err)
+	}
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	return dailySchedule{hour: at.Hour(), minute: at.Minute(), interval: interval}, nil
+}
+
+// dailySchedule 每天固定时刻起按间隔运行
+type dailySchedule struct {
+	hour, minute int
+	interval     time.Duration
+}
+
+// Next 间隔小于一天时每天从起始时刻重新对齐，否则从起始时刻起按间隔顺延
+func (s dailySchedule) Next(t time.Time) time.Time {
+	start := time.Date(t.Year(), t.Month(), t.Day(), s.hour, s.minute, 0, 0, t.Location())
+	if s.interval >= 24*time.Hour {
+		for !start.After(t) {
+			start = start.Add(s.interval)
+		}
+		return start
+	}
+
+	if start.After(t) {
+		start = start.AddDate(0, 0, -1)
+	}
+	end := start.AddDate(0, 0, 1)
+	for next := start; next.Before(end); next = next.Add(s.interval) {
+		if next.After(t) {
+			return next
+		}
+	}
+	return end
+}
@@ -0,0 +1,135 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"* * * * *", false},
+		{"*/15 * * * *", false},
+		{"5/15 * * * *", false},
+		{"0 9-17/2 * * mon-fri", false},
+		{"0 0 1 jan,jul sun", false},
+		{"0 0 * * 7", false},
+		{"0 0 ? * ?", false},
+		{"@daily", false},
+		{"@HOURLY", false},
+		{"@every 90m", false},
+
+		{"", true},
+		{"* * * *", true},
+		{"* * * * * *", true},
+		{"60 * * * *", true},
+		{"* 24 * * *", true},
+		{"0 0 0 * *", true},
+		{"0 0 32 * *", true},
+		{"0 0 * 13 *", true},
+		{"0 0 * * 8", true},
+		{"*/0 * * * *", true},
+		{"*/x * * * *", true},
+		{"5-1 * * * *", true},
+		{"0 0 * foo *", true},
+		{"@every 30s", true},
+		{"@every soon", true},
+		{"@fortnightly", true},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.expr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	utc := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"strictly later", "*/15 * * * *", utc("2026-10-14 10:15"), utc("2026-10-14 10:30")},
+		{"seconds truncated", "*/15 * * * *", utc("2026-10-14 10:07").Add(42 * time.Second), utc("2026-10-14 10:15")},
+		{"step from value", "5/15 * * * *", utc("2026-10-14 10:06"), utc("2026-10-14 10:20")},
+		{"step from value wraps hour", "5/15 * * * *", utc("2026-10-14 10:50"), utc("2026-10-14 11:05")},
+		{"range with step", "0 9-17/4 * * *", utc("2026-10-14 13:00"), utc("2026-10-14 17:00")},
+		{"daily macro", "@daily", utc("2026-10-14 10:00"), utc("2026-10-15 00:00")},
+		{"7 is sunday", "0 0 * * 7", utc("2026-10-14 10:00"), utc("2026-10-18 00:00")},
+		{"0 is sunday", "0 0 * * 0", utc("2026-10-14 10:00"), utc("2026-10-18 00:00")},
+		{"weekday names", "30 8 * * mon-fri", utc("2026-10-16 09:00"), utc("2026-10-19 08:30")},
+		{"dom or dow: dom first", "0 0 13 * fri", utc("2026-10-10 00:00"), utc("2026-10-13 00:00")},
+		{"dom or dow: dow first", "0 0 13 * fri", utc("2026-10-13 00:00"), utc("2026-10-16 00:00")},
+		{"dom only", "0 0 31 * *", utc("2026-04-01 00:00"), utc("2026-05-31 00:00")},
+		{"dow only", "0 12 * * wed", utc("2026-10-14 12:00"), utc("2026-10-21 12:00")},
+		{"leap day", "0 0 29 2 *", utc("2026-03-01 00:00"), utc("2028-02-29 00:00")},
+		{"month names", "0 0 1 jan,jul *", utc("2026-02-01 00:00"), utc("2026-07-01 00:00")},
+		{"never", "0 0 30 2 *", utc("2026-01-01 00:00"), time.Time{}},
+		{"every", "@every 90m", utc("2026-10-14 10:07"), utc("2026-10-14 11:37")},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: Parse(%q): %v", tt.name, tt.expr, err)
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%s: Next(%q, %v) = %v, want %v", tt.name, tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2026-03-08 02:00 EST 跳到 03:00 EDT，2026-11-01 02:00 EDT 回拨到 01:00 EST
+	est := time.FixedZone("EST", -5*3600)
+	edt := time.FixedZone("EDT", -4*3600)
+	at := func(loc *time.Location, s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v.In(ny)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"skipped time runs after the jump", "30 2 * * *", at(est, "2026-03-08 01:00"), at(edt, "2026-03-08 03:30")},
+		{"every minute across the jump", "* * * * *", at(est, "2026-03-08 01:59"), at(edt, "2026-03-08 03:00")},
+		{"day after the jump", "30 2 * * *", at(edt, "2026-03-08 03:30"), at(edt, "2026-03-09 02:30")},
+		{"repeated time runs once", "30 1 * * *", at(edt, "2026-11-01 01:30"), at(est, "2026-11-02 01:30")},
+		{"from inside the repeated hour", "45 1 * * *", at(est, "2026-11-01 01:30"), at(est, "2026-11-02 01:45")},
+		{"hourly across the fall back", "0 * * * *", at(edt, "2026-11-01 01:00"), at(est, "2026-11-01 02:00")},
+		{"daily across the fall back", "0 3 * * *", at(edt, "2026-10-31 03:00"), at(est, "2026-11-01 03:00")},
+		{"daily across the jump", "0 3 * * *", at(est, "2026-03-07 03:00"), at(edt, "2026-03-08 03:00")},
+		{"every is elapsed time", "@every 1h", at(edt, "2026-11-01 01:30"), at(est, "2026-11-01 01:30")},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: Parse(%q): %v", tt.name, tt.expr, err)
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%s: Next(%q, %v) = %v, want %v", tt.name, tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
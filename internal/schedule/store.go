@@ -0,0 +1,79 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultStatePath 调度状态文件的默认路径
+const DefaultStatePath = "data/schedule_state.json"
+
+// JobState 一个定时任务的运行记录
+type JobState struct {
+	LastRun    time.Time `json:"last_run"`    // 最近一次完整运行的开始时间，用于推算停机期间错过的运行
+	LastFinish time.Time `json:"last_finish"` // 最近一次运行的结束时间
+	LastStatus string    `json:"last_status"` // completed 或 interrupted
+}
+
+// Store 持久化的调度状态，进程重启后据此补跑错过的任务
+type Store struct {
+	path string
+
+	mu   sync.Mutex
+	jobs map[string]JobState
+}
+
+// OpenStore 读取调度状态文件，文件不存在时返回空状态
+func OpenStore(path string) (*Store, error) {
+	if path == "" {
+		path = DefaultStatePath
+	}
+	s := &Store{path: path, jobs: make(map[string]JobState)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule state: %v", err)
+	}
+	if err := json.Unmarshal(data, &s.jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule state %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// Get 返回任务的运行记录，从未运行过时为零值
+func (s *Store) Get(job string) JobState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[job]
+}
+
+// Put 更新任务的运行记录并写回文件
+func (s *Store) Put(job string, state JobState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job] = state
+
+	data, err := json.MarshalIndent(s.jobs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schedule state: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create schedule state directory: %v", err)
+	}
+	// 先写临时文件再改名，避免进程中断时留下不完整的状态文件
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write schedule state: %v", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write schedule state: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package training
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"webshell-detector/internal/dataset"
+	"webshell-detector/pkg/mlmodel"
+)
+
+// trainForest 训练随机森林：自助采样 + 每个节点随机选取 sqrt(特征数) 个候选特征，按基尼系数分裂
+func trainForest(ds *Dataset, opts Options, data *mlmodel.ModelData) {
+	rng := rand.New(rand.NewSource(opts.Seed))
+	dim := len(ds.X[0])
+	maxFeatures := int(math.Sqrt(float64(dim)))
+	if maxFeatures < 1 {
+		maxFeatures = 1
+	}
+
+	builder := &treeBuilder{
+		ds:             ds,
+		rng:            rng,
+		maxDepth:       opts.MaxDepth,
+		minSamplesLeaf: opts.MinSamplesLeaf,
+		maxFeatures:    maxFeatures,
+	}
+	if builder.minSamplesLeaf < 1 {
+		builder.minSamplesLeaf = 1
+	}
+
+	data.Trees = make([]mlmodel.Tree, 0, opts.Trees)
+	for t := 0; t < opts.Trees; t++ {
+		sample := make([]int, len(ds.X))
+		for i := range sample {
+			sample[i] = rng.Intn(len(ds.X))
+		}
+		data.Trees = append(data.Trees, builder.build(sample))
+	}
+}
+
+// treeBuilder 单棵决策树的构建器
+type treeBuilder struct {
+	ds             *Dataset
+	rng            *rand.Rand
+	maxDepth       int
+	minSamplesLeaf int
+	maxFeatures    int
+	nodes          []mlmodel.TreeNode
+}
+
+// build 构建一棵树，节点按先序排列，保证子节点下标大于父节点
+func (b *treeBuilder) build(samples []int) mlmodel.Tree {
+	b.nodes = nil
+	b.grow(samples, 0)
+	return mlmodel.Tree{Nodes: b.nodes}
+}
+
+// grow 递归生长节点，返回节点下标
+func (b *treeBuilder) grow(samples []int, depth int) int {
+	idx := len(b.nodes)
+	b.nodes = append(b.nodes, mlmodel.TreeNode{Left: -1, Right: -1})
+
+	positives := b.countPositives(samples)
+	value := float64(positives) / float64(len(samples))
+	b.nodes[idx].Cover = float64(len(samples))
+
+	if (b.maxDepth > 0 && depth >= b.maxDepth) || positives == 0 || positives == len(samples) ||
+		len(samples) < 2*b.minSamplesLeaf {
+		b.nodes[idx].Value = value
+		return idx
+	}
+
+	feature, threshold, ok := b.bestSplit(samples)
+	if !ok {
+		b.nodes[idx].Value = value
+		return idx
+	}
+
+	var left, right []int
+	for _, s := range samples {
+		if b.ds.X[s][feature] <= threshold {
+			left = append(left, s)
+		} else {
+			right = append(right, s)
+		}
+	}
+
+	leftIdx := b.grow(left, depth+1)
+	rightIdx := b.grow(right, depth+1)
+	b.nodes[idx] = mlmodel.TreeNode{
+		Feature:   feature,
+		Threshold: threshold,
+		Left:      leftIdx,
+		Right:     rightIdx,
+		Cover:     float64(len(samples)),
+	}
+	return idx
+}
+
+// bestSplit 在随机选取的候选特征中寻找基尼增益最大的分裂点
+func (b *treeBuilder) bestSplit(samples []int) (int, float64, bool) {
+	dim := len(b.ds.X[0])
+	candidates := b.rng.Perm(dim)[:b.maxFeatures]
+
+	total := len(samples)
+	totalPos := b.countPositives(samples)
+	bestImpurity := gini(totalPos, total)
+	bestFeature, bestThreshold, found := -1, 0.0, false
+
+	order := make([]int, total)
+	for _, feature := range candidates {
+		copy(order, samples)
+		sort.Slice(order, func(i, j int) bool {
+			return b.ds.X[order[i]][feature] < b.ds.X[order[j]][feature]
+		})
+
+		leftPos := 0
+		for i := 0; i < total-1; i++ {
+			if b.ds.Y[order[i]] == dataset.LabelMalicious {
+				leftPos++
+			}
+			leftN := i + 1
+			rightN := total - leftN
+			cur, next := b.ds.X[order[i]][feature], b.ds.X[order[i+1]][feature]
+			if cur == next || leftN < b.minSamplesLeaf || rightN < b.minSamplesLeaf {
+				continue
+			}
+
+			impurity := (float64(leftN)*gini(leftPos, leftN) + float64(rightN)*gini(totalPos-leftPos, rightN)) / float64(total)
+			if impurity < bestImpurity {
+				bestImpurity = impurity
+				bestFeature = feature
+				bestThreshold = (cur + next) / 2
+				found = true
+			}
+		}
+	}
+
+	return bestFeature, bestThreshold, found
+}
+
+// countPositives 统计样本中的恶意样本数
+func (b *treeBuilder) countPositives(samples []int) int {
+	count := 0
+	for _, s := range samples {
+		if b.ds.Y[s] == dataset.LabelMalicious {
+			count++
+		}
+	}
+	return count
+}
+
+// gini 计算二分类基尼不纯度
+func gini(positives, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	p := float64(positives) / float64(total)
+	return 2 * p * (1 - p)
+}
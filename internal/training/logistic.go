@@ -0,0 +1,98 @@
+package training
+
+import (
+	"math"
+
+	"webshell-detector/internal/dataset"
+	"webshell-detector/pkg/mlmodel"
+)
+
+// trainLogistic 训练带 L2 正则的逻辑回归，使用全批量梯度下降和类别平衡权重
+func trainLogistic(ds *Dataset, opts Options, data *mlmodel.ModelData) {
+	n := len(ds.X)
+	dim := len(ds.X[0])
+
+	// 计算标准化参数
+	means := make([]float64, dim)
+	scales := make([]float64, dim)
+	for _, x := range ds.X {
+		for j, v := range x {
+			means[j] += v
+		}
+	}
+	for j := range means {
+		means[j] /= float64(n)
+	}
+	for _, x := range ds.X {
+		for j, v := range x {
+			d := v - means[j]
+			scales[j] += d * d
+		}
+	}
+	for j := range scales {
+		scales[j] = math.Sqrt(scales[j] / float64(n))
+		if scales[j] == 0 {
+			scales[j] = 1
+		}
+	}
+
+	// 预先标准化
+	z := make([][]float64, n)
+	for i, x := range ds.X {
+		z[i] = make([]float64, dim)
+		for j, v := range x {
+			z[i][j] = (v - means[j]) / scales[j]
+		}
+	}
+
+	// 类别平衡权重，避免样本不均衡时偏向多数类
+	positives := 0
+	for _, y := range ds.Y {
+		if y == dataset.LabelMalicious {
+			positives++
+		}
+	}
+	posWeight := float64(n) / (2 * float64(positives))
+	negWeight := float64(n) / (2 * float64(n-positives))
+
+	weights := make([]float64, dim)
+	intercept := 0.0
+	grad := make([]float64, dim)
+	for epoch := 0; epoch < opts.Epochs; epoch++ {
+		for j := range grad {
+			grad[j] = 0
+		}
+		gradIntercept := 0.0
+
+		for i, x := range z {
+			margin := intercept
+			for j, v := range x {
+				margin += weights[j] * v
+			}
+			target, sampleWeight := 0.0, negWeight
+			if ds.Y[i] == dataset.LabelMalicious {
+				target, sampleWeight = 1.0, posWeight
+			}
+			diff := sampleWeight * (sigmoid(margin) - target)
+			for j, v := range x {
+				grad[j] += diff * v
+			}
+			gradIntercept += diff
+		}
+
+		for j := range weights {
+			weights[j] -= opts.LearningRate * (grad[j]/float64(n) + opts.L2*weights[j])
+		}
+		intercept -= opts.LearningRate * gradIntercept / float64(n)
+	}
+
+	data.Weights = weights
+	data.Intercept = intercept
+	data.Means = means
+	data.Scales = scales
+}
+
+// sigmoid 逻辑函数
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
@@ -0,0 +1,216 @@
+package training
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"webshell-detector/internal/dataset"
+	"webshell-detector/pkg/mlmodel"
+)
+
+// Dataset 训练用特征矩阵
+type Dataset struct {
+	X [][]float64
+	Y []int
+}
+
+// Options 训练参数
+type Options struct {
+	Algorithm string  // 训练算法：logistic/random_forest
+	Seed      int64   // 随机种子，相同数据和种子得到相同模型
+	TestSplit float64 // 留出评估的样本比例
+	Threshold float64 // 写入模型的判定阈值
+
+	// 逻辑回归参数
+	Epochs       int
+	LearningRate float64
+	L2           float64
+
+	// 随机森林参数
+	Trees          int
+	MaxDepth       int
+	MinSamplesLeaf int
+}
+
+// DefaultOptions 默认训练参数
+func DefaultOptions() Options {
+	return Options{
+		Algorithm:      mlmodel.ModelTypeLogistic,
+		Seed:           42,
+		TestSplit:      0.2,
+		Threshold:      0.5,
+		Epochs:         500,
+		LearningRate:   0.1,
+		L2:             0.001,
+		Trees:          100,
+		MaxDepth:       10,
+		MinSamplesLeaf: 1,
+	}
+}
+
+// Validate 检查训练参数的取值范围，避免训练出退化的模型
+func (o Options) Validate() error {
+	if !(o.TestSplit >= 0 && o.TestSplit < 1) {
+		return fmt.Errorf("test split must be in [0, 1), got %v", o.TestSplit)
+	}
+	if !(o.Threshold > 0 && o.Threshold < 1) {
+		return fmt.Errorf("threshold must be in (0, 1), got %v", o.Threshold)
+	}
+
+	switch o.Algorithm {
+	case mlmodel.ModelTypeLogistic:
+		if o.Epochs <= 0 {
+			return fmt.Errorf("epochs must be positive, got %d", o.Epochs)
+		}
+		if !(o.LearningRate > 0) || math.IsInf(o.LearningRate, 0) {
+			return fmt.Errorf("learning rate must be positive, got %v", o.LearningRate)
+		}
+		if !(o.L2 >= 0) || math.IsInf(o.L2, 0) {
+			return fmt.Errorf("L2 regularization must be non-negative, got %v", o.L2)
+		}
+	case mlmodel.ModelTypeRandomForest:
+		if o.Trees <= 0 {
+			return fmt.Errorf("number of trees must be positive, got %d", o.Trees)
+		}
+		if o.MaxDepth <= 0 {
+			return fmt.Errorf("max depth must be positive, got %d", o.MaxDepth)
+		}
+		if o.MinSamplesLeaf <= 0 {
+			return fmt.Errorf("minimum samples per leaf must be positive, got %d", o.MinSamplesLeaf)
+		}
+	default:
+		return fmt.Errorf("unsupported training algorithm: %s", o.Algorithm)
+	}
+	return nil
+}
+
+// Metrics 留出集评估指标
+type Metrics struct {
+	Samples   int
+	Accuracy  float64
+	Precision float64
+	Recall    float64
+	F1        float64
+}
+
+// LoadDataset 读取样本并用检测器相同的特征提取代码生成特征矩阵
+func LoadDataset(samples []dataset.Sample) (*Dataset, error) {
+	ds := &Dataset{}
+	for _, sample := range samples {
+		record, err := dataset.NewRecord(sample)
+		if err != nil {
+			fmt.Printf("Warning: Skipping %s: %v\n", sample.Path, err)
+			continue
+		}
+		ds.X = append(ds.X, record.Features)
+		ds.Y = append(ds.Y, record.Label)
+	}
+	if len(ds.X) == 0 {
+		return nil, fmt.Errorf("no usable samples")
+	}
+	return ds, nil
+}
+
+// Split 按种子打乱后切分训练集和测试集
+func (ds *Dataset) Split(testRatio float64, seed int64) (*Dataset, *Dataset) {
+	rng := rand.New(rand.NewSource(seed))
+	order := rng.Perm(len(ds.X))
+
+	testSize := int(float64(len(ds.X)) * testRatio)
+	train, test := &Dataset{}, &Dataset{}
+	for i, idx := range order {
+		target := train
+		if i < testSize {
+			target = test
+		}
+		target.X = append(target.X, ds.X[idx])
+		target.Y = append(target.Y, ds.Y[idx])
+	}
+	return train, test
+}
+
+// Train 按选项训练模型，返回可被 mlmodel.LoadModel 直接加载的模型数据，
+// 返回前按加载模型时的校验检查模型数据
+func Train(ds *Dataset, opts Options) (*mlmodel.ModelData, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if len(ds.X) == 0 {
+		return nil, fmt.Errorf("empty training set")
+	}
+	if !hasBothClasses(ds.Y) {
+		return nil, fmt.Errorf("training set must contain both benign and malicious samples")
+	}
+
+	data := &mlmodel.ModelData{
+		Type:          opts.Algorithm,
+		SchemaVersion: mlmodel.FeatureSchemaVersion,
+		FeatureNames:  mlmodel.FeatureNames,
+		FeatureCount:  mlmodel.FeatureCount,
+		Threshold:     opts.Threshold,
+	}
+
+	switch opts.Algorithm {
+	case mlmodel.ModelTypeLogistic:
+		trainLogistic(ds, opts, data)
+	case mlmodel.ModelTypeRandomForest:
+		trainForest(ds, opts, data)
+	}
+
+	if _, err := mlmodel.NewModel(data); err != nil {
+		return nil, fmt.Errorf("trained model is invalid: %v", err)
+	}
+	return data, nil
+}
+
+// Evaluate 在数据集上评估模型
+func Evaluate(model *mlmodel.Model, ds *Dataset, threshold float64) (*Metrics, error) {
+	var tp, fp, tn, fn int
+	for i, x := range ds.X {
+		p, err := model.Predict(x)
+		if err != nil {
+			return nil, err
+		}
+		predicted := p >= threshold
+		actual := ds.Y[i] == dataset.LabelMalicious
+		switch {
+		case predicted && actual:
+			tp++
+		case predicted && !actual:
+			fp++
+		case !predicted && actual:
+			fn++
+		default:
+			tn++
+		}
+	}
+
+	m := &Metrics{Samples: len(ds.X)}
+	if m.Samples > 0 {
+		m.Accuracy = float64(tp+tn) / float64(m.Samples)
+	}
+	if tp+fp > 0 {
+		m.Precision = float64(tp) / float64(tp+fp)
+	}
+	if tp+fn > 0 {
+		m.Recall = float64(tp) / float64(tp+fn)
+	}
+	if m.Precision+m.Recall > 0 {
+		m.F1 = 2 * m.Precision * m.Recall / (m.Precision + m.Recall)
+	}
+	return m, nil
+}
+
+// hasBothClasses 检查标签中是否同时包含正负样本
+func hasBothClasses(labels []int) bool {
+	var benign, malicious bool
+	for _, y := range labels {
+		if y == dataset.LabelMalicious {
+			malicious = true
+		} else {
+			benign = true
+		}
+	}
+	return benign && malicious
+}
@@ -0,0 +1,151 @@
+package training
+
+import (
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"webshell-detector/internal/dataset"
+	"webshell-detector/pkg/mlmodel"
+)
+
+// separableDataset 生成按特征 0 可分的数据集，其余特征为噪声
+func separableDataset(n int) *Dataset {
+	rng := rand.New(rand.NewSource(7))
+	ds := &Dataset{}
+	for i := 0; i < n; i++ {
+		x := make([]float64, mlmodel.FeatureCount)
+		for j := range x {
+			x[j] = rng.Float64()
+		}
+		label := dataset.LabelBenign
+		if i%2 == 0 {
+			label = dataset.LabelMalicious
+			x[0] += 2
+		}
+		ds.X = append(ds.X, x)
+		ds.Y = append(ds.Y, label)
+	}
+	return ds
+}
+
+func TestTrainRoundTrip(t *testing.T) {
+	ds := separableDataset(80)
+	train, test := ds.Split(0.25, 42)
+
+	for _, algorithm := range []string{mlmodel.ModelTypeLogistic, mlmodel.ModelTypeRandomForest} {
+		opts := DefaultOptions()
+		opts.Algorithm = algorithm
+		opts.Epochs = 200
+		opts.Trees = 10
+		opts.MaxDepth = 4
+
+		data, err := Train(train, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", algorithm, err)
+		}
+		again, err := Train(train, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", algorithm, err)
+		}
+		if !reflect.DeepEqual(data, again) {
+			t.Errorf("%s: training twice with the same seed produced different models", algorithm)
+		}
+
+		// 保存后由检测器使用的加载路径读回
+		path := filepath.Join(t.TempDir(), "model.json")
+		if err := mlmodel.SaveModel(path, data); err != nil {
+			t.Fatalf("%s: %v", algorithm, err)
+		}
+		model, err := mlmodel.LoadModel(path)
+		if err != nil {
+			t.Fatalf("%s: loading trained model: %v", algorithm, err)
+		}
+		inMemory, err := mlmodel.NewModel(data)
+		if err != nil {
+			t.Fatalf("%s: %v", algorithm, err)
+		}
+		for _, x := range test.X {
+			loaded, err := model.Predict(x)
+			if err != nil {
+				t.Fatalf("%s: %v", algorithm, err)
+			}
+			direct, err := inMemory.Predict(x)
+			if err != nil {
+				t.Fatalf("%s: %v", algorithm, err)
+			}
+			if loaded != direct {
+				t.Fatalf("%s: loaded model predicts %g, trained model %g", algorithm, loaded, direct)
+			}
+		}
+
+		metrics, err := Evaluate(model, test, opts.Threshold)
+		if err != nil {
+			t.Fatalf("%s: %v", algorithm, err)
+		}
+		if metrics.Samples != len(test.X) || metrics.Accuracy < 0.95 {
+			t.Errorf("%s: accuracy %.2f on %d samples, want >= 0.95 on %d", algorithm, metrics.Accuracy, metrics.Samples, len(test.X))
+		}
+	}
+}
+
+func TestTrainRejectsUnusableInput(t *testing.T) {
+	oneClass := separableDataset(4)
+	for i := range oneClass.Y {
+		oneClass.Y[i] = dataset.LabelBenign
+	}
+	unknown := DefaultOptions()
+	unknown.Algorithm = "svm"
+
+	tests := []struct {
+		name string
+		ds   *Dataset
+		opts Options
+	}{
+		{"empty", &Dataset{}, DefaultOptions()},
+		{"single class", oneClass, DefaultOptions()},
+		{"unknown algorithm", separableDataset(4), unknown},
+	}
+	for _, tt := range tests {
+		if _, err := Train(tt.ds, tt.opts); err == nil {
+			t.Errorf("%s: Train succeeded, want error", tt.name)
+		}
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Options)
+		valid  bool
+	}{
+		{"defaults", func(o *Options) {}, true},
+		{"no hold-out", func(o *Options) { o.TestSplit = 0 }, true},
+		{"forest ignores epochs", func(o *Options) { o.Algorithm = mlmodel.ModelTypeRandomForest; o.Epochs = 0 }, true},
+		{"test split 1", func(o *Options) { o.TestSplit = 1 }, false},
+		{"negative test split", func(o *Options) { o.TestSplit = -0.1 }, false},
+		{"threshold 0", func(o *Options) { o.Threshold = 0 }, false},
+		{"threshold above 1", func(o *Options) { o.Threshold = 1.5 }, false},
+		{"zero epochs", func(o *Options) { o.Epochs = 0 }, false},
+		{"negative learning rate", func(o *Options) { o.LearningRate = -0.1 }, false},
+		{"negative L2", func(o *Options) { o.L2 = -1 }, false},
+		{"zero trees", func(o *Options) { o.Algorithm = mlmodel.ModelTypeRandomForest; o.Trees = 0 }, false},
+		{"zero depth", func(o *Options) { o.Algorithm = mlmodel.ModelTypeRandomForest; o.MaxDepth = 0 }, false},
+		{"zero leaf size", func(o *Options) { o.Algorithm = mlmodel.ModelTypeRandomForest; o.MinSamplesLeaf = 0 }, false},
+		{"unknown algorithm", func(o *Options) { o.Algorithm = "svm" }, false},
+	}
+	for _, tt := range tests {
+		opts := DefaultOptions()
+		tt.modify(&opts)
+		if err := opts.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate = %v, want valid %v", tt.name, err, tt.valid)
+		}
+		if tt.valid {
+			continue
+		}
+		if _, err := Train(separableDataset(8), opts); err == nil {
+			t.Errorf("%s: Train accepted invalid options", tt.name)
+		}
+	}
+}
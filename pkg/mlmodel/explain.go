@@ -0,0 +1,175 @@
+package mlmodel
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// 特征贡献的含义随模型类型不同：
+//   - linear：weight×value，Bias 为 0，Bias+Σ贡献为截断前的得分
+//   - logistic：weight×标准化后的特征值，Bias 为截距，Bias+Σ贡献为 sigmoid 前的 margin
+//   - random_forest：沿决策路径逐个分裂累计期望概率的变化（Saabas 方法）后对所有树取平均，
+//     Bias 为各树根节点期望概率的平均，Bias+Σ贡献为校准前的平均概率
+//   - gradient_boosting：同上但在 margin 空间累加，Bias 含 base_score
+//
+// 贡献为正表示该特征把样本推向恶意，为负表示推向正常。
+
+// Contribution 单个特征对预测的贡献
+type Contribution struct {
+	Name         string  `json:"name"`
+	Value        float64 `json:"value"`        // 特征取值
+	Contribution float64 `json:"contribution"` // 对模型原始输出的贡献
+}
+
+// Explanation 单个样本的预测及特征贡献
+type Explanation struct {
+	Score    float64        // 最终预测概率
+	Bias     float64        // 与特征无关的基准值
+	Features []Contribution // 与特征顺序一致的全部贡献
+}
+
+// Top 返回按贡献绝对值降序排列的前 n 个非零贡献
+func (e *Explanation) Top(n int) []Contribution {
+	top := make([]Contribution, 0, len(e.Features))
+	for _, c := range e.Features {
+		if c.Contribution != 0 {
+			top = append(top, c)
+		}
+	}
+	sort.SliceStable(top, func(i, j int) bool {
+		return math.Abs(top[i].Contribution) > math.Abs(top[j].Contribution)
+	})
+	if n > 0 && len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// ExplainBatch 批量预测并计算每个特征的贡献，整批使用同一版本的模型，返回解释和该模型版本
+func (m *Model) ExplainBatch(batch [][]float64) ([]*Explanation, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.predictor == nil {
+		return nil, "", errNotLoaded
+	}
+	names := m.featureNames()
+	explanations := make([]*Explanation, len(batch))
+	for i, features := range batch {
+		if len(features) != m.FeatureCount {
+			return nil, "", fmt.Errorf("invalid feature count at index %d: expected %d, got %d", i, m.FeatureCount, len(features))
+		}
+
+		bias, contributions := m.predictor.explain(features)
+		e := &Explanation{
+			Score:    m.predictor.predict(features),
+			Bias:     bias,
+			Features: make([]Contribution, len(features)),
+		}
+		for j, x := range features {
+			e.Features[j] = Contribution{Name: names[j], Value: x, Contribution: contributions[j]}
+		}
+		explanations[i] = e
+	}
+	return explanations, m.Checksum, nil
+}
+
+// featureNames 返回模型的特征名称，模型未记录时使用当前特征模式的名称
+func (m *Model) featureNames() []string {
+	if len(m.ModelData.FeatureNames) == m.FeatureCount {
+		return m.ModelData.FeatureNames
+	}
+	if len(FeatureNames) == m.FeatureCount {
+		return FeatureNames
+	}
+	names := make([]string, m.FeatureCount)
+	for i := range names {
+		names[i] = fmt.Sprintf("feature_%d", i)
+	}
+	return names
+}
+
+func (l *linearPredictor) explain(features []float64) (float64, []float64) {
+	contributions := make([]float64, len(features))
+	for i, x := range features {
+		contributions[i] = l.weights[i] * x
+	}
+	return 0, contributions
+}
+
+func (l *logisticPredictor) explain(features []float64) (float64, []float64) {
+	contributions := make([]float64, len(features))
+	for i, x := range features {
+		scale := l.scales[i]
+		if scale == 0 {
+			scale = 1
+		}
+		contributions[i] = l.weights[i] * (x - l.means[i]) / scale
+	}
+	return l.intercept, contributions
+}
+
+func (f *forestPredictor) explain(features []float64) (float64, []float64) {
+	bias, contributions := explainTrees(f.trees, f.expected, features)
+	n := float64(len(f.trees))
+	for i := range contributions {
+		contributions[i] /= n
+	}
+	return bias / n, contributions
+}
+
+func (b *boostingPredictor) explain(features []float64) (float64, []float64) {
+	bias, contributions := explainTrees(b.trees, b.expected, features)
+	return b.baseScore + bias, contributions
+}
+
+// explainTrees 累加各树根节点期望值和决策路径上每次分裂带来的期望值变化
+func explainTrees(trees []Tree, expected [][]float64, features []float64) (float64, []float64) {
+	contributions := make([]float64, len(features))
+	bias := 0.0
+	for t := range trees {
+		nodes := trees[t].Nodes
+		e := expected[t]
+		bias += e[0]
+
+		i := 0
+		for !nodes[i].isLeaf() {
+			node := &nodes[i]
+			next := node.Right
+			if features[node.Feature] <= node.Threshold {
+				next = node.Left
+			}
+			contributions[node.Feature] += e[next] - e[i]
+			i = next
+		}
+	}
+	return bias, contributions
+}
+
+// expectations 计算每个节点的期望输出：叶子为叶子值，内部节点为子节点按样本数加权的平均；
+// 模型未记录样本数时两个子节点等权
+func (t *Tree) expectations() []float64 {
+	n := len(t.Nodes)
+	expected := make([]float64, n)
+	cover := make([]float64, n)
+
+	// 子节点下标总大于父节点，逆序遍历即可自底向上计算
+	for i := n - 1; i >= 0; i-- {
+		node := &t.Nodes[i]
+		if node.isLeaf() {
+			expected[i] = node.Value
+			cover[i] = node.Cover
+			continue
+		}
+
+		left, right := node.Left, node.Right
+		if cover[left] > 0 && cover[right] > 0 {
+			cover[i] = cover[left] + cover[right]
+			expected[i] = (expected[left]*cover[left] + expected[right]*cover[right]) / cover[i]
+		} else {
+			expected[i] = (expected[left] + expected[right]) / 2
+		}
+	}
+	return expected
+}
@@ -0,0 +1,329 @@
+package mlmodel
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// FeatureSchemaVersion 特征模式版本，特征的增删、顺序或计算方式变化时必须递增，
+// 使用旧版本特征训练的模型将被拒绝加载
+const FeatureSchemaVersion = 2
+
+// NGramBuckets token 二元组哈希到的桶数
+const NGramBuckets = 32
+
+// longLiteralLength 超过该长度的字符串常量视为长字符串（常见于编码载荷）
+const longLiteralLength = 100
+
+// featureGroup 按正则计数的特征组
+type featureGroup struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// countGroups 以正则计数的特征，顺序即特征顺序
+var countGroups = []featureGroup{
+	{"superglobal_count", regexp.MustCompile(`\$_(?:POST|GET|REQUEST|COOKIE|SERVER|FILES)\b|request\.getParameter\s*\(|Request\.(?:Form|QueryString)\b`)},
+	{"exec_sink_count", regexp.MustCompile(`(?i)\b(?:system|exec|shell_exec|passthru|proc_open|popen|pcntl_exec)\s*\(|Runtime\.getRuntime\(\)\.exec|\bProcessBuilder\b|WScript\.Shell`)},
+	{"code_sink_count", regexp.MustCompile(`(?i)\b(?:eval|assert|create_function|call_user_func|call_user_func_array|array_map|preg_replace|include|include_once|require|require_once)\s*\(`)},
+	{"file_sink_count", regexp.MustCompile(`(?i)\b(?:file_put_contents|fwrite|fopen|move_uploaded_file|chmod|chown|unlink|copy|rename|symlink)\s*\(`)},
+	{"decode_func_count", regexp.MustCompile(`(?i)\b(?:base64_decode|gzinflate|gzuncompress|gzdecode|str_rot13|convert_uudecode|hex2bin|strrev|pack)\s*\(`)},
+	{"network_func_count", regexp.MustCompile(`(?i)\b(?:fsockopen|socket_create|curl_exec|stream_socket_client)\s*\(`)},
+	{"variable_call_count", regexp.MustCompile(`\$\w+\s*\(`)},
+	{"chr_call_count", regexp.MustCompile(`(?i)\bchr\s*\(`)},
+	{"error_suppress_count", regexp.MustCompile(`@\s*\$?\w+\s*\(`)},
+	{"hex_escape_count", regexp.MustCompile(`\\x[0-9a-fA-F]{2}`)},
+	{"function_def_count", regexp.MustCompile(`(?i)\bfunction\b`)},
+	{"open_tag_count", regexp.MustCompile(`<\?(?:php|=)?|<%`)},
+}
+
+// scalarFeatures 除正则计数和 n-gram 外的标量特征，顺序即特征顺序
+var scalarFeatures = []string{
+	// 结构特征
+	"log_size",
+	"log_line_count",
+	"log_max_line_length",
+	"avg_line_length",
+	"max_brace_depth",
+	// 熵与字符类别频率
+	"entropy",
+	"alpha_ratio",
+	"digit_ratio",
+	"whitespace_ratio",
+	"punct_ratio",
+	"upper_ratio",
+	"nonprintable_ratio",
+	// 字符串常量统计
+	"literal_count",
+	"literal_avg_length",
+	"log_literal_max_length",
+	"literal_byte_ratio",
+	"long_literal_count",
+	"literal_max_entropy",
+}
+
+// FeatureNames 当前特征模式下全部特征的名称，顺序与 ExtractFeatures 输出一致
+var FeatureNames = buildFeatureNames()
+
+// FeatureCount 当前特征模式的特征维度
+var FeatureCount = len(FeatureNames)
+
+// buildFeatureNames 生成特征名称列表
+func buildFeatureNames() []string {
+	names := make([]string, 0, len(scalarFeatures)+len(countGroups)+NGramBuckets)
+	names = append(names, scalarFeatures...)
+	for _, group := range countGroups {
+		names = append(names, group.name)
+	}
+	for i := 0; i < NGramBuckets; i++ {
+		names = append(names, fmt.Sprintf("ngram_%02d", i))
+	}
+	return names
+}
+
+// ExtractFeatures 按当前特征模式提取文件特征，检测器与训练工具共用
+func ExtractFeatures(content []byte) []float64 {
+	features := make([]float64, 0, FeatureCount)
+	text := string(content)
+
+	// 1. 结构特征
+	lines := strings.Split(text, "\n")
+	maxLine := 0
+	for _, line := range lines {
+		if len(line) > maxLine {
+			maxLine = len(line)
+		}
+	}
+	features = append(features,
+		math.Log1p(float64(len(content))),
+		math.Log1p(float64(len(lines))),
+		math.Log1p(float64(maxLine)),
+		float64(len(content))/float64(len(lines)),
+		float64(maxBraceDepth(content)),
+	)
+
+	// 2. 熵与字符类别频率
+	features = append(features, Entropy(content))
+	features = append(features, charClassRatios(text)...)
+
+	// 3. 字符串常量与 token
+	tokens, literals := tokenize(content)
+	features = append(features, literalStats(literals, len(content))...)
+
+	// 4. 超全局变量、危险函数等计数
+	for _, group := range countGroups {
+		features = append(features, float64(len(group.pattern.FindAllStringIndex(text, -1))))
+	}
+
+	// 5. token 二元组哈希频率
+	features = append(features, ngramHashes(tokens)...)
+
+	return features
+}
+
+// Entropy 计算字节序列的香农熵（0-8）
+func Entropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+
+	entropy := 0.0
+	total := float64(len(data))
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / total
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// maxBraceDepth 计算花括号的最大嵌套深度
+func maxBraceDepth(content []byte) int {
+	depth, maxDepth := 0, 0
+	for _, b := range content {
+		switch b {
+		case '{':
+			depth++
+			if depth > maxDepth {
+				maxDepth = depth
+			}
+		case '}':
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+	return maxDepth
+}
+
+// charClassRatios 计算字母、数字、空白、标点、大写和不可打印字符的占比
+func charClassRatios(text string) []float64 {
+	var alpha, digit, space, punct, upper, nonPrintable, total int
+	for _, r := range text {
+		total++
+		switch {
+		case unicode.IsLetter(r):
+			alpha++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		case unicode.IsDigit(r):
+			digit++
+		case unicode.IsSpace(r):
+			space++
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			punct++
+		}
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			nonPrintable++
+		}
+	}
+
+	ratios := make([]float64, 6)
+	if total == 0 {
+		return ratios
+	}
+	n := float64(total)
+	ratios[0] = float64(alpha) / n
+	ratios[1] = float64(digit) / n
+	ratios[2] = float64(space) / n
+	ratios[3] = float64(punct) / n
+	ratios[4] = float64(upper) / n
+	ratios[5] = float64(nonPrintable) / n
+	return ratios
+}
+
+// tokenize 将源码切分为 token，普通变量统一为 $v、字符串常量统一为 STR，
+// 同时返回所有字符串常量的内容
+func tokenize(content []byte) ([]string, [][]byte) {
+	var tokens []string
+	var literals [][]byte
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '\'' || c == '"':
+			// 字符串常量，处理反斜杠转义
+			j := i + 1
+			for j < len(content) && content[j] != c {
+				if content[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			end := j
+			if end > len(content) {
+				end = len(content)
+			}
+			literals = append(literals, content[i+1:end])
+			tokens = append(tokens, "STR")
+			i = j + 1
+
+		case c == '$' || c == '_' || isASCIILetter(c):
+			j := i + 1
+			for j < len(content) && (content[j] == '_' || isASCIILetter(content[j]) || isASCIIDigit(content[j])) {
+				j++
+			}
+			word := string(content[i:j])
+			if c == '$' && !strings.HasPrefix(word, "$_") {
+				// 超全局变量保留原名，其余变量统一
+				tokens = append(tokens, "$v")
+			} else {
+				tokens = append(tokens, strings.ToLower(word))
+			}
+			i = j
+
+		case isASCIIDigit(c):
+			j := i + 1
+			for j < len(content) && (isASCIIDigit(content[j]) || isASCIILetter(content[j])) {
+				j++
+			}
+			tokens = append(tokens, "NUM")
+			i = j
+
+		case c <= ' ':
+			i++
+
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+
+	return tokens, literals
+}
+
+// literalStats 计算字符串常量的数量、平均长度、最大长度、字节占比、长字符串数和最大熵
+func literalStats(literals [][]byte, contentLen int) []float64 {
+	stats := make([]float64, 6)
+	if len(literals) == 0 {
+		return stats
+	}
+
+	total, maxLen, long := 0, 0, 0
+	maxEntropy := 0.0
+	for _, lit := range literals {
+		total += len(lit)
+		if len(lit) > maxLen {
+			maxLen = len(lit)
+		}
+		if len(lit) >= longLiteralLength {
+			long++
+		}
+		if e := Entropy(lit); e > maxEntropy {
+			maxEntropy = e
+		}
+	}
+
+	stats[0] = float64(len(literals))
+	stats[1] = float64(total) / float64(len(literals))
+	stats[2] = math.Log1p(float64(maxLen))
+	if contentLen > 0 {
+		stats[3] = float64(total) / float64(contentLen)
+	}
+	stats[4] = float64(long)
+	stats[5] = maxEntropy
+	return stats
+}
+
+// ngramHashes 将相邻 token 二元组哈希到固定桶中并归一化为频率
+func ngramHashes(tokens []string) []float64 {
+	buckets := make([]float64, NGramBuckets)
+	if len(tokens) < 2 {
+		return buckets
+	}
+
+	h := fnv.New32a()
+	for i := 0; i+1 < len(tokens); i++ {
+		h.Reset()
+		h.Write([]byte(tokens[i]))
+		h.Write([]byte{0})
+		h.Write([]byte(tokens[i+1]))
+		buckets[h.Sum32()%NGramBuckets]++
+	}
+
+	total := float64(len(tokens) - 1)
+	for i := range buckets {
+		buckets[i] /= total
+	}
+	return buckets
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isASCIIDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,65 @@
+package mlmodel
+
+import "fmt"
+
+// logisticPredictor 逻辑回归：特征标准化后线性组合，经 sigmoid 输出概率
+//
+// 模型文件字段：
+//
+//	"type": "logistic",
+//	"weights": [...],   // 标准化后特征的系数
+//	"intercept": -1.2,  // 截距
+//	"means": [...],     // 各特征的训练集均值
+//	"scales": [...]     // 各特征的训练集标准差，0 视为 1
+type logisticPredictor struct {
+	weights   []float64
+	intercept float64
+	means     []float64
+	scales    []float64
+}
+
+// newLogisticPredictor 根据模型数据构建逻辑回归预测器
+func newLogisticPredictor(data *ModelData) (predictor, error) {
+	if len(data.Weights) != data.FeatureCount {
+		return nil, fmt.Errorf("logistic model has %d weights for %d features", len(data.Weights), data.FeatureCount)
+	}
+
+	means := data.Means
+	if means == nil {
+		means = make([]float64, data.FeatureCount)
+	}
+	scales := data.Scales
+	if scales == nil {
+		scales = make([]float64, data.FeatureCount)
+		for i := range scales {
+			scales[i] = 1
+		}
+	}
+	if len(means) != data.FeatureCount || len(scales) != data.FeatureCount {
+		return nil, fmt.Errorf("logistic model scaling parameters do not match feature count %d", data.FeatureCount)
+	}
+
+	return &logisticPredictor{
+		weights:   data.Weights,
+		intercept: data.Intercept,
+		means:     means,
+		scales:    scales,
+	}, nil
+}
+
+func (l *logisticPredictor) predict(features []float64) float64 {
+	return sigmoid(l.margin(features))
+}
+
+// margin 计算标准化后的线性组合
+func (l *logisticPredictor) margin(features []float64) float64 {
+	z := l.intercept
+	for i, x := range features {
+		scale := l.scales[i]
+		if scale == 0 {
+			scale = 1
+		}
+		z += l.weights[i] * (x - l.means[i]) / scale
+	}
+	return z
+}
@@ -1,18 +1,49 @@
 package mlmodel
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 )
 
+// 支持的模型类型
+const (
+	ModelTypeLinear           = "linear"
+	ModelTypeLogistic         = "logistic"
+	ModelTypeRandomForest     = "random_forest"
+	ModelTypeGradientBoosting = "gradient_boosting"
+)
+
 // ModelData 模型数据结构
 type ModelData struct {
-	Weights      []float64 `json:"weights"`
-	Threshold    float64   `json:"threshold"`
-	FeatureCount int       `json:"feature_count"`
+	Type          string    `json:"type"`           // 模型类型，为空时视为 linear
+	SchemaVersion int       `json:"schema_version"` // 训练时使用的特征模式版本
+	FeatureNames  []string  `json:"feature_names"`  // 训练时使用的特征名称
+	Weights       []float64 `json:"weights"`
+	Threshold     float64   `json:"threshold"`
+	FeatureCount  int       `json:"feature_count"`
+
+	// 逻辑回归参数，见 logistic.go
+	Intercept float64   `json:"intercept,omitempty"`
+	Means     []float64 `json:"means,omitempty"`
+	Scales    []float64 `json:"scales,omitempty"`
+
+	// 树集成模型参数，格式见 tree.go
+	Trees       []Tree       `json:"trees,omitempty"`
+	BaseScore   float64      `json:"base_score,omitempty"`
+	Calibration *Calibration `json:"calibration,omitempty"`
+}
+
+// predictor 具体模型类型的预测实现，输入已通过维度检查
+type predictor interface {
+	predict(features []float64) float64
+	// explain 返回基准值和每个特征的贡献，含义见 explain.go
+	explain(features []float64) (float64, []float64)
 }
 
 // Model 机器学习模型结构
@@ -22,30 +53,100 @@ type Model struct {
 	FeatureCount int
 	ModelData    *ModelData
 	Threshold    float64
+	Checksum     string // 模型文件的 SHA-256，用于标识模型版本
+	predictor    predictor
+	canaries     []Canary // 热加载校验用的金丝雀样本
+	configured   float64  // 配置的判定阈值，热加载校验金丝雀样本时与检测器使用相同的阈值
+	override     bool     // 配置的阈值是否覆盖模型自带的阈值
+	generation   uint64   // 每次切换模型后递增，供检测器判断结果缓存是否失效
 	mu           sync.RWMutex
+	reloadMu     sync.Mutex // 使热加载依次执行
 }
 
-// LoadModel 加载机器学习模型
+// LoadModel 加载机器学习模型，与热加载一样核对校验和文件；需要校验金丝雀样本时
+// 先用 Unloaded 创建模型并设置金丝雀样本，再调用 Load
 func LoadModel(modelPath string) (*Model, error) {
-	file, err := os.ReadFile(modelPath) // 读取整个文件
+	model := Unloaded(modelPath)
+	if err := model.Load(); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// Unloaded 返回尚未加载的模型，只记录模型文件路径，用于初始加载失败时监控模型文件，
+// 文件修复后经热加载切换为可用模型；加载前预测返回错误
+func Unloaded(modelPath string) *Model {
+	return &Model{Path: modelPath}
+}
+
+// Loaded 模型是否已加载
+func (m *Model) Loaded() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.predictor != nil
+}
+
+// errNotLoaded 模型尚未加载
+var errNotLoaded = fmt.Errorf("model not loaded")
+
+// NewModel 从内存中的模型数据创建模型，用于训练后直接评估
+func NewModel(data *ModelData) (*Model, error) {
+	encoded, err := json.Marshal(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open model file: %v", err)
+		return nil, fmt.Errorf("failed to encode model: %v", err)
 	}
+	return newModel(data, checksum(encoded))
+}
 
-	var modelData ModelData
-	if err := json.Unmarshal(file, &modelData); err != nil {
-		return nil, fmt.Errorf("failed to decode model: %v", err)
+// newModel 校验模型数据并构建预测器
+func newModel(data *ModelData, sum string) (*Model, error) {
+	if err := checkSchema(data); err != nil {
+		return nil, err
 	}
 
-	model := &Model{
-		Path:         modelPath,
+	p, err := newPredictor(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Model{
 		LastUpdate:   time.Now(),
-		FeatureCount: modelData.FeatureCount,
-		ModelData:    &modelData,
-		Threshold:    modelData.Threshold,
+		FeatureCount: data.FeatureCount,
+		ModelData:    data,
+		Threshold:    data.Threshold,
+		Checksum:     sum,
+		predictor:    p,
+	}, nil
+}
+
+// SaveModel 将模型数据写入文件
+func SaveModel(path string, data *ModelData) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode model: %v", err)
 	}
 
-	return model, nil
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create model directory: %v", err)
+	}
+
+	if err := writeFileAtomic(path, encoded); err != nil {
+		return fmt.Errorf("failed to write model: %v", err)
+	}
+	return nil
+}
+
+// writeFileAtomic 先写临时文件再重命名，读取方不会看到写了一半的文件
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
 }
 
 // Predict 使用模型进行预测
@@ -53,14 +154,61 @@ func (m *Model) Predict(features []float64) (float64, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	if m.predictor == nil {
+		return 0, errNotLoaded
+	}
 	if len(features) != m.FeatureCount {
 		return 0, fmt.Errorf("invalid feature count: expected %d, got %d", m.FeatureCount, len(features))
 	}
 
+	return m.predictor.predict(features), nil
+}
+
+// PredictBatch 批量预测，整批只加一次锁，保证同一批次使用同一版本的模型，返回分数和该模型版本
+func (m *Model) PredictBatch(batch [][]float64) ([]float64, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.predictor == nil {
+		return nil, "", errNotLoaded
+	}
+	scores := make([]float64, len(batch))
+	for i, features := range batch {
+		if len(features) != m.FeatureCount {
+			return nil, "", fmt.Errorf("invalid feature count at index %d: expected %d, got %d", i, m.FeatureCount, len(features))
+		}
+		scores[i] = m.predictor.predict(features)
+	}
+	return scores, m.Checksum, nil
+}
+
+// newPredictor 根据模型类型构建预测器
+func newPredictor(data *ModelData) (predictor, error) {
+	switch data.Type {
+	case "", ModelTypeLinear:
+		if len(data.Weights) != data.FeatureCount {
+			return nil, fmt.Errorf("linear model has %d weights for %d features", len(data.Weights), data.FeatureCount)
+		}
+		return &linearPredictor{weights: data.Weights}, nil
+	case ModelTypeLogistic:
+		return newLogisticPredictor(data)
+	case ModelTypeRandomForest, ModelTypeGradientBoosting:
+		return newTreePredictor(data)
+	default:
+		return nil, fmt.Errorf("unsupported model type: %s", data.Type)
+	}
+}
+
+// linearPredictor 线性模型：加权求和后截断到 0-1
+type linearPredictor struct {
+	weights []float64
+}
+
+func (l *linearPredictor) predict(features []float64) float64 {
 	// 使用加载的权重进行预测
 	score := 0.0
 	for i, feature := range features {
-		score += feature * m.ModelData.Weights[i]
+		score += feature * l.weights[i]
 	}
 
 	// 归一化得分到 0-1 范围
@@ -70,50 +218,31 @@ func (m *Model) Predict(features []float64) (float64, error) {
 		score = 0.0
 	}
 
-	return score, nil
+	return score
 }
 
-// UpdateModel 更新模型
-func (m *Model) UpdateModel(newModelPath string) error {
-	// 创建备份
-	backupPath := m.Path + ".backup"
-	if err := copyFile(m.Path, backupPath); err != nil {
-		return fmt.Errorf("failed to create backup: %v", err)
-	}
-
-	// 加载新模型
-	file, err := os.ReadFile(newModelPath)
-	if err != nil {
-		return fmt.Errorf("failed to open new model file: %v", err)
-	}
-
-	var newModelData ModelData
-	if err := json.Unmarshal(file, &newModelData); err != nil {
-		// 恢复备份
-		if restoreErr := copyFile(backupPath, m.Path); restoreErr != nil {
-			return fmt.Errorf("failed to decode new model and restore backup: %v, %v", err, restoreErr)
-		}
-		return fmt.Errorf("failed to decode new model: %v", err)
-	}
+// GetThreshold 获取模型自带的判定阈值
+func (m *Model) GetThreshold() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.Threshold
+}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// DefaultThreshold 配置和模型都未指定阈值时使用的判定阈值
+const DefaultThreshold = 0.5
 
-	// 复制新模型到原位置
-	if err := copyFile(newModelPath, m.Path); err != nil {
-		return fmt.Errorf("failed to copy new model: %v", err)
+// ResolveThreshold 返回实际使用的判定阈值及其来源：override 时使用配置的阈值，
+// 否则模型自带的（训练时校准的）阈值优先，其次为配置的阈值，都没有时使用默认值
+func ResolveThreshold(modelThreshold, configured float64, override bool) (float64, string) {
+	switch {
+	case configured > 0 && override:
+		return configured, "configuration (override_threshold)"
+	case modelThreshold > 0:
+		return modelThreshold, "model"
+	case configured > 0:
+		return configured, "configuration"
 	}
-
-	// 更新模型数据
-	m.ModelData = &newModelData
-	m.LastUpdate = time.Now()
-	m.FeatureCount = newModelData.FeatureCount
-	m.Threshold = newModelData.Threshold
-
-	// 删除备份
-	os.Remove(backupPath)
-
-	return nil
+	return DefaultThreshold, "default"
 }
 
 // SetThreshold 设置预测阈值
@@ -136,26 +265,62 @@ func (m *Model) GetModelInfo() map[string]interface{} {
 
 	return map[string]interface{}{
 		"path":          m.Path,
+		"type":          m.modelType(),
 		"last_update":   m.LastUpdate,
 		"feature_count": m.FeatureCount,
 		"threshold":     m.Threshold,
+		"checksum":      m.Checksum,
 	}
 }
 
-// copyFile 复制文件
-func copyFile(src, dst string) error {
-	sourceFile, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer sourceFile.Close()
+// Type 返回模型类型
+func (m *Model) Type() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.modelType()
+}
 
-	destFile, err := os.Create(dst)
-	if err != nil {
-		return err
+// modelType 返回模型类型，调用方需持有锁
+func (m *Model) modelType() string {
+	if m.ModelData == nil || m.ModelData.Type == "" {
+		return ModelTypeLinear
 	}
-	defer destFile.Close()
+	return m.ModelData.Type
+}
+
+// Generation 返回模型的切换次数，热加载或更新模型后递增
+func (m *Model) Generation() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.generation
+}
 
-	_, err = destFile.ReadFrom(sourceFile)
-	return err
+// Version 返回当前模型的版本标识
+func (m *Model) Version() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.Checksum
+}
+
+// checksum 计算模型文件内容的 SHA-256
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkSchema 检查模型是否基于当前特征模式训练
+func checkSchema(data *ModelData) error {
+	if data.SchemaVersion != FeatureSchemaVersion {
+		return fmt.Errorf("model feature schema version %d does not match detector schema version %d, retrain the model",
+			data.SchemaVersion, FeatureSchemaVersion)
+	}
+	if data.FeatureCount != FeatureCount {
+		return fmt.Errorf("model feature count %d does not match schema feature count %d", data.FeatureCount, FeatureCount)
+	}
+	for i, name := range data.FeatureNames {
+		if i >= len(FeatureNames) || FeatureNames[i] != name {
+			return fmt.Errorf("model feature %d (%s) does not match schema", i, name)
+		}
+	}
+	return nil
 }
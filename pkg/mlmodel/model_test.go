@@ -0,0 +1,211 @@
+package mlmodel
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// schemaModel 基于当前特征模式的指定类型模型，其余参数由调用方填写
+func schemaModel(modelType string) *ModelData {
+	return &ModelData{
+		Type:          modelType,
+		SchemaVersion: FeatureSchemaVersion,
+		FeatureNames:  FeatureNames,
+		FeatureCount:  FeatureCount,
+		Threshold:     0.5,
+	}
+}
+
+// featureVector 前几个特征取给定值，其余为 0
+func featureVector(values ...float64) []float64 {
+	features := make([]float64, FeatureCount)
+	copy(features, values)
+	return features
+}
+
+// stump 按特征 feature 是否 <= threshold 输出 left 或 right 的单层树
+func stump(feature int, threshold, left, right float64) Tree {
+	return Tree{Nodes: []TreeNode{
+		{Feature: feature, Threshold: threshold, Left: 1, Right: 2},
+		{Left: -1, Right: -1, Value: left},
+		{Left: -1, Right: -1, Value: right},
+	}}
+}
+
+const epsilon = 1e-9
+
+func TestTreeEvaluate(t *testing.T) {
+	// 根按特征 0 分裂，右子树再按特征 1 分裂
+	tree := Tree{Nodes: []TreeNode{
+		{Feature: 0, Threshold: 1.5, Left: 1, Right: 2},
+		{Left: -1, Right: -1, Value: 0.1},
+		{Feature: 1, Threshold: 0, Left: 3, Right: 4},
+		{Left: -1, Right: -1, Value: 0.4},
+		{Left: -1, Right: -1, Value: 0.9},
+	}}
+	if err := tree.validate(FeatureCount); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		features []float64
+		want     float64
+	}{
+		{featureVector(1, 5), 0.1},
+		{featureVector(1.5, 5), 0.1}, // 等于阈值走左子树
+		{featureVector(2, 0), 0.4},
+		{featureVector(2, 0.1), 0.9},
+	}
+	for _, tt := range tests {
+		if got := tree.evaluate(tt.features); got != tt.want {
+			t.Errorf("evaluate(%v) = %g, want %g", tt.features[:2], got, tt.want)
+		}
+	}
+}
+
+func TestTreeEnsemblePredict(t *testing.T) {
+	forest := schemaModel(ModelTypeRandomForest)
+	forest.Trees = []Tree{stump(0, 0.5, 0.2, 0.8), stump(1, 0.5, 0.0, 1.0)}
+
+	boosting := schemaModel(ModelTypeGradientBoosting)
+	boosting.BaseScore = -1
+	boosting.Trees = []Tree{stump(0, 0.5, -0.5, 1.5), stump(1, 0.5, 0, 0.25)}
+
+	calibrated := schemaModel(ModelTypeGradientBoosting)
+	calibrated.Trees = boosting.Trees
+	calibrated.BaseScore = boosting.BaseScore
+	calibrated.Calibration = &Calibration{A: 2, B: 0.5}
+
+	tests := []struct {
+		name     string
+		data     *ModelData
+		features []float64
+		want     float64
+	}{
+		{"forest averages leaves", forest, featureVector(0, 0), 0.1},
+		{"forest mixed", forest, featureVector(1, 0), 0.4},
+		{"forest all malicious", forest, featureVector(1, 1), 0.9},
+		{"boosting margin", boosting, featureVector(0, 0), 1 / (1 + math.Exp(1.5))},
+		{"boosting sums trees", boosting, featureVector(1, 1), 1 / (1 + math.Exp(-0.75))},
+		{"boosting calibration", calibrated, featureVector(1, 1), 1 / (1 + math.Exp(-(2*0.75 + 0.5)))},
+	}
+	for _, tt := range tests {
+		model, err := NewModel(tt.data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, err := model.Predict(tt.features)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if math.Abs(got-tt.want) > epsilon {
+			t.Errorf("%s: Predict = %.6f, want %.6f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLogisticPredict(t *testing.T) {
+	data := schemaModel(ModelTypeLogistic)
+	data.Weights = make([]float64, FeatureCount)
+	data.Weights[0], data.Weights[1], data.Weights[2] = 2, -1, 3
+	data.Intercept = -0.5
+	data.Means = make([]float64, FeatureCount)
+	data.Means[0], data.Means[1], data.Means[2] = 10, 1, 4
+	data.Scales = make([]float64, FeatureCount)
+	for i := range data.Scales {
+		data.Scales[i] = 1
+	}
+	data.Scales[0] = 5
+	data.Scales[2] = 0 // 训练集中恒定的特征，按 1 处理
+
+	model, err := NewModel(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		features []float64
+		margin   float64
+	}{
+		{featureVector(10, 1, 4), -0.5},
+		{featureVector(20, 1, 4), -0.5 + 2*2},
+		{featureVector(10, 3, 4), -0.5 - 2},
+		{featureVector(10, 1, 5), -0.5 + 3},
+	}
+	for _, tt := range tests {
+		got, err := model.Predict(tt.features)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := 1 / (1 + math.Exp(-tt.margin))
+		if math.Abs(got-want) > epsilon {
+			t.Errorf("Predict(%v) = %.6f, want sigmoid(%g) = %.6f", tt.features[:3], got, tt.margin, want)
+		}
+	}
+
+	// 未带标准化参数时按原始特征计算
+	data.Means, data.Scales = nil, nil
+	model, err = NewModel(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := model.Predict(featureVector(1, 1, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 1 / (1 + math.Exp(-(-0.5 + 2 - 1 + 3))); math.Abs(got-want) > epsilon {
+		t.Errorf("Predict without scaling = %.6f, want %.6f", got, want)
+	}
+}
+
+func TestNewModelRejectsInvalidModels(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*ModelData)
+		wantErr string
+	}{
+		{"old schema", func(d *ModelData) { d.SchemaVersion = FeatureSchemaVersion - 1 }, "schema version"},
+		{"feature count", func(d *ModelData) { d.FeatureCount = FeatureCount - 1 }, "feature count"},
+		{"feature names", func(d *ModelData) {
+			d.FeatureNames = append([]string{"renamed"}, FeatureNames[1:]...)
+		}, "does not match schema"},
+		{"linear weights", func(d *ModelData) { d.Type = ModelTypeLinear; d.Weights = []float64{1} }, "weights"},
+		{"logistic weights", func(d *ModelData) { d.Type = ModelTypeLogistic; d.Weights = []float64{1} }, "weights"},
+		{"logistic scales", func(d *ModelData) {
+			d.Type = ModelTypeLogistic
+			d.Weights = make([]float64, FeatureCount)
+			d.Scales = []float64{1}
+		}, "scaling parameters"},
+		{"no trees", func(d *ModelData) { d.Trees = nil }, "no trees"},
+		{"feature out of range", func(d *ModelData) { d.Trees = []Tree{stump(FeatureCount, 0, 0, 1)} }, "out of range"},
+		{"child before parent", func(d *ModelData) {
+			d.Trees = []Tree{{Nodes: []TreeNode{{Feature: 0, Left: 0, Right: 1}, {Left: -1}}}}
+		}, "invalid child index"},
+		{"child out of range", func(d *ModelData) {
+			d.Trees = []Tree{{Nodes: []TreeNode{{Feature: 0, Left: 1, Right: 5}, {Left: -1}}}}
+		}, "invalid child index"},
+		{"unknown type", func(d *ModelData) { d.Type = "svm" }, "unsupported model type"},
+	}
+	for _, tt := range tests {
+		data := schemaModel(ModelTypeRandomForest)
+		data.Trees = []Tree{stump(0, 0.5, 0, 1)}
+		tt.modify(data)
+		_, err := NewModel(data)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: NewModel error = %v, want error containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPredictRejectsWrongDimension(t *testing.T) {
+	model, err := NewModel(linearModelData(0.01, 0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := model.Predict(make([]float64, FeatureCount-1)); err == nil {
+		t.Error("Predict accepted a feature vector of the wrong length")
+	}
+	if _, _, err := model.PredictBatch([][]float64{featureVector(), make([]float64, FeatureCount+1)}); err == nil {
+		t.Error("PredictBatch accepted a feature vector of the wrong length")
+	}
+}
@@ -0,0 +1,180 @@
+package mlmodel
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// checksumSuffix 可选的校验和文件后缀，内容为模型文件的 SHA-256
+const checksumSuffix = ".sha256"
+
+// Canary 金丝雀样本：新模型必须对其给出与标签一致的判定才能上线
+type Canary struct {
+	Name      string
+	Features  []float64
+	Malicious bool
+}
+
+// errUnchanged 模型文件内容与当前模型相同，未切换模型
+var errUnchanged = fmt.Errorf("model unchanged")
+
+// SetCanaries 设置热加载时用于校验新模型的金丝雀样本
+func (m *Model) SetCanaries(canaries []Canary) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.canaries = canaries
+}
+
+// CanaryCount 返回金丝雀样本数
+func (m *Model) CanaryCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.canaries)
+}
+
+// SetConfiguredThreshold 设置配置的判定阈值，热加载时按与检测器相同的规则（见 ResolveThreshold）
+// 确定金丝雀样本的判定阈值
+func (m *Model) SetConfiguredThreshold(threshold float64, override bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.configured = threshold
+	m.override = override
+}
+
+// Load 读取模型文件，执行与热加载相同的校验后切换，启动时加载的模型不会绕过
+// 校验和文件和金丝雀样本的检查
+func (m *Model) Load() error {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	file, err := os.ReadFile(m.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open model file: %v", err)
+	}
+	candidate, err := m.validateCandidate(file, m.Path)
+	if err != nil {
+		return err
+	}
+
+	m.swap(candidate)
+	return nil
+}
+
+// Reload 重新读取模型文件，校验通过后原子切换；校验失败时继续使用内存中的当前模型，
+// 不改动模型文件，之后写入校验和文件或修复模型文件会再次触发校验。
+// 多次重新加载依次执行，内容与当前模型相同时返回 errUnchanged
+func (m *Model) Reload() error {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	file, err := os.ReadFile(m.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read model file: %v", err)
+	}
+
+	// 内容未变化无需处理
+	if checksum(file) == m.Version() {
+		return errUnchanged
+	}
+
+	candidate, err := m.validateCandidate(file, m.Path)
+	if err != nil {
+		if !m.Loaded() {
+			return fmt.Errorf("new model rejected, ML detection stays disabled: %v", err)
+		}
+		return fmt.Errorf("new model rejected, still serving %s: %v", ShortVersion(m.Version()), err)
+	}
+
+	m.swap(candidate)
+	return nil
+}
+
+// validateCandidate 校验候选模型：格式与特征模式、维度与树结构、校验和、金丝雀样本预测
+func (m *Model) validateCandidate(file []byte, path string) (*Model, error) {
+	var data ModelData
+	if err := json.Unmarshal(file, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode model: %v", err)
+	}
+
+	sum := checksum(file)
+	candidate, err := newModel(&data, sum)
+	if err != nil {
+		return nil, err
+	}
+	candidate.Path = m.Path
+
+	if err := verifyChecksum(path, sum); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	canaries := m.canaries
+	threshold, _ := ResolveThreshold(candidate.Threshold, m.configured, m.override)
+	m.mu.RUnlock()
+	if err := candidate.checkCanaries(canaries, threshold); err != nil {
+		return nil, err
+	}
+
+	return candidate, nil
+}
+
+// checkCanaries 按检测器实际使用的判定阈值检查模型对金丝雀样本的预测
+func (m *Model) checkCanaries(canaries []Canary, threshold float64) error {
+	for _, canary := range canaries {
+		if len(canary.Features) != m.FeatureCount {
+			return fmt.Errorf("canary %s has %d features, model expects %d", canary.Name, len(canary.Features), m.FeatureCount)
+		}
+		p := m.predictor.predict(canary.Features)
+		if math.IsNaN(p) || math.IsInf(p, 0) || p < 0 || p > 1 {
+			return fmt.Errorf("canary %s: invalid prediction %v", canary.Name, p)
+		}
+		if (p >= threshold) != canary.Malicious {
+			return fmt.Errorf("canary %s misclassified (score %.4f, threshold %g)", canary.Name, p, threshold)
+		}
+	}
+	return nil
+}
+
+// verifyChecksum 存在校验和文件时核对模型文件的 SHA-256，不存在时输出警告
+func verifyChecksum(path, sum string) error {
+	content, err := os.ReadFile(path + checksumSuffix)
+	if os.IsNotExist(err) {
+		fmt.Printf("Warning: No checksum file %s, integrity of model %s is not verified\n", path+checksumSuffix, ShortVersion(sum))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read checksum file: %v", err)
+	}
+
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 || !strings.EqualFold(fields[0], sum) {
+		return fmt.Errorf("checksum mismatch: model file does not match %s", path+checksumSuffix)
+	}
+	return nil
+}
+
+// swap 原子切换为候选模型
+func (m *Model) swap(candidate *Model) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ModelData = candidate.ModelData
+	m.LastUpdate = time.Now()
+	m.FeatureCount = candidate.FeatureCount
+	m.Threshold = candidate.Threshold
+	m.Checksum = candidate.Checksum
+	m.predictor = candidate.predictor
+	m.generation++
+}
+
+// ShortVersion 返回便于展示的短版本号
+func ShortVersion(version string) string {
+	if len(version) > 12 {
+		return version[:12]
+	}
+	return version
+}
@@ -0,0 +1,142 @@
+package mlmodel
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// linearModelData 基于当前特征模式的线性模型，所有特征权重相同
+func linearModelData(weight, threshold float64) *ModelData {
+	weights := make([]float64, FeatureCount)
+	for i := range weights {
+		weights[i] = weight
+	}
+	return &ModelData{
+		SchemaVersion: FeatureSchemaVersion,
+		FeatureNames:  FeatureNames,
+		FeatureCount:  FeatureCount,
+		Weights:       weights,
+		Threshold:     threshold,
+	}
+}
+
+// writeModel 写入模型文件，返回文件内容
+func writeModel(t *testing.T, path string, data *ModelData) []byte {
+	t.Helper()
+	if err := SaveModel(path, data); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestReloadKeepsModelFileOnRejection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.json")
+	writeModel(t, path, linearModelData(0.01, 0.5))
+	model, err := LoadModel(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := model.Version()
+
+	// 先复制模型，校验和文件尚是旧模型的
+	if err := os.WriteFile(path+checksumSuffix, []byte(original+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	updated := writeModel(t, path, linearModelData(0.02, 0.6))
+	if err := model.Reload(); err == nil {
+		t.Fatal("Reload accepted a model that does not match its checksum file")
+	}
+	if model.Version() != original {
+		t.Errorf("rejected reload changed the active version")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, updated) {
+		t.Fatal("rejected reload overwrote the model file")
+	}
+
+	// 随后写入校验和文件，再次校验后切换
+	if err := os.WriteFile(path+checksumSuffix, []byte(checksum(updated)+"  model.json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.Reload(); err != nil {
+		t.Fatalf("Reload after checksum update: %v", err)
+	}
+	if model.Version() != checksum(updated) {
+		t.Errorf("active version = %s, want %s", ShortVersion(model.Version()), ShortVersion(checksum(updated)))
+	}
+	if got := model.GetThreshold(); got != 0.6 {
+		t.Errorf("threshold after reload = %g, want 0.6", got)
+	}
+	if err := model.Reload(); err != errUnchanged {
+		t.Errorf("Reload of the active model = %v, want errUnchanged", err)
+	}
+}
+
+func TestReloadRejectsInvalidModel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.json")
+	writeModel(t, path, linearModelData(0.01, 0.5))
+	model, err := LoadModel(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := model.Version()
+
+	broken, err := json.Marshal(linearModelData(0.01, 0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken = broken[:len(broken)/2]
+	if err := os.WriteFile(path, broken, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.Reload(); err == nil {
+		t.Fatal("Reload accepted a truncated model")
+	}
+	if model.Version() != original || !model.Loaded() {
+		t.Errorf("rejected reload replaced the active model")
+	}
+}
+
+func TestLoadVerifiesLikeReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.json")
+	content := writeModel(t, path, linearModelData(0.01, 0.5))
+
+	// 启动时同样核对校验和文件
+	if err := os.WriteFile(path+checksumSuffix, []byte(checksum([]byte("other"))+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadModel(path); err == nil {
+		t.Fatal("LoadModel accepted a model that does not match its checksum file")
+	}
+	if err := os.WriteFile(path+checksumSuffix, []byte(checksum(content)+"  model.json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadModel(path); err != nil {
+		t.Fatalf("LoadModel with a matching checksum file: %v", err)
+	}
+
+	// 启动时同样校验金丝雀样本：特征全为 0 时线性模型得分为 0
+	clean := make([]float64, FeatureCount)
+	model := Unloaded(path)
+	model.SetCanaries([]Canary{{Name: "shell.php", Features: clean, Malicious: true}})
+	if err := model.Load(); err == nil || model.Loaded() {
+		t.Fatalf("Load accepted a model that misclassifies a canary: %v", err)
+	}
+	model.SetCanaries([]Canary{{Name: "index.php", Features: clean}})
+	if err := model.Load(); err != nil || !model.Loaded() {
+		t.Fatalf("Load with passing canaries: %v", err)
+	}
+	if model.Version() != checksum(content) {
+		t.Errorf("loaded version = %s, want %s", ShortVersion(model.Version()), ShortVersion(checksum(content)))
+	}
+}
@@ -0,0 +1,170 @@
+package mlmodel
+
+import (
+	"fmt"
+	"math"
+)
+
+// 树集成模型的 JSON 格式（与 ModelData 共用顶层字段）：
+//
+//	{
+//	  "type": "random_forest" | "gradient_boosting",
+//	  "schema_version": 2,
+//	  "feature_names": [...],
+//	  "feature_count": 62,
+//	  "threshold": 0.5,
+//	  "base_score": 0.0,                 // 仅 gradient_boosting：初始 margin
+//	  "calibration": {"a": 1.0, "b": 0.0}, // 可选：Platt 校准 p = sigmoid(a*margin + b)
+//	  "trees": [
+//	    {"nodes": [
+//	      {"feature": 3, "threshold": 1.5, "left": 1, "right": 2},
+//	      {"left": -1, "right": -1, "value": 0.1, "cover": 80},
+//	      {"left": -1, "right": -1, "value": 0.9, "cover": 20}
+//	    ]}
+//	  ]
+//	}
+//
+// 节点按数组下标引用，根节点为 0，子节点下标必须大于父节点；left 为 -1 表示叶子。
+// 分裂规则与 scikit-learn 一致：features[feature] <= threshold 走左子树。
+// random_forest 的叶子值为恶意类别概率，预测结果为所有树的平均值；
+// gradient_boosting 的叶子值为已乘学习率的 margin 增量，预测结果为 sigmoid(base_score + Σ叶子值)。
+// cover 为训练时落入该节点的样本数，可选，仅用于计算特征贡献（见 explain.go）。
+
+// TreeNode 决策树节点
+type TreeNode struct {
+	Feature   int     `json:"feature"`
+	Threshold float64 `json:"threshold"`
+	Left      int     `json:"left"`
+	Right     int     `json:"right"`
+	Value     float64 `json:"value"`
+	Cover     float64 `json:"cover,omitempty"`
+}
+
+// Tree 决策树
+type Tree struct {
+	Nodes []TreeNode `json:"nodes"`
+}
+
+// Calibration Platt 校准参数
+type Calibration struct {
+	A float64 `json:"a"`
+	B float64 `json:"b"`
+}
+
+// probabilityEpsilon 概率转 margin 时的截断值，避免 logit 溢出
+const probabilityEpsilon = 1e-6
+
+// isLeaf 判断节点是否为叶子
+func (n *TreeNode) isLeaf() bool {
+	return n.Left < 0
+}
+
+// validate 检查树结构，保证预测时不会越界或死循环
+func (t *Tree) validate(featureCount int) error {
+	if len(t.Nodes) == 0 {
+		return fmt.Errorf("empty tree")
+	}
+	for i, node := range t.Nodes {
+		if node.isLeaf() {
+			continue
+		}
+		if node.Feature < 0 || node.Feature >= featureCount {
+			return fmt.Errorf("node %d: feature index %d out of range", i, node.Feature)
+		}
+		if node.Left <= i || node.Left >= len(t.Nodes) || node.Right <= i || node.Right >= len(t.Nodes) {
+			return fmt.Errorf("node %d: invalid child index (%d, %d)", i, node.Left, node.Right)
+		}
+	}
+	return nil
+}
+
+// evaluate 沿树走到叶子，返回叶子值
+func (t *Tree) evaluate(features []float64) float64 {
+	i := 0
+	for {
+		node := &t.Nodes[i]
+		if node.isLeaf() {
+			return node.Value
+		}
+		if features[node.Feature] <= node.Threshold {
+			i = node.Left
+		} else {
+			i = node.Right
+		}
+	}
+}
+
+// forestPredictor 随机森林：叶子概率取平均
+type forestPredictor struct {
+	trees       []Tree
+	expected    [][]float64 // 各树节点的期望输出，用于计算特征贡献
+	calibration *Calibration
+}
+
+func (f *forestPredictor) predict(features []float64) float64 {
+	sum := 0.0
+	for i := range f.trees {
+		sum += f.trees[i].evaluate(features)
+	}
+	p := sum / float64(len(f.trees))
+
+	if f.calibration != nil {
+		p = clampProbability(p)
+		return f.calibration.apply(math.Log(p / (1 - p)))
+	}
+	return math.Max(0, math.Min(1, p))
+}
+
+// boostingPredictor 梯度提升树：margin 累加后取 sigmoid
+type boostingPredictor struct {
+	trees       []Tree
+	expected    [][]float64
+	baseScore   float64
+	calibration *Calibration
+}
+
+func (b *boostingPredictor) predict(features []float64) float64 {
+	margin := b.baseScore
+	for i := range b.trees {
+		margin += b.trees[i].evaluate(features)
+	}
+
+	if b.calibration != nil {
+		return b.calibration.apply(margin)
+	}
+	return sigmoid(margin)
+}
+
+// apply 对 margin 应用 Platt 校准
+func (c *Calibration) apply(margin float64) float64 {
+	return sigmoid(c.A*margin + c.B)
+}
+
+// newTreePredictor 根据模型数据构建树集成预测器
+func newTreePredictor(data *ModelData) (predictor, error) {
+	if len(data.Trees) == 0 {
+		return nil, fmt.Errorf("%s model has no trees", data.Type)
+	}
+	expected := make([][]float64, len(data.Trees))
+	for i := range data.Trees {
+		if err := data.Trees[i].validate(data.FeatureCount); err != nil {
+			return nil, fmt.Errorf("invalid tree %d: %v", i, err)
+		}
+		expected[i] = data.Trees[i].expectations()
+	}
+
+	if data.Type == ModelTypeRandomForest {
+		return &forestPredictor{trees: data.Trees, expected: expected, calibration: data.Calibration}, nil
+	}
+	return &boostingPredictor{trees: data.Trees, expected: expected, baseScore: data.BaseScore, calibration: data.Calibration}, nil
+}
+
+// sigmoid 逻辑函数
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// clampProbability 将概率限制在 (0,1) 开区间内
+func clampProbability(p float64) float64 {
+	return math.Max(probabilityEpsilon, math.Min(1-probabilityEpsilon, p))
+}
@@ -0,0 +1,66 @@
+package mlmodel
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce 模型文件变化后等待写入完成的时间
+const reloadDebounce = time.Second
+
+// Watch 监控模型文件及其校验和文件的变化并自动热加载，每次切换或拒绝新模型后调用 onReload，
+// 文件内容与当前模型相同时不调用；返回停止监控的函数
+func (m *Model) Watch(onReload func(version string, err error)) (func() error, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create model watcher: %v", err)
+	}
+
+	// 监控所在目录，模型文件通过重命名替换时也能收到事件
+	if err := watcher.Add(filepath.Dir(m.Path)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch model directory: %v", err)
+	}
+
+	// 先复制模型再写入校验和文件时，模型会因校验和不一致被拒绝，校验和文件写入后需要再次校验
+	target := filepath.Clean(m.Path)
+	sumFile := target + checksumSuffix
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				name := filepath.Clean(event.Name)
+				if (name != target && name != sumFile) || event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) == 0 {
+					continue
+				}
+
+				// 合并短时间内的多次写入，Reload 本身依次执行，定时器回调重叠时不会同时校验
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDebounce, func() {
+					err := m.Reload()
+					if err == errUnchanged {
+						return
+					}
+					onReload(m.Version(), err)
+				})
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				onReload(m.Version(), fmt.Errorf("model watcher error: %v", err))
+			}
+		}
+	}()
+
+	return watcher.Close, nil
+}
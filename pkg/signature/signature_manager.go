@@ -1,7 +1,9 @@
 package signature
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -34,6 +36,7 @@ type Manager struct {
 	mu         sync.RWMutex
 	lastUpdate time.Time
 	dbPath     string
+	generation uint64 // 特征每次变化后递增，供检测器判断结果缓存是否失效
 }
 
 // NewManager 创建特征库管理器
@@ -128,6 +131,7 @@ func (m *Manager) loadSignatures() error {
 
 	m.signatures = signatures
 	m.lastUpdate = time.Now()
+	m.generation++
 	return nil
 }
 
@@ -138,6 +142,25 @@ func (m *Manager) GetSignatures() []Signature {
 	return m.signatures
 }
 
+// Generation 返回特征集合的版本号，特征增删改或重新加载后递增
+func (m *Manager) Generation() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.generation
+}
+
+// Fingerprint 返回当前特征集合的摘要，特征增删改后摘要随之变化
+func (m *Manager) Fingerprint() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	h := sha256.New()
+	for _, sig := range m.signatures {
+		fmt.Fprintf(h, "%d\x00%s\x00%s\x00%g\x00%s\n", sig.ID, sig.Pattern, sig.Type, sig.Weight, sig.Category)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // AddSignature 添加新特征
 func (m *Manager) AddSignature(sig Signature) error {
 	m.mu.Lock()
@@ -154,6 +177,7 @@ func (m *Manager) AddSignature(sig Signature) error {
 	id, _ := result.LastInsertId()
 	sig.ID = int(id)
 	m.signatures = append(m.signatures, sig)
+	m.generation++
 	return nil
 }
 
@@ -179,6 +203,7 @@ func (m *Manager) UpdateSignature(sig Signature) error {
 			break
 		}
 	}
+	m.generation++
 	return nil
 }
 
@@ -199,6 +224,7 @@ func (m *Manager) DeleteSignature(id int) error {
 			break
 		}
 	}
+	m.generation++
 	return nil
 }
 
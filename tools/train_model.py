@@ -1,104 +1,103 @@
+import csv
+import json
 import os
-import pickle
+import sys
+
 import numpy as np
-import json
 from sklearn.ensemble import RandomForestClassifier
 from sklearn.model_selection import train_test_split
 from sklearn.metrics import classification_report
 
-def extract_features(file_path):
-    """提取文件特征"""
-    try:
-        with open(file_path, 'r', encoding='utf-8', errors='ignore') as f:
-            content = f.read()
-            
-        features = []
-        
-        # 特征1: 文件大小
-        features.append(len(content))
-        
-        # 特征2: 危险函数的数量
-        dangerous_funcs = ['eval(', 'system(', 'exec(', 'shell_exec(', 'passthru(']
-        count = sum(content.count(func) for func in dangerous_funcs)
-        features.append(count)
-        
-        # 特征3: base64编码字符串的数量
-        features.append(content.count('base64_decode'))
-        
-        # 特征4: $_POST, $_GET 等超全局变量的使用数量
-        super_globals = ['$_POST', '$_GET', '$_REQUEST', '$_SERVER']
-        count = sum(content.count(sg) for sg in super_globals)
-        features.append(count)
-        
-        # 特征5: 文件权限相关函数的使用数量
-        perm_funcs = ['chmod(', 'chown(', 'fopen(', 'file_put_contents(']
-        count = sum(content.count(func) for func in perm_funcs)
-        features.append(count)
-        
-        return features
-    except Exception as e:
-        print(f"Error processing {file_path}: {e}")
-        return None
-
-def collect_samples(normal_dir, webshell_dir):
-    """收集训练样本"""
-    X = []  # 特征
-    y = []  # 标签
-    
-    # 收集正常文件样本
-    for root, _, files in os.walk(normal_dir):
-        for file in files:
-            if file.endswith('.php'):
-                file_path = os.path.join(root, file)
-                features = extract_features(file_path)
-                if features:
-                    X.append(features)
-                    y.append(0)  # 正常文件标记为0
-    
-    # 收集webshell样本
-    for root, _, files in os.walk(webshell_dir):
-        for file in files:
-            if file.endswith('.php'):
-                file_path = os.path.join(root, file)
-                features = extract_features(file_path)
-                if features:
-                    X.append(features)
-                    y.append(1)  # webshell文件标记为1
-    
-    return np.array(X), np.array(y)
-
-def save_model_for_go(model, feature_weights, path):
-    """保存一个简化的模型格式供 Go 程序使用"""
+# 特征由 Go 侧统一提取，先导出特征文件：
+#   ./webshell-detector features -benign training_data/normal \
+#       -malicious training_data/webshell -output data/features.csv
+FEATURES_CSV = "data/features.csv"
+
+
+def load_features(path):
+    """读取 Go 导出的特征文件，返回特征矩阵、标签、特征名称和特征模式版本"""
+    X = []
+    y = []
+    schema_versions = set()
+
+    with open(path, newline='') as f:
+        reader = csv.reader(f)
+        header = next(reader)
+        feature_names = header[3:]  # path,label,schema_version 之后为特征列
+
+        for row in reader:
+            y.append(int(row[1]))
+            schema_versions.add(int(row[2]))
+            X.append([float(v) for v in row[3:]])
+
+    if len(schema_versions) > 1:
+        raise ValueError(f"mixed feature schema versions: {sorted(schema_versions)}")
+
+    schema_version = schema_versions.pop() if schema_versions else 0
+    return np.array(X), np.array(y), feature_names, schema_version
+
+
+def export_tree(estimator):
+    """将 sklearn 决策树导出为 Go 端的节点数组格式（见 pkg/mlmodel/tree.go）"""
+    tree = estimator.tree_
+    nodes = []
+    for i in range(tree.node_count):
+        left = int(tree.children_left[i])
+        right = int(tree.children_right[i])
+        if left == -1:
+            counts = tree.value[i][0]
+            total = counts.sum()
+            nodes.append({
+                'left': -1,
+                'right': -1,
+                'value': float(counts[1] / total) if total > 0 else 0.0,
+                'cover': float(tree.n_node_samples[i]),
+            })
+        else:
+            nodes.append({
+                'feature': int(tree.feature[i]),
+                'threshold': float(tree.threshold[i]),
+                'left': left,
+                'right': right,
+                'cover': float(tree.n_node_samples[i]),
+            })
+    return {'nodes': nodes}
+
+
+def save_model_for_go(model, feature_names, schema_version, path):
+    """保存随机森林模型供 Go 程序使用"""
     model_data = {
-        'weights': feature_weights.tolist(),  # 转换为普通列表
+        'type': 'random_forest',
+        'schema_version': schema_version,
+        'feature_names': feature_names,
         'threshold': 0.5,
-        'feature_count': len(feature_weights)
+        'feature_count': len(feature_names),
+        'trees': [export_tree(est) for est in model.estimators_],
     }
-    
+
     with open(path, 'w') as f:  # 使用文本模式打开
-        json.dump(model_data, f, indent=2)
+        json.dump(model_data, f)
+
 
 def main():
-    # 训练数据目录
-    normal_dir = "training_data/normal"
-    webshell_dir = "training_data/webshell"
-    
+    features_path = sys.argv[1] if len(sys.argv) > 1 else FEATURES_CSV
+
     # 确保输出目录存在
     os.makedirs("data/models", exist_ok=True)
-    
-    # 收集样本
-    print("Collecting samples...")
-    X, y = collect_samples(normal_dir, webshell_dir)
-    
+
+    # 读取特征
+    print(f"Loading features from {features_path}...")
+    X, y, feature_names, schema_version = load_features(features_path)
+
     if len(X) == 0:
         print("No samples found!")
         return
-    
+
     # 分割训练集和测试集
     X_train, X_test, y_train, y_test = train_test_split(
         X, y, test_size=0.2, random_state=42
     )
-    
+
     # 创建并训练随机森林模型
     print("Training model...")
     model = RandomForestClassifier(
@@ -107,32 +106,24 @@ def main():
         random_state=42
     )
     model.fit(X_train, y_train)
-    
+
     # 评估模型
     print("\nModel Evaluation:")
     y_pred = model.predict(X_test)
     print(classification_report(y_test, y_pred))
-    
-    # 获取特征权重
-    feature_weights = model.feature_importances_
-    
-    # 保存简化的模型
+
+    # 保存模型
     model_path = "data/models/rf_model.bin"
-    save_model_for_go(model, feature_weights, model_path)
-    
-    print(f"Model saved to {model_path}")
-    
+    save_model_for_go(model, feature_names, schema_version, model_path)
+
+    print(f"Model saved to {model_path} (feature schema v{schema_version})")
+
     # 特征重要性
     print("\nFeature Importance:")
-    feature_names = [
-        "File Size",
-        "Dangerous Functions",
-        "Base64 Usage",
-        "Superglobals",
-        "File Operations"
-    ]
-    for name, importance in zip(feature_names, model.feature_importances_):
+    ranked = sorted(zip(feature_names, model.feature_importances_), key=lambda x: -x[1])
+    for name, importance in ranked[:20]:
         print(f"{name}: {importance:.4f}")
 
+
 if __name__ == "__main__":
-    main()
\ No newline at end of file
+    main()